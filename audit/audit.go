@@ -0,0 +1,178 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/coolstina/connecter"
+)
+
+// Operation defines the kind of data-mutating operation.
+type Operation string
+
+func (o Operation) String() string {
+	return string(o)
+}
+
+const (
+	OperationOfCreate Operation = "create"
+	OperationOfUpdate Operation = "update"
+	OperationOfDelete Operation = "delete"
+	OperationOfWrite  Operation = "write"
+	OperationOfBulk   Operation = "bulk"
+)
+
+// Actor describes who performed the operation.
+type Actor struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name,omitempty"`
+	Source     string            `json:"source,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Event defines a single audit record.
+type Event struct {
+	Time      time.Time              `json:"time"`
+	Backend   connecter.DriverName   `json:"backend"`
+	Operation Operation              `json:"operation"`
+	Target    string                 `json:"target"`
+	Statement string                 `json:"statement,omitempty"`
+	Fields    map[string]interface{} `json:"fields,omitempty"`
+	Affected  int64                  `json:"affected"`
+	Actor     *Actor                 `json:"actor,omitempty"`
+	Error     string                 `json:"error,omitempty"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx that carries the given actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext get actor from the given context.
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	if ctx == nil {
+		return Actor{}, false
+	}
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	return actor, ok
+}
+
+// Auditor attaches actor info to events, redacts the configured
+// fields and writes the result to the sink.
+type Auditor struct {
+	sink    Sink
+	options *options
+}
+
+// New initialize auditor instance with the given sink.
+func New(sink Sink, ops ...Option) *Auditor {
+	opts := &options{
+		redactValue:  "[REDACTED]",
+		redact:       make(map[string]struct{}),
+		now:          time.Now,
+		errorHandler: func(err error) {},
+	}
+
+	for _, o := range ops {
+		o.apply(opts)
+	}
+
+	return &Auditor{sink: sink, options: opts}
+}
+
+// Record completes the given event and writes it to the sink.
+// Sink errors are reported to the error handler and never returned,
+// the audited operation must not fail because of auditing.
+func (a *Auditor) Record(ctx context.Context, event Event) {
+	if a == nil || a.sink == nil {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = a.options.now()
+	}
+
+	if actor, ok := ActorFromContext(ctx); ok && event.Actor == nil {
+		event.Actor = &actor
+	}
+
+	if event.Fields != nil {
+		event.Fields = a.Redact(event.Fields)
+	}
+
+	if err := a.sink.Write(ctx, &event); err != nil {
+		a.options.errorHandler(err)
+	}
+}
+
+// Redact returns a copy of fields where every configured field name,
+// at any depth, is replaced by the redact value.
+func (a *Auditor) Redact(fields map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if a.redacted(key) {
+			redacted[key] = a.options.redactValue
+			continue
+		}
+		redacted[key] = a.redactValue(reflect.ValueOf(value))
+	}
+	return redacted
+}
+
+func (a *Auditor) redacted(field string) bool {
+	_, ok := a.options.redact[strings.ToLower(field)]
+	return ok
+}
+
+func (a *Auditor) redactValue(value reflect.Value) interface{} {
+	if !value.IsValid() {
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if value.IsNil() {
+			return nil
+		}
+		return a.redactValue(value.Elem())
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return value.Interface()
+		}
+		fields := make(map[string]interface{}, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			fields[iter.Key().String()] = iter.Value().Interface()
+		}
+		return a.Redact(fields)
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface()
+		}
+		items := make([]interface{}, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			items = append(items, a.redactValue(value.Index(i)))
+		}
+		return items
+	}
+
+	return value.Interface()
+}
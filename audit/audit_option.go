@@ -0,0 +1,71 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"strings"
+	"time"
+)
+
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(ops *options)
+
+func (o optionFunc) apply(ops *options) {
+	o(ops)
+}
+
+type options struct {
+	redact       map[string]struct{}
+	redactValue  interface{}
+	now          func() time.Time
+	errorHandler func(err error)
+}
+
+// WithRedactFields Specifies field names whose values are replaced
+// before the event is written. Field names are case insensitive.
+func WithRedactFields(fields ...string) Option {
+	return optionFunc(func(ops *options) {
+		for _, field := range fields {
+			ops.redact[strings.ToLower(field)] = struct{}{}
+		}
+	})
+}
+
+// WithRedactValue Specifies the value that replaces redacted fields.
+// Default value "[REDACTED]".
+func WithRedactValue(value interface{}) Option {
+	return optionFunc(func(ops *options) {
+		ops.redactValue = value
+	})
+}
+
+// WithErrorHandler Specifies the handler invoked when the sink fails to
+// write an event. Errors are dropped by default.
+func WithErrorHandler(handler func(err error)) Option {
+	return optionFunc(func(ops *options) {
+		ops.errorHandler = handler
+	})
+}
+
+// WithClock Specifies the function used to stamp events.
+// Default is time.Now.
+func WithClock(now func() time.Time) Option {
+	return optionFunc(func(ops *options) {
+		ops.now = now
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// Sink defines the destination of audit events.
+type Sink interface {
+	Write(ctx context.Context, event *Event) error
+}
+
+// SinkFunc is an adapter to allow the use of ordinary functions as Sink.
+type SinkFunc func(ctx context.Context, event *Event) error
+
+// Write calls f(ctx, event).
+func (f SinkFunc) Write(ctx context.Context, event *Event) error {
+	return f(ctx, event)
+}
+
+// WriterSink writes events as JSON lines to an io.Writer.
+type WriterSink struct {
+	mu      sync.Mutex
+	writer  io.Writer
+	encoder *json.Encoder
+}
+
+// NewWriterSink initialize sink that writes JSON lines to the given writer.
+func NewWriterSink(writer io.Writer) *WriterSink {
+	return &WriterSink{writer: writer, encoder: json.NewEncoder(writer)}
+}
+
+// NewFileSink initialize sink that appends JSON lines to the given file,
+// the file will be created if not exists.
+func NewFileSink(filename string) (*WriterSink, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewWriterSink(file), nil
+}
+
+// Write encodes the event as one line.
+func (s *WriterSink) Write(ctx context.Context, event *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.encoder.Encode(event)
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (s *WriterSink) Close() error {
+	if closer, ok := s.writer.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// NewChannelSink initialize sink that sends events to the given channel.
+// Write blocks until the event is received or ctx is done.
+func NewChannelSink(ch chan<- Event) Sink {
+	return SinkFunc(func(ctx context.Context, event *Event) error {
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		select {
+		case ch <- *event:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
@@ -0,0 +1,91 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coolstina/connecter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditor_Record(t *testing.T) {
+	events := make(chan Event, 1)
+	now := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	auditor := New(NewChannelSink(events),
+		WithRedactFields("password", "Token"),
+		WithClock(func() time.Time { return now }),
+	)
+
+	ctx := WithActor(context.Background(), Actor{ID: "1", Name: "helloshaohua"})
+	auditor.Record(ctx, Event{
+		Backend:   connecter.DriverNameOfMySQL,
+		Operation: OperationOfCreate,
+		Target:    "users",
+		Fields: map[string]interface{}{
+			"username": "helloshaohua",
+			"password": "secret",
+			"profile": map[string]interface{}{
+				"token": "secret",
+			},
+			"devices": []map[string]interface{}{
+				{"name": "phone", "token": "secret"},
+			},
+		},
+	})
+
+	event := <-events
+	assert.Equal(t, now, event.Time)
+	assert.Equal(t, "1", event.Actor.ID)
+	assert.Equal(t, "helloshaohua", event.Fields["username"])
+	assert.Equal(t, "[REDACTED]", event.Fields["password"])
+	assert.Equal(t, map[string]interface{}{"token": "[REDACTED]"}, event.Fields["profile"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "phone", "token": "[REDACTED]"},
+	}, event.Fields["devices"])
+}
+
+func TestAuditor_Record_SinkError(t *testing.T) {
+	var actual error
+	expected := errors.New("sink unavailable")
+	auditor := New(
+		SinkFunc(func(ctx context.Context, event *Event) error { return expected }),
+		WithErrorHandler(func(err error) { actual = err }),
+	)
+
+	auditor.Record(context.Background(), Event{Operation: OperationOfDelete})
+	assert.Equal(t, expected, actual)
+}
+
+func TestWriterSink_Write(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	auditor := New(NewWriterSink(buffer))
+
+	auditor.Record(context.Background(), Event{Backend: connecter.DriverNameOfRedis, Target: "username"})
+	auditor.Record(context.Background(), Event{Backend: connecter.DriverNameOfRedis, Target: "password"})
+
+	decoder := json.NewDecoder(buffer)
+	for _, expected := range []string{"username", "password"} {
+		var event Event
+		assert.NoError(t, decoder.Decode(&event))
+		assert.Equal(t, expected, event.Target)
+		assert.Nil(t, event.Actor)
+	}
+}
@@ -21,6 +21,9 @@ func (d DriverName) String() string {
 }
 
 const (
-	DriverNameOfMySQL  DriverName = "mysql"
-	DriverNameOfSQLite DriverName = "sqlite"
+	DriverNameOfMySQL         DriverName = "mysql"
+	DriverNameOfSQLite        DriverName = "sqlite"
+	DriverNameOfRedis         DriverName = "redis"
+	DriverNameOfMongo         DriverName = "mongo"
+	DriverNameOfElasticsearch DriverName = "elasticsearch"
 )
@@ -0,0 +1,187 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// SchemeOfSRV prefixes targets resolved through DNS SRV records,
+	// such as srv://_mongodb._tcp.example.com.
+	SchemeOfSRV = "srv://"
+	// SchemeOfDNS prefixes targets resolved through DNS A/AAAA
+	// records, such as dns://redis.example.com:6379.
+	SchemeOfDNS = "dns://"
+)
+
+// Resolver defines the DNS lookups used by discovery,
+// *net.Resolver satisfies it.
+type Resolver interface {
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// Updater is implemented by clients that support dynamic endpoints,
+// such as the mysql ReplicaSet. The mongo, elasticsearch and redis
+// sentinel clients can't replace their endpoints once connected, they
+// resolve the targets once and follow topology changes themselves.
+type Updater interface {
+	UpdateEndpoints(hosts []string) error
+}
+
+// Discoverer resolves targets into host:port lists.
+type Discoverer struct {
+	targets []string
+	options *options
+}
+
+// New initialize discoverer instance for the given targets. A target
+// is either srv://name, dns://host:port or a literal host:port.
+func New(targets []string, ops ...Option) *Discoverer {
+	opts := &options{
+		resolver:     net.DefaultResolver,
+		interval:     time.Minute,
+		errorHandler: func(err error) {},
+	}
+
+	for _, o := range ops {
+		o.apply(opts)
+	}
+
+	return &Discoverer{targets: targets, options: opts}
+}
+
+// Resolve resolves every target and returns the sorted, deduplicated hosts.
+func (d *Discoverer) Resolve(ctx context.Context) ([]string, error) {
+	seen := make(map[string]struct{})
+	hosts := make([]string, 0, len(d.targets))
+
+	for _, target := range d.targets {
+		resolved, err := d.resolve(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, host := range resolved {
+			if _, ok := seen[host]; !ok {
+				seen[host] = struct{}{}
+				hosts = append(hosts, host)
+			}
+		}
+	}
+
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("discovery: no hosts found for %s", strings.Join(d.targets, ","))
+	}
+
+	sort.Strings(hosts)
+	return hosts, nil
+}
+
+func (d *Discoverer) resolve(ctx context.Context, target string) ([]string, error) {
+	switch {
+	case strings.HasPrefix(target, SchemeOfSRV):
+		_, records, err := d.options.resolver.LookupSRV(ctx, "", "", strings.TrimPrefix(target, SchemeOfSRV))
+		if err != nil {
+			return nil, err
+		}
+
+		hosts := make([]string, 0, len(records))
+		for _, record := range records {
+			host := strings.TrimSuffix(record.Target, ".")
+			hosts = append(hosts, net.JoinHostPort(host, strconv.Itoa(int(record.Port))))
+		}
+		return hosts, nil
+	case strings.HasPrefix(target, SchemeOfDNS):
+		host, port, err := net.SplitHostPort(strings.TrimPrefix(target, SchemeOfDNS))
+		if err != nil {
+			return nil, err
+		}
+
+		addrs, err := d.options.resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		hosts := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			hosts = append(hosts, net.JoinHostPort(addr, port))
+		}
+		return hosts, nil
+	}
+
+	return []string{target}, nil
+}
+
+// Watch resolves the targets every interval until ctx is done and
+// calls onChange with the hosts whenever they differ from the previous
+// resolution, starting with the first one. Resolution errors are
+// reported to the error handler and the previous hosts are kept.
+func (d *Discoverer) Watch(ctx context.Context, onChange func(hosts []string)) {
+	var current []string
+
+	refresh := func() {
+		hosts, err := d.Resolve(ctx)
+		if err != nil {
+			d.options.errorHandler(err)
+			return
+		}
+		if !reflect.DeepEqual(hosts, current) {
+			current = hosts
+			onChange(hosts)
+		}
+	}
+
+	refresh()
+
+	ticker := time.NewTicker(d.options.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			refresh()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Update watches the targets and pushes every change to the updater,
+// update errors are reported to the error handler.
+func (d *Discoverer) Update(ctx context.Context, updater Updater) {
+	d.Watch(ctx, func(hosts []string) {
+		if err := updater.UpdateEndpoints(hosts); err != nil {
+			d.options.errorHandler(err)
+		}
+	})
+}
+
+// URLs prefixes every host with the given scheme, such as http.
+func URLs(scheme string, hosts []string) []string {
+	urls := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		urls = append(urls, scheme+"://"+host)
+	}
+	return urls
+}
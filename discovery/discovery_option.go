@@ -0,0 +1,57 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import "time"
+
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(ops *options)
+
+func (o optionFunc) apply(ops *options) {
+	o(ops)
+}
+
+type options struct {
+	resolver     Resolver
+	interval     time.Duration
+	errorHandler func(err error)
+}
+
+// WithResolver Specifies the resolver used for DNS lookups.
+// Default is net.DefaultResolver.
+func WithResolver(resolver Resolver) Option {
+	return optionFunc(func(ops *options) {
+		ops.resolver = resolver
+	})
+}
+
+// WithInterval Specifies the interval between two resolutions
+// when watching. Default is 1 minute.
+func WithInterval(interval time.Duration) Option {
+	return optionFunc(func(ops *options) {
+		ops.interval = interval
+	})
+}
+
+// WithErrorHandler Specifies the handler invoked when a watched
+// resolution or update fails. Errors are dropped by default.
+func WithErrorHandler(handler func(err error)) Option {
+	return optionFunc(func(ops *options) {
+		ops.errorHandler = handler
+	})
+}
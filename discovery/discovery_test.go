@@ -0,0 +1,119 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeResolver struct {
+	mu    sync.Mutex
+	srv   map[string][]*net.SRV
+	hosts map[string][]string
+}
+
+func (f *fakeResolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	records, ok := f.srv[name]
+	if !ok {
+		return "", nil, errors.New("no such host")
+	}
+	return name, records, nil
+}
+
+func (f *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	addrs, ok := f.hosts[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+func (f *fakeResolver) set(host string, addrs ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hosts[host] = addrs
+}
+
+func TestDiscoverer_Resolve(t *testing.T) {
+	resolver := &fakeResolver{
+		srv: map[string][]*net.SRV{
+			"_mongodb._tcp.example.com": {
+				{Target: "mongo-1.example.com.", Port: 27017},
+				{Target: "mongo-0.example.com.", Port: 27017},
+			},
+		},
+		hosts: map[string][]string{
+			"redis.example.com": {"10.0.0.2", "10.0.0.1"},
+		},
+	}
+
+	discoverer := New([]string{
+		"srv://_mongodb._tcp.example.com",
+		"dns://redis.example.com:6379",
+		"10.0.0.1:6379",
+	}, WithResolver(resolver))
+
+	hosts, err := discoverer.Resolve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"10.0.0.1:6379",
+		"10.0.0.2:6379",
+		"mongo-0.example.com:27017",
+		"mongo-1.example.com:27017",
+	}, hosts)
+
+	_, err = New([]string{"srv://unknown"}, WithResolver(resolver)).Resolve(context.Background())
+	assert.Error(t, err)
+}
+
+type fakeUpdater struct {
+	updates chan []string
+}
+
+func (f *fakeUpdater) UpdateEndpoints(hosts []string) error {
+	f.updates <- hosts
+	return nil
+}
+
+func TestDiscoverer_Update(t *testing.T) {
+	resolver := &fakeResolver{hosts: map[string][]string{"es.example.com": {"10.0.0.1"}}}
+	discoverer := New([]string{"dns://es.example.com:9200"},
+		WithResolver(resolver),
+		WithInterval(5*time.Millisecond),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updater := &fakeUpdater{updates: make(chan []string)}
+	go discoverer.Update(ctx, updater)
+
+	assert.Equal(t, []string{"10.0.0.1:9200"}, <-updater.updates)
+
+	resolver.set("es.example.com", "10.0.0.1", "10.0.0.3")
+	assert.Equal(t, []string{"10.0.0.1:9200", "10.0.0.3:9200"}, <-updater.updates)
+	assert.Equal(t, []string{"http://10.0.0.1:9200"}, URLs("http", []string{"10.0.0.1:9200"}))
+}
@@ -0,0 +1,148 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/coolstina/connecter"
+	"github.com/coolstina/connecter/audit"
+)
+
+// WithAudit Specifies the auditor that records index, update, delete
+// and bulk requests. The actor is read from the context given to Do.
+func WithAudit(auditor *audit.Auditor) Option {
+	return optionFunc(func(ops *options) {
+		ops.transports = append(ops.transports, func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				operation, ok := auditOperation(req.Method, req.URL.Path)
+				if !ok {
+					return next.RoundTrip(req)
+				}
+
+				event := audit.Event{
+					Backend:   connecter.DriverNameOfElasticsearch,
+					Operation: operation,
+					Target:    auditTarget(req.URL.Path),
+					Statement: req.Method + " " + req.URL.Path,
+				}
+
+				if operation != audit.OperationOfBulk {
+					event.Fields = requestFields(req)
+				}
+
+				resp, err := next.RoundTrip(req)
+				if err != nil {
+					event.Error = err.Error()
+				} else if resp.StatusCode >= http.StatusMultipleChoices {
+					event.Error = resp.Status
+				}
+
+				auditor.Record(req.Context(), event)
+				return resp, err
+			})
+		})
+	})
+}
+
+// auditOperation classifies the request by method and path,
+// it reports false for requests that do not mutate data.
+func auditOperation(method, path string) (audit.Operation, bool) {
+	if method == http.MethodGet || method == http.MethodHead {
+		return "", false
+	}
+
+	segments := pathSegments(path)
+	if len(segments) == 0 {
+		return "", false
+	}
+
+	for _, segment := range segments {
+		switch segment {
+		case "_bulk":
+			return audit.OperationOfBulk, true
+		case "_update", "_update_by_query":
+			return audit.OperationOfUpdate, true
+		case "_delete_by_query":
+			return audit.OperationOfDelete, true
+		case "_create":
+			return audit.OperationOfCreate, true
+		}
+	}
+
+	// Any other underscore endpoint, such as _search, is a read or an admin call.
+	for _, segment := range segments {
+		if strings.HasPrefix(segment, "_") && segment != "_doc" {
+			return "", false
+		}
+	}
+
+	switch method {
+	case http.MethodDelete:
+		return audit.OperationOfDelete, true
+	case http.MethodPut, http.MethodPost:
+		if len(segments) == 1 {
+			return audit.OperationOfCreate, true
+		}
+		return audit.OperationOfWrite, true
+	}
+
+	return "", false
+}
+
+// auditTarget get index name of the request path.
+func auditTarget(path string) string {
+	segments := pathSegments(path)
+	if len(segments) == 0 || strings.HasPrefix(segments[0], "_") {
+		return ""
+	}
+	return segments[0]
+}
+
+func pathSegments(path string) []string {
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// requestFields decodes the JSON request body and restores it
+// so that it can still be sent.
+func requestFields(req *http.Request) map[string]interface{} {
+	if req.Body == nil {
+		return nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	_ = req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
+	}
+
+	return fields
+}
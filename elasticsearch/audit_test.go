@@ -0,0 +1,49 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/coolstina/connecter/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditOperation(t *testing.T) {
+	grids := []struct {
+		method    string
+		path      string
+		operation audit.Operation
+		audited   bool
+	}{
+		{method: http.MethodGet, path: "/hello_world/_doc/1"},
+		{method: http.MethodPost, path: "/hello_world/_search"},
+		{method: http.MethodHead, path: "/hello_world"},
+		{method: http.MethodPut, path: "/hello_world", operation: audit.OperationOfCreate, audited: true},
+		{method: http.MethodPut, path: "/hello_world/_doc/1", operation: audit.OperationOfWrite, audited: true},
+		{method: http.MethodPut, path: "/hello_world/_doc/1/_create", operation: audit.OperationOfCreate, audited: true},
+		{method: http.MethodPost, path: "/hello_world/_doc/1/_update", operation: audit.OperationOfUpdate, audited: true},
+		{method: http.MethodDelete, path: "/hello_world/_doc/1", operation: audit.OperationOfDelete, audited: true},
+		{method: http.MethodPost, path: "/hello_world/_delete_by_query", operation: audit.OperationOfDelete, audited: true},
+		{method: http.MethodPost, path: "/_bulk", operation: audit.OperationOfBulk, audited: true},
+	}
+
+	for _, grid := range grids {
+		operation, audited := auditOperation(grid.method, grid.path)
+		assert.Equal(t, grid.audited, audited, grid.path)
+		assert.Equal(t, grid.operation, operation, grid.path)
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"github.com/coolstina/connecter/discovery"
+)
+
+type discoveryTarget struct {
+	discoverer *discovery.Discoverer
+	scheme     string
+}
+
+// WithDiscovery Specifies the discoverer resolving the node URLs when
+// connecting, they are added to the ones of WithSetURL. The targets
+// are resolved once, the client can't replace its URLs afterwards, so
+// enable sniffing to follow the cluster membership once connected.
+func WithDiscovery(discoverer *discovery.Discoverer, scheme string) Option {
+	return optionFunc(func(ops *options) {
+		ops.discovery = &discoveryTarget{discoverer: discoverer, scheme: scheme}
+	})
+}
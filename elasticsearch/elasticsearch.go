@@ -18,10 +18,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"reflect"
 	"time"
 	"unsafe"
 
+	"github.com/coolstina/connecter/discovery"
 	"github.com/olivere/elastic"
 )
 
@@ -77,7 +79,56 @@ func NewConnection(ops ...Option) (*elastic.Client, error) {
 		}
 	}
 
-	return elastic.NewClient(fs...)
+	if opts.discovery != nil {
+		hosts, err := opts.discovery.discoverer.Resolve(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		urls := append(append([]string{}, opts.urls...), discovery.URLs(opts.discovery.scheme, hosts)...)
+		fs = append(fs, elastic.SetURL(urls...))
+	}
+
+	if opts.httpClient != nil || len(opts.transports) > 0 {
+		fs = append(fs, elastic.SetHttpClient(httpClient(opts)))
+	}
+
+	client, err := elastic.NewClient(fs...)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.warmUp != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), opts.warmUp.timeout)
+		report := WarmUp(ctx, client, opts.warmUp.connections)
+		cancel()
+
+		if opts.warmUp.report != nil {
+			opts.warmUp.report(report)
+		}
+	}
+
+	return client, nil
+}
+
+// httpClient returns a copy of the configured http client whose
+// transport is wrapped by the option transports in order.
+func httpClient(opts *options) *http.Client {
+	client := http.Client{}
+	if opts.httpClient != nil {
+		client = *opts.httpClient
+	}
+
+	rt := client.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+
+	for i := len(opts.transports) - 1; i >= 0; i-- {
+		rt = opts.transports[i](rt)
+	}
+
+	client.Transport = rt
+	return &client
 }
 
 // CreateIndexIfNotExists Create elastic mappings if not exists.
@@ -67,6 +67,20 @@ type options struct {
 	sendGetBodyAs             *string
 	retrier                   Retrier
 	headers                   http.Header
+	transports                []transport
+	warmUp                    *warmUp
+	discovery                 *discoveryTarget
+}
+
+// transport wraps the round tripper used to reach Elasticsearch.
+type transport func(next http.RoundTripper) http.RoundTripper
+
+// roundTripperFunc is an adapter to allow the use of ordinary
+// functions as http.RoundTripper.
+type roundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
 }
 
 var (
@@ -0,0 +1,68 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"net/http"
+
+	"github.com/coolstina/connecter/ratelimit"
+)
+
+// readEndpoints defines the endpoints that only read data
+// even though they are requested with POST.
+var readEndpoints = map[string]struct{}{
+	"_search":     {},
+	"_msearch":    {},
+	"_count":      {},
+	"_mget":       {},
+	"_explain":    {},
+	"_validate":   {},
+	"_field_caps": {},
+	"_scroll":     {},
+}
+
+// WithRateLimit Specifies the limiter that throttles requests,
+// bulk requests are bulk, searches and GET requests are reads
+// and other requests are writes. In fail fast mode the request
+// fails with ratelimit.ErrLimited.
+func WithRateLimit(limiter *ratelimit.Limiter) Option {
+	return optionFunc(func(ops *options) {
+		ops.transports = append(ops.transports, func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				if err := limiter.Take(req.Context(), requestClass(req.Method, req.URL.Path)); err != nil {
+					return nil, err
+				}
+				return next.RoundTrip(req)
+			})
+		})
+	})
+}
+
+func requestClass(method, path string) ratelimit.Class {
+	if method == http.MethodGet || method == http.MethodHead {
+		return ratelimit.ClassOfRead
+	}
+
+	for _, segment := range pathSegments(path) {
+		if segment == "_bulk" {
+			return ratelimit.ClassOfBulk
+		}
+		if _, ok := readEndpoints[segment]; ok {
+			return ratelimit.ClassOfRead
+		}
+	}
+
+	return ratelimit.ClassOfWrite
+}
@@ -0,0 +1,42 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/coolstina/connecter/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestClass(t *testing.T) {
+	grids := []struct {
+		method string
+		path   string
+		class  ratelimit.Class
+	}{
+		{method: http.MethodGet, path: "/hello_world/_doc/1", class: ratelimit.ClassOfRead},
+		{method: http.MethodPost, path: "/hello_world/_search", class: ratelimit.ClassOfRead},
+		{method: http.MethodPost, path: "/_search/scroll", class: ratelimit.ClassOfRead},
+		{method: http.MethodPut, path: "/hello_world/_doc/1", class: ratelimit.ClassOfWrite},
+		{method: http.MethodDelete, path: "/hello_world", class: ratelimit.ClassOfWrite},
+		{method: http.MethodPost, path: "/hello_world/_bulk", class: ratelimit.ClassOfBulk},
+	}
+
+	for _, grid := range grids {
+		assert.Equal(t, grid.class, requestClass(grid.method, grid.path), grid.path)
+	}
+}
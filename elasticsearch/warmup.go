@@ -0,0 +1,51 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package elasticsearch
+
+import (
+	"context"
+	"time"
+
+	"github.com/coolstina/connecter"
+	"github.com/olivere/elastic"
+)
+
+type warmUp struct {
+	connections int
+	timeout     time.Duration
+	report      func(report connecter.WarmUpReport)
+}
+
+// WithWarmUp Specifies the number of requests NewConnection sends in
+// parallel before returning, bounded by the timeout. The report tells
+// how many succeeded, see WarmUp for the connections they open.
+func WithWarmUp(connections int, timeout time.Duration, report func(report connecter.WarmUpReport)) Option {
+	return optionFunc(func(ops *options) {
+		ops.warmUp = &warmUp{connections: connections, timeout: timeout, report: report}
+	})
+}
+
+// WarmUp requests the cluster health n times in parallel. The client
+// can't hold a keep-alive connection between requests, so a request
+// done early returns its connection and a later request may reuse it:
+// the report counts requests, not connections. The transport keeps at
+// most MaxIdleConnsPerHost connections idle, specify an http client
+// with WithHttpClient to keep more.
+func WarmUp(ctx context.Context, client *elastic.Client, n int) connecter.WarmUpReport {
+	return connecter.WarmUp(ctx, connecter.DriverNameOfElasticsearch, n, func(ctx context.Context) (func(), error) {
+		_, err := client.ClusterHealth().Do(ctx)
+		return nil, err
+	})
+}
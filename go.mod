@@ -8,6 +8,7 @@ require (
 	github.com/fortytw2/leaktest v1.3.0 // indirect
 	github.com/gabriel-vasile/mimetype v1.4.0 // indirect
 	github.com/go-redis/redis v6.15.9+incompatible
+	github.com/go-sql-driver/mysql v1.6.0
 	github.com/google/go-cmp v0.5.6 // indirect
 	github.com/mailru/easyjson v0.7.7 // indirect
 	github.com/olivere/elastic v6.2.37+incompatible
@@ -15,6 +16,9 @@ require (
 	github.com/stretchr/testify v1.7.0
 	go.mongodb.org/mongo-driver v1.8.0
 	golang.org/x/net v0.0.0-20211123203042-d83791d6bcd9 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20210107192922-496545a6307b
 	gorm.io/driver/mysql v1.2.0
+	gorm.io/driver/sqlite v1.2.6
 	gorm.io/gorm v1.22.3
+	modernc.org/sqlite v1.14.2
 )
@@ -0,0 +1,116 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"sync"
+
+	"github.com/coolstina/connecter"
+	"github.com/coolstina/connecter/audit"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// auditOperations defines the audited commands.
+var auditOperations = map[string]audit.Operation{
+	"insert":        audit.OperationOfCreate,
+	"update":        audit.OperationOfUpdate,
+	"delete":        audit.OperationOfDelete,
+	"findAndModify": audit.OperationOfUpdate,
+}
+
+// auditCommandFields defines the command fields recorded in events.
+var auditCommandFields = []string{"documents", "updates", "deletes", "query", "update", "remove"}
+
+type pendingEvent struct {
+	ctx   context.Context
+	event audit.Event
+}
+
+// WithAudit Specifies the auditor that records insert, update,
+// delete and findAndModify commands once they have finished.
+func WithAudit(auditor *audit.Auditor) Option {
+	return optionFunc(func(ops *opts) {
+		ops.monitors = append(ops.monitors, auditMonitor(auditor))
+	})
+}
+
+func auditMonitor(auditor *audit.Auditor) *event.CommandMonitor {
+	var pending sync.Map
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			operation, ok := auditOperations[evt.CommandName]
+			if !ok {
+				return
+			}
+
+			if remove, ok := evt.Command.Lookup("remove").BooleanOK(); ok && remove {
+				operation = audit.OperationOfDelete
+			}
+
+			collection, _ := evt.Command.Lookup(evt.CommandName).StringValueOK()
+			pending.Store(evt.RequestID, &pendingEvent{
+				ctx: ctx,
+				event: audit.Event{
+					Backend:   connecter.DriverNameOfMongo,
+					Operation: operation,
+					Target:    evt.DatabaseName + "." + collection,
+					Statement: evt.CommandName,
+					Fields:    commandFields(evt.Command),
+				},
+			})
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			value, ok := pending.LoadAndDelete(evt.RequestID)
+			if !ok {
+				return
+			}
+
+			p := value.(*pendingEvent)
+			if n, ok := evt.Reply.Lookup("n").Int32OK(); ok {
+				p.event.Affected = int64(n)
+			}
+			auditor.Record(p.ctx, p.event)
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			value, ok := pending.LoadAndDelete(evt.RequestID)
+			if !ok {
+				return
+			}
+
+			p := value.(*pendingEvent)
+			p.event.Error = evt.Failure
+			auditor.Record(p.ctx, p.event)
+		},
+	}
+}
+
+func commandFields(command bson.Raw) map[string]interface{} {
+	var document bson.M
+	if err := bson.Unmarshal(command, &document); err != nil {
+		return nil
+	}
+
+	fields := make(map[string]interface{})
+	for _, name := range auditCommandFields {
+		if value, ok := document[name]; ok {
+			fields[name] = value
+		}
+	}
+
+	return fields
+}
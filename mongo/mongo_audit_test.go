@@ -0,0 +1,95 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coolstina/connecter"
+	"github.com/coolstina/connecter/audit"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+func rawDocument(t *testing.T, document bson.D) bson.Raw {
+	data, err := bson.Marshal(document)
+	assert.NoError(t, err)
+	return data
+}
+
+func TestAuditMonitor(t *testing.T) {
+	events := make(chan audit.Event, 4)
+	monitor := auditMonitor(audit.New(audit.NewChannelSink(events)))
+	ctx := audit.WithActor(context.Background(), audit.Actor{ID: "u1"})
+
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command: rawDocument(t, bson.D{
+			{Key: "insert", Value: "users"},
+			{Key: "documents", Value: bson.A{bson.D{{Key: "name", Value: "helloshaohua"}}}},
+		}),
+		DatabaseName: "connecter",
+		CommandName:  "insert",
+		RequestID:    1,
+	})
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", RequestID: 1},
+		Reply:                rawDocument(t, bson.D{{Key: "n", Value: int32(1)}, {Key: "ok", Value: 1}}),
+	})
+
+	recorded := <-events
+	assert.Equal(t, connecter.DriverNameOfMongo, recorded.Backend)
+	assert.Equal(t, audit.OperationOfCreate, recorded.Operation)
+	assert.Equal(t, "connecter.users", recorded.Target)
+	assert.Equal(t, "insert", recorded.Statement)
+	assert.Equal(t, int64(1), recorded.Affected)
+	assert.Contains(t, recorded.Fields, "documents")
+	assert.Equal(t, "u1", recorded.Actor.ID)
+
+	// Reads are not audited.
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command:      rawDocument(t, bson.D{{Key: "find", Value: "users"}}),
+		DatabaseName: "connecter",
+		CommandName:  "find",
+		RequestID:    2,
+	})
+	monitor.Succeeded(ctx, &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", RequestID: 2},
+		Reply:                rawDocument(t, bson.D{{Key: "ok", Value: 1}}),
+	})
+	assert.Empty(t, events)
+
+	// A findAndModify removing its document is a delete.
+	monitor.Started(ctx, &event.CommandStartedEvent{
+		Command: rawDocument(t, bson.D{
+			{Key: "findAndModify", Value: "users"},
+			{Key: "query", Value: bson.D{{Key: "name", Value: "helloshaohua"}}},
+			{Key: "remove", Value: true},
+		}),
+		DatabaseName: "connecter",
+		CommandName:  "findAndModify",
+		RequestID:    3,
+	})
+	monitor.Failed(ctx, &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "findAndModify", RequestID: 3},
+		Failure:              "not primary",
+	})
+
+	recorded = <-events
+	assert.Equal(t, audit.OperationOfDelete, recorded.Operation)
+	assert.Equal(t, "not primary", recorded.Error)
+	assert.Contains(t, recorded.Fields, "query")
+}
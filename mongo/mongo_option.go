@@ -17,6 +17,9 @@ package mongo
 import (
 	"sync"
 	"time"
+
+	"github.com/coolstina/connecter/discovery"
+	"go.mongodb.org/mongo-driver/event"
 )
 
 type Option interface {
@@ -43,6 +46,9 @@ type opts struct {
 	tls                      bool
 	w                        string
 	directConnection         bool
+	monitors                 []*event.CommandMonitor
+	warmUp                   *warmUp
+	discoverer               *discovery.Discoverer
 }
 
 var access sync.Mutex
@@ -159,6 +165,16 @@ func WithDirectConnection(direct bool) Option {
 	})
 }
 
+// WithDiscovery Specifies the discoverer resolving the mongod server
+// hosts when connecting, the hosts replace the ones of the URI. The
+// targets are resolved once, the client can't replace its hosts
+// afterwards, the driver monitors the topology itself once connected.
+func WithDiscovery(discoverer *discovery.Discoverer) Option {
+	return optionFunc(func(ops *opts) {
+		ops.discoverer = discoverer
+	})
+}
+
 func in(source []string, find string) bool {
 	for _, item := range source {
 		if item == find {
@@ -0,0 +1,258 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+
+	"github.com/coolstina/connecter/ratelimit"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collection wraps a collection whose operations take a token of the
+// limiter before they are sent. Finds, counts and aggregations are
+// reads, writes of more than one document are bulk and other writes
+// are writes. Change streams are reads, index changes and drops are
+// writes. An operation the limiter rejects fails with its error,
+// such as ratelimit.ErrLimited in fail fast mode. Only the operations
+// are limited, the batches fetched by their cursors are not.
+type Collection struct {
+	*mongo.Collection
+	limiter *ratelimit.Limiter
+}
+
+// RateLimit initialize collection instance limited by the limiter.
+func RateLimit(collection *mongo.Collection, limiter *ratelimit.Limiter) *Collection {
+	return &Collection{Collection: collection, limiter: limiter}
+}
+
+func (c *Collection) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return nil, err
+	}
+	return c.Collection.InsertOne(ctx, document, opts...)
+}
+
+func (c *Collection) InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	if err := c.limiter.Take(ctx, bulkClass(len(documents))); err != nil {
+		return nil, err
+	}
+	return c.Collection.InsertMany(ctx, documents, opts...)
+}
+
+func (c *Collection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	if err := c.limiter.Take(ctx, bulkClass(len(models))); err != nil {
+		return nil, err
+	}
+	return c.Collection.BulkWrite(ctx, models, opts...)
+}
+
+func (c *Collection) UpdateByID(ctx context.Context, id interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return nil, err
+	}
+	return c.Collection.UpdateByID(ctx, id, update, opts...)
+}
+
+func (c *Collection) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return nil, err
+	}
+	return c.Collection.UpdateOne(ctx, filter, update, opts...)
+}
+
+func (c *Collection) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return nil, err
+	}
+	return c.Collection.UpdateMany(ctx, filter, update, opts...)
+}
+
+func (c *Collection) ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return nil, err
+	}
+	return c.Collection.ReplaceOne(ctx, filter, replacement, opts...)
+}
+
+func (c *Collection) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return nil, err
+	}
+	return c.Collection.DeleteOne(ctx, filter, opts...)
+}
+
+func (c *Collection) DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return nil, err
+	}
+	return c.Collection.DeleteMany(ctx, filter, opts...)
+}
+
+func (c *Collection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfRead); err != nil {
+		return nil, err
+	}
+	return c.Collection.Find(ctx, filter, opts...)
+}
+
+func (c *Collection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfRead); err != nil {
+		return nil, err
+	}
+	return c.Collection.Aggregate(ctx, pipeline, opts...)
+}
+
+func (c *Collection) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfRead); err != nil {
+		return 0, err
+	}
+	return c.Collection.CountDocuments(ctx, filter, opts...)
+}
+
+func (c *Collection) EstimatedDocumentCount(ctx context.Context, opts ...*options.EstimatedDocumentCountOptions) (int64, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfRead); err != nil {
+		return 0, err
+	}
+	return c.Collection.EstimatedDocumentCount(ctx, opts...)
+}
+
+func (c *Collection) Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfRead); err != nil {
+		return nil, err
+	}
+	return c.Collection.Distinct(ctx, fieldName, filter, opts...)
+}
+
+func (c *Collection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfRead); err != nil {
+		return rejected(err)
+	}
+	return c.Collection.FindOne(ctx, filter, opts...)
+}
+
+func (c *Collection) FindOneAndDelete(ctx context.Context, filter interface{}, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return rejected(err)
+	}
+	return c.Collection.FindOneAndDelete(ctx, filter, opts...)
+}
+
+func (c *Collection) FindOneAndReplace(ctx context.Context, filter interface{}, replacement interface{}, opts ...*options.FindOneAndReplaceOptions) *mongo.SingleResult {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return rejected(err)
+	}
+	return c.Collection.FindOneAndReplace(ctx, filter, replacement, opts...)
+}
+
+func (c *Collection) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return rejected(err)
+	}
+	return c.Collection.FindOneAndUpdate(ctx, filter, update, opts...)
+}
+
+func (c *Collection) Watch(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error) {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfRead); err != nil {
+		return nil, err
+	}
+	return c.Collection.Watch(ctx, pipeline, opts...)
+}
+
+func (c *Collection) Drop(ctx context.Context) error {
+	if err := c.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return err
+	}
+	return c.Collection.Drop(ctx)
+}
+
+// Clone clones the collection, the clone is limited by the limiter.
+func (c *Collection) Clone(opts ...*options.CollectionOptions) (*Collection, error) {
+	clone, err := c.Collection.Clone(opts...)
+	if err != nil {
+		return nil, err
+	}
+	return RateLimit(clone, c.limiter), nil
+}
+
+// Indexes get the index view of the collection, limited by the limiter.
+func (c *Collection) Indexes() IndexView {
+	return IndexView{IndexView: c.Collection.Indexes(), limiter: c.limiter}
+}
+
+// IndexView wraps an index view whose operations take a token of the
+// limiter before they are sent. Listings are reads and other
+// operations are writes.
+type IndexView struct {
+	mongo.IndexView
+	limiter *ratelimit.Limiter
+}
+
+func (v IndexView) List(ctx context.Context, opts ...*options.ListIndexesOptions) (*mongo.Cursor, error) {
+	if err := v.limiter.Take(ctx, ratelimit.ClassOfRead); err != nil {
+		return nil, err
+	}
+	return v.IndexView.List(ctx, opts...)
+}
+
+func (v IndexView) ListSpecifications(ctx context.Context, opts ...*options.ListIndexesOptions) ([]*mongo.IndexSpecification, error) {
+	if err := v.limiter.Take(ctx, ratelimit.ClassOfRead); err != nil {
+		return nil, err
+	}
+	return v.IndexView.ListSpecifications(ctx, opts...)
+}
+
+func (v IndexView) CreateOne(ctx context.Context, model mongo.IndexModel, opts ...*options.CreateIndexesOptions) (string, error) {
+	if err := v.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return "", err
+	}
+	return v.IndexView.CreateOne(ctx, model, opts...)
+}
+
+func (v IndexView) CreateMany(ctx context.Context, models []mongo.IndexModel, opts ...*options.CreateIndexesOptions) ([]string, error) {
+	if err := v.limiter.Take(ctx, bulkClass(len(models))); err != nil {
+		return nil, err
+	}
+	return v.IndexView.CreateMany(ctx, models, opts...)
+}
+
+func (v IndexView) DropOne(ctx context.Context, name string, opts ...*options.DropIndexesOptions) (bson.Raw, error) {
+	if err := v.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return nil, err
+	}
+	return v.IndexView.DropOne(ctx, name, opts...)
+}
+
+func (v IndexView) DropAll(ctx context.Context, opts ...*options.DropIndexesOptions) (bson.Raw, error) {
+	if err := v.limiter.Take(ctx, ratelimit.ClassOfWrite); err != nil {
+		return nil, err
+	}
+	return v.IndexView.DropAll(ctx, opts...)
+}
+
+// bulkClass returns the class of a write of n documents.
+func bulkClass(n int) ratelimit.Class {
+	if n > 1 {
+		return ratelimit.ClassOfBulk
+	}
+	return ratelimit.ClassOfWrite
+}
+
+// rejected returns the result of an operation the limiter rejected.
+func rejected(err error) *mongo.SingleResult {
+	return mongo.NewSingleResultFromDocument(bson.D{}, err, nil)
+}
@@ -0,0 +1,87 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coolstina/connecter/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestRateLimit(t *testing.T) {
+	// The client is never connected, the limiter rejects every operation.
+	client, err := mongo.NewClient(options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	assert.NoError(t, err)
+
+	limiter := ratelimit.New(
+		ratelimit.WithDefaultRate(0.001, 1),
+		ratelimit.WithMode(ratelimit.ModeOfFailFast),
+	)
+	for _, class := range []ratelimit.Class{ratelimit.ClassOfRead, ratelimit.ClassOfWrite, ratelimit.ClassOfBulk} {
+		assert.True(t, limiter.Allow(class))
+	}
+
+	collection := RateLimit(client.Database("connecter").Collection("users"), limiter)
+	ctx := context.Background()
+	document := bson.D{{Key: "name", Value: "helloshaohua"}}
+
+	_, err = collection.InsertOne(ctx, document)
+	assert.Equal(t, ratelimit.ErrLimited, err)
+
+	_, err = collection.InsertMany(ctx, []interface{}{document, document})
+	assert.Equal(t, ratelimit.ErrLimited, err)
+
+	_, err = collection.Find(ctx, document)
+	assert.Equal(t, ratelimit.ErrLimited, err)
+
+	assert.Equal(t, ratelimit.ErrLimited, collection.FindOne(ctx, document).Err())
+	assert.Equal(t, ratelimit.ErrLimited, collection.FindOne(ctx, document).Decode(&bson.M{}))
+	assert.Equal(t, ratelimit.ErrLimited, collection.FindOneAndDelete(ctx, document).Err())
+
+	_, err = collection.Aggregate(ctx, mongo.Pipeline{})
+	assert.Equal(t, ratelimit.ErrLimited, err)
+
+	_, err = collection.Distinct(ctx, "name", document)
+	assert.Equal(t, ratelimit.ErrLimited, err)
+
+	_, err = collection.Watch(ctx, mongo.Pipeline{})
+	assert.Equal(t, ratelimit.ErrLimited, err)
+
+	assert.Equal(t, ratelimit.ErrLimited, collection.Drop(ctx))
+
+	// Clones and index views keep the limiter.
+	clone, err := collection.Clone()
+	assert.NoError(t, err)
+	_, err = clone.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: document})
+	assert.Equal(t, ratelimit.ErrLimited, err)
+
+	_, err = collection.Indexes().List(ctx)
+	assert.Equal(t, ratelimit.ErrLimited, err)
+
+	stats := limiter.Stats()
+	assert.Equal(t, uint64(7), stats[ratelimit.ClassOfRead].Rejected)
+	assert.Equal(t, uint64(4), stats[ratelimit.ClassOfWrite].Rejected)
+	assert.Equal(t, uint64(1), stats[ratelimit.ClassOfBulk].Rejected)
+}
+
+func TestBulkClass(t *testing.T) {
+	assert.Equal(t, ratelimit.ClassOfWrite, bulkClass(1))
+	assert.Equal(t, ratelimit.ClassOfBulk, bulkClass(2))
+}
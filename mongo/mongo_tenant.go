@@ -0,0 +1,102 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+
+	"github.com/coolstina/connecter/tenant"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TenantConfig defines the connection and database of a tenant.
+type TenantConfig struct {
+	Host     string
+	Username string
+	Password string
+	Database string
+	Options  []Option
+}
+
+// TenantResolver resolves the connection config of a tenant.
+type TenantResolver interface {
+	Resolve(ctx context.Context, id string) (*TenantConfig, error)
+}
+
+// TenantResolverFunc is an adapter to allow the use of ordinary
+// functions as TenantResolver.
+type TenantResolverFunc func(ctx context.Context, id string) (*TenantConfig, error)
+
+// Resolve calls f(ctx, id).
+func (f TenantResolverFunc) Resolve(ctx context.Context, id string) (*TenantConfig, error) {
+	return f(ctx, id)
+}
+
+// TenantRouter routes each request to the database of the tenant
+// carried by its context, using one database per tenant.
+type TenantRouter struct {
+	cache *tenant.Cache
+}
+
+// NewTenantRouter initialize tenant router instance. Clients are
+// connected on first use and cached according to the cache options.
+func NewTenantRouter(resolver TenantResolver, cache ...tenant.Option) *TenantRouter {
+	open := func(ctx context.Context, id string) (interface{}, func() error, error) {
+		config, err := resolver.Resolve(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		client, err := NewConnection(config.Host, config.Username, config.Password, config.Options...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		disconnect := func() error {
+			return client.Disconnect(context.Background())
+		}
+
+		return client.Database(config.Database), disconnect, nil
+	}
+
+	return &TenantRouter{cache: tenant.NewCache(open, cache...)}
+}
+
+// Database returns the database of the tenant carried by ctx and the
+// function releasing it once no longer used. Evicted clients are
+// disconnected after their last release.
+func (r *TenantRouter) Database(ctx context.Context) (*mongo.Database, func(), error) {
+	id, err := tenant.MustFromContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, release, err := r.cache.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn.(*mongo.Database), release, nil
+}
+
+// Evict disconnects the client of the tenant if cached.
+func (r *TenantRouter) Evict(id string) error {
+	return r.cache.Evict(id)
+}
+
+// Close disconnects every tenant client.
+func (r *TenantRouter) Close() error {
+	return r.cache.Close()
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/coolstina/connecter/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantRouter(t *testing.T) {
+	var mu sync.Mutex
+	connected := make(map[string]*opts)
+	hosts := map[string]string{"t1": "127.0.0.1:27018", "t2": "127.0.0.2:27019"}
+
+	// The clients are never used, connecting doesn't reach the hosts.
+	router := NewTenantRouter(TenantResolverFunc(func(ctx context.Context, id string) (*TenantConfig, error) {
+		record := optionFunc(func(ops *opts) {
+			mu.Lock()
+			defer mu.Unlock()
+			connected[id] = ops
+		})
+		return &TenantConfig{Host: hosts[id], Username: id, Password: "secret", Database: id, Options: []Option{record}}, nil
+	}))
+	defer router.Close()
+
+	for id := range hosts {
+		database, release, err := router.Database(tenant.WithTenant(context.Background(), id))
+		assert.NoError(t, err)
+		assert.Equal(t, id, database.Name())
+		release()
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for id, host := range hosts {
+		clientOptions, err := clientOptions(connected[id])
+		assert.NoError(t, err)
+		assert.Equal(t, []string{host}, clientOptions.Hosts)
+		assert.Equal(t, id, clientOptions.Auth.Username)
+	}
+}
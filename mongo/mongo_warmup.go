@@ -0,0 +1,51 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/coolstina/connecter"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+type warmUp struct {
+	connections int
+	timeout     time.Duration
+	report      func(report connecter.WarmUpReport)
+}
+
+// WithWarmUp Specifies the number of pings NewConnection sends in
+// parallel before returning, bounded by the timeout. The report tells
+// how many succeeded, see WarmUp for the connections they open.
+func WithWarmUp(connections int, timeout time.Duration, report func(report connecter.WarmUpReport)) Option {
+	return optionFunc(func(ops *opts) {
+		ops.warmUp = &warmUp{connections: connections, timeout: timeout, report: report}
+	})
+}
+
+// WarmUp pings the primary n times in parallel. The driver can't hold
+// a pooled connection between operations, so a ping done early returns
+// its connection and a later ping may reuse it: the report counts
+// pings, not connections. Opened connections stay in the pool up to
+// maxPoolSize and maxIdleTimeMS, use minPoolSize to keep a number of
+// connections open.
+func WarmUp(ctx context.Context, client *mongo.Client, n int) connecter.WarmUpReport {
+	return connecter.WarmUp(ctx, connecter.DriverNameOfMongo, n, func(ctx context.Context) (func(), error) {
+		return nil, client.Ping(ctx, readpref.Primary())
+	})
+}
@@ -0,0 +1,214 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+const fakeDriverName = "connecter_fake"
+
+var fake = &fakeDriver{}
+
+func init() {
+	sql.Register(fakeDriverName, fake)
+}
+
+// fakeCall is a statement the fake driver received on a connection
+// opened with the data source name.
+type fakeCall struct {
+	DSN   string
+	Query string
+	Args  []interface{}
+}
+
+// fakeHandler answers a statement, nil rows answer it with no row.
+type fakeHandler func(query string, args []interface{}) (driver.Rows, error)
+
+// fakeDriver records every statement instead of talking to a server.
+type fakeDriver struct {
+	mu      sync.Mutex
+	calls   []fakeCall
+	handler fakeHandler
+}
+
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{driver: d, dsn: dsn}, nil
+}
+
+func (d *fakeDriver) record(dsn, query string, named []driver.NamedValue) (driver.Rows, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var args []interface{}
+	for _, arg := range named {
+		args = append(args, arg.Value)
+	}
+	d.calls = append(d.calls, fakeCall{DSN: dsn, Query: query, Args: args})
+
+	if d.handler == nil {
+		return nil, nil
+	}
+	return d.handler(query, args)
+}
+
+func (d *fakeDriver) handle(handler fakeHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handler = handler
+}
+
+func (d *fakeDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.calls = nil
+	d.handler = nil
+}
+
+func (d *fakeDriver) recorded() []fakeCall {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]fakeCall(nil), d.calls...)
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+	dsn    string
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake: prepare is not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx records the statements the MySQL driver sends.
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if level := sql.IsolationLevel(opts.Isolation); level != sql.LevelDefault {
+		if _, err := c.driver.record(c.dsn, "SET TRANSACTION ISOLATION LEVEL "+strings.ToUpper(level.String()), nil); err != nil {
+			return nil, err
+		}
+	}
+
+	query := "START TRANSACTION"
+	if opts.ReadOnly {
+		query += " READ ONLY"
+	}
+	if _, err := c.driver.record(c.dsn, query, nil); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	_, err := c.driver.record(c.dsn, "COMMIT", nil)
+	return err
+}
+
+func (c *fakeConn) Rollback() error {
+	_, err := c.driver.record(c.dsn, "ROLLBACK", nil)
+	return err
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	rows, err := c.driver.record(c.dsn, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if rows, ok := rows.(*fakeRows); ok {
+		return fakeResult{affected: rows.affected, lastInsertID: rows.lastInsertID}, nil
+	}
+	return fakeResult{}, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := c.driver.record(c.dsn, query, args)
+	if err != nil || rows != nil {
+		return rows, err
+	}
+	switch {
+	case strings.EqualFold(query, "SELECT VERSION()"):
+		return &fakeRows{columns: []string{"VERSION()"}, values: [][]driver.Value{{"8.0.27"}}}, nil
+	case strings.EqualFold(query, "SELECT DATABASE(), CONNECTION_ID()"):
+		return &fakeRows{columns: []string{"DATABASE()", "CONNECTION_ID()"}, values: [][]driver.Value{{"connecter", int64(7)}}}, nil
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+	// affected and lastInsertID are the result of the statements
+	// executed.
+	affected     int64
+	lastInsertID int64
+}
+
+type fakeResult struct {
+	affected     int64
+	lastInsertID int64
+}
+
+func (r fakeResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+
+func (r fakeResult) RowsAffected() (int64, error) { return r.affected, nil }
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// newFakeDB opens a gorm db on the fake driver.
+func newFakeDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DriverName:                fakeDriverName,
+		DSN:                       "fake",
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	assert.NoError(t, err)
+	return db
+}
+
+// queriesOf returns the statements of the calls.
+func queriesOf(calls []fakeCall) []string {
+	var queries []string
+	for _, call := range calls {
+		queries = append(queries, call.Query)
+	}
+	return queries
+}
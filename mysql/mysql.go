@@ -15,38 +15,104 @@
 package mysql
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
+	"net"
+	"strings"
+	"time"
 
+	"github.com/coolstina/connecter"
+	driver "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
 
 // NewConnection create a new gorm db instance with the given options.
 func NewConnection(config *Config, ops ...Option) (*gorm.DB, error) {
+	options := resolve(ops...)
+	driverName := driverNameOf(config)
+
+	if options.explain != nil && config.Logger != nil {
+		return nil, ErrExplainLogger
+	}
+
+	// A dry run capture never touches the database.
+	dryRun := options.capture != nil && !options.capture.options.execute
 
 	// If not exists then create.
-	err := CreateDatabaseIfNotExists(
-		config.DriverName.String(),
-		NewDataSourceNameForNoSelectDatabase(config.Host, config.Username, config.Password, ops...),
-		config.Database,
-	)
+	if !config.SkipCreateDatabase && !dryRun {
+		err := createDatabase(
+			driverName,
+			driverConfig(config.Host, config.Username, config.Password, "", options).FormatDSN(),
+			config.Database,
+			options,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+	}
 
+	dsn := driverConfig(config.Host, config.Username, config.Password, config.Database, options).FormatDSN()
+	conn, err := openDB(driverName, dsn, options)
 	if err != nil {
 		return nil, err
 	}
 
-	dsn := NewDataSourceNameForConfig(config, ops...)
-	opts := &gorm.Config{Logger: config.Logger}
+	var dialector gorm.Dialector = mysql.New(mysql.Config{
+		DriverName:                driverName,
+		DSN:                       dsn,
+		Conn:                      conn,
+		SkipInitializeWithVersion: dryRun,
+	})
 
-	db, err := gorm.Open(mysql.Open(dsn), opts)
+	if options.redactParams {
+		dialector = redactedDialector{dialector.(*mysql.Dialector)}
+	}
+
+	db, err := gorm.Open(dialector, gormConfig(config, options, dryRun))
 	if err != nil {
+		_ = conn.Close()
 		return nil, err
 	}
 
+	// Every error from now on closes the connection and its plugins.
+	fail := func(err error) (*gorm.DB, error) {
+		_ = Close(db)
+		return nil, err
+	}
+
+	if e := explainerOf(db.Logger); e != nil {
+		if err := db.Use(e); err != nil {
+			return fail(err)
+		}
+	}
+
+	// Registered even without capture, CaptureSQL sessions need it.
+	if err := db.Use(&capturePlugin{capture: options.capture}); err != nil {
+		return fail(err)
+	}
+
+	if len(config.Replicas) > 0 && !dryRun {
+		if err := db.Use(NewReplicaSet(config, ops...)); err != nil {
+			return fail(err)
+		}
+	}
+
+	if options.auditor != nil {
+		if err := RegisterAudit(db, options.auditor); err != nil {
+			return fail(err)
+		}
+	}
+
+	if options.limiter != nil {
+		if err := RegisterRateLimit(db, options.limiter); err != nil {
+			return fail(err)
+		}
+	}
+
 	sqlDB, err := db.DB()
 	if err != nil {
-		return nil, err
+		return fail(err)
 	}
 
 	// SetMaxOpenConns sets the maximum number of open connections to the database.
@@ -58,69 +124,199 @@ func NewConnection(config *Config, ops ...Option) (*gorm.DB, error) {
 	// SetMaxIdleConns sets the maximum number of connections in the idle connection pool.
 	sqlDB.SetMaxIdleConns(config.MaxIdleConnections)
 
+	if dryRun {
+		db.ConnPool = &capturePool{ConnPool: db.ConnPool}
+		db.Statement.ConnPool = db.ConnPool
+		return db, nil
+	}
+
+	// Read the session variables back, the server may ignore or rewrite them.
+	if err := verifySession(context.Background(), sqlDB, options); err != nil {
+		return fail(err)
+	}
+
+	if options.warmUp != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), options.warmUp.timeout)
+		report := WarmUp(ctx, db, options.warmUp.connections)
+		cancel()
+
+		if options.warmUp.report != nil {
+			options.warmUp.report(report)
+		}
+	}
+
 	return db, nil
 }
 
+// Close closes the connection of NewConnection, along with its replicas
+// and explain connection, it returns the first error met.
+func Close(db *gorm.DB) error {
+	var first error
+	if set, ok := Replicas(db); ok {
+		first = set.Close()
+	}
+
+	if e, ok := db.Config.Plugins[explainName].(*explainer); ok {
+		if err := e.Close(); first == nil {
+			first = err
+		}
+	}
+
+	sqlDB, err := db.DB()
+	if err == nil {
+		err = sqlDB.Close()
+	}
+	if first == nil {
+		first = err
+	}
+	return first
+}
+
+// WarmUp opens n pool connections in parallel and returns them idle
+// to the pool, the report tells how many were established before ctx
+// was done.
+func WarmUp(ctx context.Context, db *gorm.DB, n int) connecter.WarmUpReport {
+	return connecter.WarmUp(ctx, connecter.DriverNameOfMySQL, n, func(ctx context.Context) (func(), error) {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := sqlDB.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := conn.PingContext(ctx); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+
+		return func() { _ = conn.Close() }, nil
+	})
+}
+
 // NewDataSourceNameForConfig Get data source name for given options.
 func NewDataSourceNameForConfig(config *Config, ops ...Option) string {
 	return NewDataSourceName(config.Host, config.Username, config.Password, config.Database, ops...)
 }
 
+// NewDataSourceNameForNoSelectDatabase Get data source name without choosing database.
 func NewDataSourceNameForNoSelectDatabase(host, username, password string, ops ...Option) string {
-	return NewDataSourceName(host, username, password, "")
+	return NewDataSourceName(host, username, password, "", ops...)
 }
 
 // NewDataSourceName initialize database of the data source name.
 // If database parameter is empty, will not choose database, such as only open database connection.
 func NewDataSourceName(host, username, password, database string, ops ...Option) string {
-	options := &options{
-		charset:   "utf8mb4",
-		parseTime: true,
-		location:  "Local",
+	return NewDriverConfig(host, username, password, database, ops...).FormatDSN()
+}
+
+// NewDriverConfig initialize the driver config the data source name is
+// formatted from. The host defaults to port 3306 when it has none.
+func NewDriverConfig(host, username, password, database string, ops ...Option) *driver.Config {
+	return driverConfig(host, username, password, database, resolve(ops...))
+}
+
+func driverConfig(host, username, password, database string, options *options) *driver.Config {
+	config := driver.NewConfig()
+	config.User = username
+	config.Passwd = password
+	config.DBName = database
+	config.Net = "tcp"
+	config.Addr = hostWithPort(host)
+	config.ParseTime = options.parseTime
+	config.Timeout = options.timeout
+	config.ReadTimeout = options.readTimeout
+	config.WriteTimeout = options.writeTimeout
+	config.TLSConfig = options.tls
+	config.InterpolateParams = options.interpolateParams
+	config.MultiStatements = options.multiStatements
+	config.Params = map[string]string{}
+
+	if options.socket != "" {
+		config.Net = "unix"
+		config.Addr = options.socket
 	}
 
-	for _, o := range ops {
-		o.apply(options)
+	if options.collation != "" {
+		config.Collation = options.collation
 	}
 
-	args := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=%s&parseTime=%t&loc=%s",
-		username,
-		password,
-		host,
-		database,
-		options.charset,
-		options.parseTime,
-		options.location,
-	)
+	if options.maxAllowedPacket != nil {
+		config.MaxAllowedPacket = *options.maxAllowedPacket
+	}
 
-	return args
-}
+	if options.allowNativePasswords != nil {
+		config.AllowNativePasswords = *options.allowNativePasswords
+	}
 
-// CreateDatabaseIfNotExists If database not exists, then create it.
-func CreateDatabaseIfNotExists(driverName, dataSourceName, databaseName string, ops ...Option) error {
-	options := &options{
-		charset: "utf8mb4",
+	// Unknown locations are passed through for the driver to report on open.
+	if location, err := time.LoadLocation(options.location); err == nil {
+		config.Loc = location
+	} else {
+		config.Params["loc"] = options.location
+	}
+
+	if options.charset != "" {
+		config.Params["charset"] = options.charset
 	}
 
-	for _, o := range ops {
-		o.apply(options)
+	for name, value := range options.params {
+		config.Params[name] = value
 	}
 
-	db, err := sql.Open(driverName, dataSourceName)
+	return config
+}
+
+// hostWithPort appends the default MySQL port to host if it has none.
+func hostWithPort(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil || host == "" {
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), "3306")
+}
+
+// CreateDatabaseIfNotExists If database not exists, then create it.
+// The application user of WithApplicationUser is created and granted
+// privileges on it as well.
+func CreateDatabaseIfNotExists(driverName, dataSourceName, databaseName string, ops ...Option) error {
+	return createDatabase(driverName, dataSourceName, databaseName, resolve(ops...))
+}
+
+// DropDatabaseIfExists If database exists, then drop it, such as
+// when tearing down a test database.
+func DropDatabaseIfExists(driverName, dataSourceName, databaseName string) error {
+	database, err := QuoteIdentifier(databaseName)
 	if err != nil {
 		return err
 	}
-	defer db.Close()
 
-	s := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s` DEFAULT CHARACTER SET %s", databaseName, options.charset)
-	if _, err = db.Exec(s); err != nil {
-		return err
+	return execute(driverName, dataSourceName, []string{"DROP DATABASE IF EXISTS " + database})
+}
+
+// gormConfig builds the gorm configuration for the connection, a dry
+// run neither pings the database nor begins default transactions.
+func gormConfig(config *Config, options *options, dryRun bool) *gorm.Config {
+	return &gorm.Config{
+		Logger:                 newLogger(config, options),
+		DryRun:                 dryRun,
+		SkipDefaultTransaction: dryRun,
+		DisableAutomaticPing:   dryRun,
 	}
+}
 
-	return nil
+// driverNameOf returns the database/sql driver name of the config,
+// the MySQL driver if it has none.
+func driverNameOf(config *Config) string {
+	if config.DriverName == "" {
+		return connecter.DriverNameOfMySQL.String()
+	}
+	return config.DriverName.String()
 }
 
-// GormComment get table comment for the description.
+// GormComment get table comment for the description, quoted so it may
+// contain quotes and backslashes.
 func GormComment(description string) string {
-	return fmt.Sprintf("comment '%s'", description)
+	return "comment " + quoteString(description)
 }
@@ -0,0 +1,229 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/coolstina/connecter"
+	"github.com/coolstina/connecter/audit"
+	"gorm.io/gorm"
+)
+
+const skipAuditKey = "connecter:skip_audit"
+
+// RegisterAudit registers gorm callbacks that record every create,
+// update and delete executed through db to the auditor, including the
+// INSERT, REPLACE, UPDATE, DELETE and TRUNCATE statements run by Exec.
+func RegisterAudit(db *gorm.DB, auditor *audit.Auditor) error {
+	callbacks := []struct {
+		operation audit.Operation
+		register  func(name string, fn func(*gorm.DB)) error
+	}{
+		{audit.OperationOfCreate, db.Callback().Create().After("gorm:create").Register},
+		{audit.OperationOfUpdate, db.Callback().Update().After("gorm:update").Register},
+		{audit.OperationOfDelete, db.Callback().Delete().After("gorm:delete").Register},
+		{audit.OperationOfWrite, db.Callback().Raw().After("gorm:raw").Register},
+	}
+
+	for _, callback := range callbacks {
+		operation := callback.operation
+		name := "connecter:audit_" + operation.String()
+		err := callback.register(name, func(db *gorm.DB) {
+			if _, ok := db.Get(skipAuditKey); ok || db.DryRun {
+				return
+			}
+
+			if operation != audit.OperationOfWrite {
+				auditor.Record(db.Statement.Context, auditEvent(db, operation))
+				return
+			}
+
+			// Exec runs any statement, only the ones writing rows are audited.
+			event, ok := rawAuditEvent(db)
+			if ok {
+				auditor.Record(db.Statement.Context, event)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rawStatements defines the audited statements run by Exec, with the
+// keywords before their table.
+var rawStatements = []struct {
+	prefix    string
+	operation audit.Operation
+}{
+	{"INSERT INTO ", audit.OperationOfCreate},
+	{"INSERT IGNORE INTO ", audit.OperationOfCreate},
+	{"INSERT ", audit.OperationOfCreate},
+	{"REPLACE INTO ", audit.OperationOfCreate},
+	{"REPLACE ", audit.OperationOfCreate},
+	{"UPDATE ", audit.OperationOfUpdate},
+	{"DELETE FROM ", audit.OperationOfDelete},
+	{"TRUNCATE TABLE ", audit.OperationOfDelete},
+	{"TRUNCATE ", audit.OperationOfDelete},
+}
+
+// rawAuditEvent returns the event of the statement run by Exec, false
+// for the statements that write no rows.
+func rawAuditEvent(db *gorm.DB) (audit.Event, bool) {
+	statement := strings.TrimSpace(db.Statement.SQL.String())
+	for _, raw := range rawStatements {
+		if len(statement) < len(raw.prefix) || !strings.EqualFold(statement[:len(raw.prefix)], raw.prefix) {
+			continue
+		}
+
+		event := auditEvent(db, raw.operation)
+		if event.Target == "" {
+			event.Target = rawTarget(statement[len(raw.prefix):])
+		}
+		return event, true
+	}
+
+	return audit.Event{}, false
+}
+
+// rawTarget returns the table at the start of the statement rest,
+// without quotes.
+func rawTarget(rest string) string {
+	fields := strings.FieldsFunc(rest, func(r rune) bool {
+		return r == ' ' || r == '(' || r == '\n' || r == '\t'
+	})
+	if len(fields) == 0 {
+		return ""
+	}
+	return strings.ReplaceAll(fields[0], "`", "")
+}
+
+func auditEvent(db *gorm.DB, operation audit.Operation) audit.Event {
+	event := audit.Event{
+		Backend:   connecter.DriverNameOfMySQL,
+		Operation: operation,
+		Target:    db.Statement.Table,
+		Statement: db.Statement.SQL.String(),
+		Fields:    auditFields(db.Statement),
+		Affected:  db.RowsAffected,
+	}
+
+	if db.Error != nil {
+		event.Error = db.Error.Error()
+	}
+
+	return event
+}
+
+// auditFields collects the assigned columns, only single struct
+// and map destinations are expanded, batches are recorded by count.
+func auditFields(stmt *gorm.Statement) map[string]interface{} {
+	if dest, ok := stmt.Dest.(map[string]interface{}); ok {
+		fields := make(map[string]interface{}, len(dest))
+		for key, value := range dest {
+			fields[key] = value
+		}
+		return fields
+	}
+
+	if stmt.Schema == nil || stmt.Dest == nil {
+		return nil
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(stmt.Dest))
+	if value.Kind() != reflect.Struct || value.Type() != stmt.Schema.ModelType {
+		return nil
+	}
+
+	fields := make(map[string]interface{})
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		if v, zero := field.ValueOf(value); !zero {
+			fields[field.DBName] = v
+		}
+	}
+
+	return fields
+}
+
+// AuditRecord defines the table row written by the MySQL audit sink.
+type AuditRecord struct {
+	ID        uint64    `gorm:"primaryKey;autoIncrement"`
+	Time      time.Time `gorm:"index"`
+	Backend   string    `gorm:"size:32"`
+	Operation string    `gorm:"size:32"`
+	Target    string    `gorm:"size:255"`
+	Statement string    `gorm:"type:text"`
+	Fields    string    `gorm:"type:text"`
+	Affected  int64
+	ActorID   string `gorm:"size:191;index"`
+	Actor     string `gorm:"type:text"`
+	Error     string `gorm:"type:text"`
+}
+
+type auditSink struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewAuditSink initialize audit sink that stores events in the given
+// table, the table will be created if not exists. Writes made by the
+// sink itself are never audited.
+func NewAuditSink(db *gorm.DB, table string) (audit.Sink, error) {
+	if err := db.Table(table).AutoMigrate(&AuditRecord{}); err != nil {
+		return nil, err
+	}
+	return &auditSink{db: db, table: table}, nil
+}
+
+func (s *auditSink) Write(ctx context.Context, event *audit.Event) error {
+	record := &AuditRecord{
+		Time:      event.Time,
+		Backend:   event.Backend.String(),
+		Operation: event.Operation.String(),
+		Target:    event.Target,
+		Statement: event.Statement,
+		Affected:  event.Affected,
+		Error:     event.Error,
+	}
+
+	if event.Fields != nil {
+		fields, err := json.Marshal(event.Fields)
+		if err != nil {
+			return err
+		}
+		record.Fields = string(fields)
+	}
+
+	if event.Actor != nil {
+		actor, err := json.Marshal(event.Actor)
+		if err != nil {
+			return err
+		}
+		record.ActorID = event.Actor.ID
+		record.Actor = string(actor)
+	}
+
+	return s.db.WithContext(ctx).Set(skipAuditKey, true).Table(s.table).Create(record).Error
+}
@@ -0,0 +1,130 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/coolstina/connecter"
+	"github.com/coolstina/connecter/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAuditTestDB(t *testing.T) (*Config, chan audit.Event) {
+	fake.reset()
+	events := make(chan audit.Event, 8)
+	return &Config{
+		Database:           "connecter",
+		DriverName:         connecter.DriverName(fakeDriverName),
+		SkipCreateDatabase: true,
+	}, events
+}
+
+func TestRegisterAudit(t *testing.T) {
+	config, events := newAuditTestDB(t)
+	db, err := NewConnection(config, WithAudit(audit.New(audit.NewChannelSink(events))))
+	assert.NoError(t, err)
+
+	ctx := audit.WithActor(context.Background(), audit.Actor{ID: "u1"})
+	db = db.WithContext(ctx)
+
+	assert.NoError(t, db.Create(&user{Name: "helloshaohua"}).Error)
+	event := <-events
+	assert.Equal(t, connecter.DriverNameOfMySQL, event.Backend)
+	assert.Equal(t, audit.OperationOfCreate, event.Operation)
+	assert.Equal(t, "users", event.Target)
+	assert.Equal(t, "helloshaohua", event.Fields["name"])
+	assert.Equal(t, "u1", event.Actor.ID)
+
+	assert.NoError(t, db.Model(&user{ID: 1}).Update("name", "coolstina").Error)
+	event = <-events
+	assert.Equal(t, audit.OperationOfUpdate, event.Operation)
+
+	assert.NoError(t, db.Delete(&user{ID: 1}).Error)
+	event = <-events
+	assert.Equal(t, audit.OperationOfDelete, event.Operation)
+
+	// Queries are not audited.
+	assert.NoError(t, db.Find(&[]user{}).Error)
+	assert.Empty(t, events)
+}
+
+func TestRegisterAudit_Exec(t *testing.T) {
+	config, events := newAuditTestDB(t)
+	db, err := NewConnection(config, WithAudit(audit.New(audit.NewChannelSink(events))))
+	assert.NoError(t, err)
+
+	grids := []struct {
+		statement string
+		operation audit.Operation
+		target    string
+	}{
+		{statement: "INSERT INTO `users` (`name`) VALUES (?)", operation: audit.OperationOfCreate, target: "users"},
+		{statement: "insert ignore into users(name) VALUES (?)", operation: audit.OperationOfCreate, target: "users"},
+		{statement: "REPLACE INTO users (name) VALUES (?)", operation: audit.OperationOfCreate, target: "users"},
+		{statement: "UPDATE users SET name = ?", operation: audit.OperationOfUpdate, target: "users"},
+		{statement: "DELETE FROM `connecter`.`users` WHERE name = ?", operation: audit.OperationOfDelete, target: "connecter.users"},
+	}
+
+	for _, grid := range grids {
+		assert.NoError(t, db.Exec(grid.statement, "helloshaohua").Error)
+		event := <-events
+		assert.Equal(t, grid.operation, event.Operation, grid.statement)
+		assert.Equal(t, grid.target, event.Target, grid.statement)
+		assert.Equal(t, grid.statement, event.Statement)
+	}
+
+	assert.NoError(t, db.Exec("TRUNCATE TABLE users").Error)
+	assert.Equal(t, audit.OperationOfDelete, (<-events).Operation)
+
+	// Statements writing no rows are not audited.
+	assert.NoError(t, db.Exec("CREATE TABLE IF NOT EXISTS users (id BIGINT)").Error)
+	assert.NoError(t, db.Exec("SET NAMES utf8mb4").Error)
+	assert.Empty(t, events)
+}
+
+func TestNewAuditSink(t *testing.T) {
+	config, events := newAuditTestDB(t)
+	db, err := NewConnection(config, WithAudit(audit.New(audit.NewChannelSink(events))))
+	assert.NoError(t, err)
+
+	sink, err := NewAuditSink(db, "audit_records")
+	assert.NoError(t, err)
+
+	fake.reset()
+	assert.NoError(t, sink.Write(context.Background(), &audit.Event{
+		Backend:   connecter.DriverNameOfMySQL,
+		Operation: audit.OperationOfCreate,
+		Target:    "users",
+		Fields:    map[string]interface{}{"name": "helloshaohua"},
+		Actor:     &audit.Actor{ID: "u1"},
+	}))
+
+	// The writes of the sink are not audited themselves.
+	assert.Empty(t, events)
+	var insert *fakeCall
+	calls := fake.recorded()
+	for i := range calls {
+		if strings.HasPrefix(calls[i].Query, "INSERT INTO `audit_records`") {
+			insert = &calls[i]
+		}
+	}
+	if assert.NotNil(t, insert) {
+		assert.Contains(t, insert.Args, `{"name":"helloshaohua"}`)
+		assert.Contains(t, insert.Args, "u1")
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidIdentifier is returned for names MySQL would reject or
+// that cannot be used safely in a statement.
+var ErrInvalidIdentifier = errors.New("mysql: invalid identifier")
+
+var (
+	// keywordPattern matches charset and collation names.
+	keywordPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	// privilegePattern matches privilege names, such as SELECT or ALL PRIVILEGES.
+	privilegePattern = regexp.MustCompile(`^[A-Za-z]+( [A-Za-z]+)*$`)
+)
+
+// QuoteIdentifier validates name and quotes it with backticks, the
+// backticks it contains are doubled.
+func QuoteIdentifier(name string) (string, error) {
+	switch {
+	case name == "", utf8.RuneCountInString(name) > 64, !utf8.ValidString(name):
+		return "", fmt.Errorf("%w: %q", ErrInvalidIdentifier, name)
+	case strings.ContainsRune(name, 0), strings.HasSuffix(name, " "):
+		return "", fmt.Errorf("%w: %q", ErrInvalidIdentifier, name)
+	}
+
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`", nil
+}
+
+// quoteString quotes s as a string literal.
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\x00", `\0`).Replace(s) + "'"
+}
+
+// grantPattern escapes the _ and % wildcards of a quoted database name,
+// GRANT would otherwise match other databases with it.
+func grantPattern(database string) string {
+	return strings.NewReplacer("_", `\_`, "%", `\%`).Replace(database)
+}
+
+func keyword(kind, name string) (string, error) {
+	if !keywordPattern.MatchString(name) {
+		return "", fmt.Errorf("%w: %s %q", ErrInvalidIdentifier, kind, name)
+	}
+	return name, nil
+}
+
+func createDatabase(driverName, dataSourceName, databaseName string, options *options) error {
+	statements, err := bootstrapStatements(databaseName, options)
+	if err != nil {
+		return err
+	}
+
+	return execute(driverName, dataSourceName, statements)
+}
+
+// bootstrapStatements builds the statements creating the database with
+// the same charset and collation the connections are opened with, and
+// the application user if any.
+func bootstrapStatements(databaseName string, options *options) ([]string, error) {
+	database, err := QuoteIdentifier(databaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	s := "CREATE DATABASE IF NOT EXISTS " + database
+	if options.charset != "" {
+		charset, err := keyword("charset", options.charset)
+		if err != nil {
+			return nil, err
+		}
+		s += " DEFAULT CHARACTER SET " + charset
+	}
+
+	if options.collation != "" {
+		collation, err := keyword("collation", options.collation)
+		if err != nil {
+			return nil, err
+		}
+		s += " COLLATE " + collation
+	}
+
+	if options.encryption != nil {
+		encryption := "N"
+		if *options.encryption {
+			encryption = "Y"
+		}
+		s += " DEFAULT ENCRYPTION '" + encryption + "'"
+	}
+
+	statements := []string{s}
+	if user := options.applicationUser; user != nil {
+		if user.Username == "" {
+			return nil, fmt.Errorf("%w: empty application user", ErrInvalidIdentifier)
+		}
+
+		host := user.Host
+		if host == "" {
+			host = "%"
+		}
+
+		privileges := user.Privileges
+		if len(privileges) == 0 {
+			privileges = []string{"ALL PRIVILEGES"}
+		}
+
+		for _, privilege := range privileges {
+			if !privilegePattern.MatchString(privilege) {
+				return nil, fmt.Errorf("%w: privilege %q", ErrInvalidIdentifier, privilege)
+			}
+		}
+
+		account := quoteString(user.Username) + "@" + quoteString(host)
+		statements = append(statements,
+			fmt.Sprintf("CREATE USER IF NOT EXISTS %s IDENTIFIED BY %s", account, quoteString(user.Password)),
+			fmt.Sprintf("GRANT %s ON %s.* TO %s", strings.ToUpper(strings.Join(privileges, ", ")), grantPattern(database), account),
+		)
+	}
+
+	return statements, nil
+}
+
+// execute runs the statements in order on a connection opened for them.
+func execute(driverName, dataSourceName string, statements []string) error {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, s := range statements {
+		if _, err = db.Exec(s); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coolstina/connecter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestBootstrapSuite(t *testing.T) {
+	suite.Run(t, &BootstrapSuite{})
+}
+
+type BootstrapSuite struct {
+	suite.Suite
+	config *Config
+}
+
+func (s *BootstrapSuite) SetupTest() {
+	fake.reset()
+	s.config = &Config{
+		Host:       "127.0.0.1",
+		Username:   "root",
+		Password:   "p@ss",
+		Database:   "connecter",
+		DriverName: connecter.DriverName(fakeDriverName),
+	}
+}
+
+func (s *BootstrapSuite) Test_NewConnection() {
+	grid := []struct {
+		name     string
+		ops      []Option
+		create   string
+		bootDSN  string
+		finalDSN string
+	}{
+		{
+			name:     "defaults",
+			create:   "CREATE DATABASE IF NOT EXISTS `connecter` DEFAULT CHARACTER SET utf8mb4",
+			bootDSN:  "root:p@ss@tcp(127.0.0.1:3306)/?loc=Local&parseTime=true&charset=utf8mb4",
+			finalDSN: "root:p@ss@tcp(127.0.0.1:3306)/connecter?loc=Local&parseTime=true&charset=utf8mb4",
+		},
+		{
+			name:     "charset",
+			ops:      []Option{WithCharset("latin1")},
+			create:   "CREATE DATABASE IF NOT EXISTS `connecter` DEFAULT CHARACTER SET latin1",
+			bootDSN:  "root:p@ss@tcp(127.0.0.1:3306)/?loc=Local&parseTime=true&charset=latin1",
+			finalDSN: "root:p@ss@tcp(127.0.0.1:3306)/connecter?loc=Local&parseTime=true&charset=latin1",
+		},
+		{
+			name:     "charset and collation",
+			ops:      []Option{WithCharset("utf8mb4"), WithCollation("utf8mb4_unicode_ci")},
+			create:   "CREATE DATABASE IF NOT EXISTS `connecter` DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci",
+			bootDSN:  "root:p@ss@tcp(127.0.0.1:3306)/?collation=utf8mb4_unicode_ci&loc=Local&parseTime=true&charset=utf8mb4",
+			finalDSN: "root:p@ss@tcp(127.0.0.1:3306)/connecter?collation=utf8mb4_unicode_ci&loc=Local&parseTime=true&charset=utf8mb4",
+		},
+		{
+			name:     "no charset",
+			ops:      []Option{WithCharset("")},
+			create:   "CREATE DATABASE IF NOT EXISTS `connecter`",
+			bootDSN:  "root:p@ss@tcp(127.0.0.1:3306)/?loc=Local&parseTime=true",
+			finalDSN: "root:p@ss@tcp(127.0.0.1:3306)/connecter?loc=Local&parseTime=true",
+		},
+		{
+			name:     "location and parse time",
+			ops:      []Option{WithLocation("UTC"), WithParseTime(false)},
+			create:   "CREATE DATABASE IF NOT EXISTS `connecter` DEFAULT CHARACTER SET utf8mb4",
+			bootDSN:  "root:p@ss@tcp(127.0.0.1:3306)/?charset=utf8mb4",
+			finalDSN: "root:p@ss@tcp(127.0.0.1:3306)/connecter?charset=utf8mb4",
+		},
+		{
+			name:     "timeouts and tls",
+			ops:      []Option{WithTimeout(time.Second), WithReadTimeout(2 * time.Second), WithTLS("preferred")},
+			create:   "CREATE DATABASE IF NOT EXISTS `connecter` DEFAULT CHARACTER SET utf8mb4",
+			bootDSN:  "root:p@ss@tcp(127.0.0.1:3306)/?loc=Local&parseTime=true&readTimeout=2s&timeout=1s&tls=preferred&charset=utf8mb4",
+			finalDSN: "root:p@ss@tcp(127.0.0.1:3306)/connecter?loc=Local&parseTime=true&readTimeout=2s&timeout=1s&tls=preferred&charset=utf8mb4",
+		},
+		{
+			name:     "unix socket and params",
+			ops:      []Option{WithUnixSocket("/tmp/mysql.sock"), WithParam("sql_mode", "'STRICT_ALL_TABLES'")},
+			create:   "CREATE DATABASE IF NOT EXISTS `connecter` DEFAULT CHARACTER SET utf8mb4",
+			bootDSN:  "root:p@ss@unix(/tmp/mysql.sock)/?loc=Local&parseTime=true&charset=utf8mb4&sql_mode=%27STRICT_ALL_TABLES%27",
+			finalDSN: "root:p@ss@unix(/tmp/mysql.sock)/connecter?loc=Local&parseTime=true&charset=utf8mb4&sql_mode=%27STRICT_ALL_TABLES%27",
+		},
+	}
+
+	for _, g := range grid {
+		s.Run(g.name, func() {
+			fake.reset()
+
+			db, err := NewConnection(s.config, g.ops...)
+			s.Require().NoError(err)
+			s.NotNil(db)
+
+			calls := fake.recorded()
+			s.Require().Len(calls, 2)
+			s.Equal(fakeCall{DSN: g.bootDSN, Query: g.create}, calls[0])
+			s.Equal(fakeCall{DSN: g.finalDSN, Query: "SELECT VERSION()"}, calls[1])
+
+			s.Equal(g.bootDSN, NewDataSourceNameForNoSelectDatabase(s.config.Host, s.config.Username, s.config.Password, g.ops...))
+			s.Equal(g.finalDSN, NewDataSourceNameForConfig(s.config, g.ops...))
+		})
+	}
+}
+
+func (s *BootstrapSuite) Test_CreateDatabaseIfNotExists() {
+	dsn := NewDataSourceNameForNoSelectDatabase(s.config.Host, s.config.Username, s.config.Password)
+	err := CreateDatabaseIfNotExists(fakeDriverName, dsn, "connecter", WithCharset("gbk"), WithCollation("gbk_chinese_ci"))
+	s.NoError(err)
+
+	s.Equal([]fakeCall{{
+		DSN:   dsn,
+		Query: "CREATE DATABASE IF NOT EXISTS `connecter` DEFAULT CHARACTER SET gbk COLLATE gbk_chinese_ci",
+	}}, fake.recorded())
+}
+
+func (s *BootstrapSuite) Test_NewConnection_SkipCreateDatabase() {
+	s.config.SkipCreateDatabase = true
+
+	db, err := NewConnection(s.config)
+	s.Require().NoError(err)
+	s.NotNil(db)
+
+	calls := fake.recorded()
+	s.Require().Len(calls, 1)
+	s.Equal("SELECT VERSION()", calls[0].Query)
+}
+
+func (s *BootstrapSuite) Test_NewConnection_ApplicationUser() {
+	_, err := NewConnection(s.config,
+		WithCollation("utf8mb4_0900_ai_ci"),
+		WithEncryption(true),
+		WithApplicationUser(ApplicationUser{
+			Username:   "app",
+			Password:   `it's\secret`,
+			Privileges: []string{"select", "INSERT", "UPDATE", "DELETE"},
+		}),
+	)
+	s.Require().NoError(err)
+
+	var queries []string
+	for _, call := range fake.recorded() {
+		queries = append(queries, call.Query)
+	}
+
+	s.Equal([]string{
+		"CREATE DATABASE IF NOT EXISTS `connecter` DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci DEFAULT ENCRYPTION 'Y'",
+		`CREATE USER IF NOT EXISTS 'app'@'%' IDENTIFIED BY 'it\'s\\secret'`,
+		"GRANT SELECT, INSERT, UPDATE, DELETE ON `connecter`.* TO 'app'@'%'",
+		"SELECT VERSION()",
+	}, queries)
+}
+
+func (s *BootstrapSuite) Test_NewConnection_Invalid() {
+	grid := []struct {
+		name     string
+		database string
+		ops      []Option
+	}{
+		{name: "empty database", database: ""},
+		{name: "long database", database: strings.Repeat("d", 65)},
+		{name: "trailing space", database: "connecter "},
+		{name: "charset", database: "connecter", ops: []Option{WithCharset("utf8mb4; DROP DATABASE mysql")}},
+		{name: "collation", database: "connecter", ops: []Option{WithCollation("utf8mb4_bin'")}},
+		{name: "privilege", database: "connecter", ops: []Option{WithApplicationUser(ApplicationUser{
+			Username: "app", Privileges: []string{"ALL ON *.* TO root"},
+		})}},
+		{name: "empty user", database: "connecter", ops: []Option{WithApplicationUser(ApplicationUser{})}},
+	}
+
+	for _, g := range grid {
+		s.Run(g.name, func() {
+			fake.reset()
+			s.config.Database = g.database
+
+			_, err := NewConnection(s.config, g.ops...)
+			s.ErrorIs(err, ErrInvalidIdentifier)
+			s.Empty(fake.recorded())
+		})
+	}
+}
+
+func (s *BootstrapSuite) Test_DropDatabaseIfExists() {
+	err := DropDatabaseIfExists(fakeDriverName, "root@/", "test`db")
+	s.NoError(err)
+	s.Equal([]fakeCall{{DSN: "root@/", Query: "DROP DATABASE IF EXISTS `test``db`"}}, fake.recorded())
+
+	fake.reset()
+	s.ErrorIs(DropDatabaseIfExists(fakeDriverName, "root@/", ""), ErrInvalidIdentifier)
+	s.Empty(fake.recorded())
+}
+
+func TestBootstrapStatements_Grant(t *testing.T) {
+	options := &options{applicationUser: &ApplicationUser{Username: "app", Privileges: []string{"SELECT"}}}
+
+	statements, err := bootstrapStatements("app_db%1", options)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"CREATE DATABASE IF NOT EXISTS `app_db%1`",
+		`CREATE USER IF NOT EXISTS 'app'@'%' IDENTIFIED BY ''`,
+		"GRANT SELECT ON `app\\_db\\%1`.* TO 'app'@'%'",
+	}, statements)
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	grid := []struct {
+		name     string
+		expected string
+		valid    bool
+	}{
+		{name: "users", expected: "`users`", valid: true},
+		{name: "order`s", expected: "`order``s`", valid: true},
+		{name: "数据库", expected: "`数据库`", valid: true},
+		{name: strings.Repeat("n", 64), expected: "`" + strings.Repeat("n", 64) + "`", valid: true},
+		{name: strings.Repeat("n", 65)},
+		{name: ""},
+		{name: "users "},
+		{name: "us\x00ers"},
+		{name: "\xff"},
+	}
+
+	for _, g := range grid {
+		actual, err := QuoteIdentifier(g.name)
+		if !g.valid {
+			assert.ErrorIs(t, err, ErrInvalidIdentifier, g.name)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, g.expected, actual)
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotSlice is returned when the rows of a bulk statement are not a
+// slice or an array.
+var ErrNotSlice = errors.New("mysql: bulk rows must be a slice")
+
+// packetOverhead is left out of max_allowed_packet for the protocol.
+const packetOverhead = 1024
+
+// BulkChunk defines the result of a statement of a bulk call.
+type BulkChunk struct {
+	// Offset is the index of the first row of the chunk.
+	Offset int
+	Rows   int
+	// Bytes is the size of the statement with its parameters.
+	Bytes int
+	// Affected counts 1 per inserted row and 2 per updated row.
+	Affected int64
+	Err      error
+}
+
+// BulkReport defines the results of a bulk call.
+type BulkReport struct {
+	Chunks   []BulkChunk
+	Affected int64
+}
+
+// Err returns the error of the first failed chunk.
+func (r BulkReport) Err() error {
+	for _, chunk := range r.Chunks {
+		if chunk.Err != nil {
+			return chunk.Err
+		}
+	}
+	return nil
+}
+
+// BulkInsert inserts rows, a slice of models, with multi-row INSERT
+// statements chunked by row count and packet size. Each chunk is a
+// gorm Create, so the model hooks and associations run and the auto
+// increment keys are set on rows, the chunk sizes are estimated
+// without the hooks.
+func BulkInsert(ctx context.Context, db *gorm.DB, rows interface{}, ops ...BulkOption) (BulkReport, error) {
+	return bulk(ctx, db, rows, nil, ops...)
+}
+
+// BulkUpsert inserts rows as BulkInsert does, updating the rows with a
+// duplicate key instead with INSERT ... ON DUPLICATE KEY UPDATE.
+func BulkUpsert(ctx context.Context, db *gorm.DB, rows interface{}, ops ...BulkOption) (BulkReport, error) {
+	options := resolveBulk(ops...)
+
+	conflict := clause.OnConflict{UpdateAll: true}
+	if options.updateColumns != nil {
+		conflict = clause.OnConflict{DoUpdates: clause.AssignmentColumns(options.updateColumns)}
+	}
+
+	return bulk(ctx, db, rows, []clause.Expression{conflict}, ops...)
+}
+
+func resolveBulk(ops ...BulkOption) *bulkOptions {
+	options := &bulkOptions{
+		chunkSize:   500,
+		transaction: true,
+	}
+
+	for _, o := range ops {
+		o.apply(options)
+	}
+
+	return options
+}
+
+func bulk(ctx context.Context, db *gorm.DB, rows interface{}, clauses []clause.Expression, ops ...BulkOption) (BulkReport, error) {
+	options := resolveBulk(ops...)
+
+	value := reflect.Indirect(reflect.ValueOf(rows))
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return BulkReport{}, ErrNotSlice
+	}
+
+	if value.Len() == 0 {
+		return BulkReport{}, nil
+	}
+
+	maxPacket := options.maxPacket
+	if maxPacket <= 0 {
+		if err := db.WithContext(ctx).Raw("SELECT @@max_allowed_packet").Scan(&maxPacket).Error; err != nil {
+			return BulkReport{}, err
+		}
+	}
+
+	chunks, err := chunkRows(ctx, db, value, clauses, options.chunkSize, maxPacket-packetOverhead)
+	if err != nil {
+		return BulkReport{}, err
+	}
+
+	if !options.transaction {
+		report := createChunks(ctx, db, value, clauses, chunks, false)
+		return report, report.Err()
+	}
+
+	var report BulkReport
+	err = WithTx(ctx, db, func(ctx context.Context, tx *gorm.DB) error {
+		// A retried transaction starts over.
+		report = createChunks(ctx, tx, value, clauses, chunks, true)
+		return report.Err()
+	}, options.txOptions...)
+
+	if err != nil {
+		report.Affected = 0
+	}
+
+	return report, err
+}
+
+// chunkRows splits the rows in chunks of at most size rows, halving the
+// chunks larger than maxPacket bytes. The statements are built with a
+// dry run skipping the hooks, they run when the chunks are created.
+func chunkRows(ctx context.Context, db *gorm.DB, rows reflect.Value, clauses []clause.Expression, size, maxPacket int) ([]BulkChunk, error) {
+	if size < 1 {
+		size = 1
+	}
+
+	var chunks []BulkChunk
+	var build func(offset, end int) error
+	build = func(offset, end int) error {
+		stmt := db.WithContext(ctx).Session(&gorm.Session{DryRun: true, SkipHooks: true, SkipDefaultTransaction: true}).
+			Clauses(clauses...).
+			Create(rows.Slice(offset, end).Interface()).
+			Statement
+		if stmt.Error != nil {
+			return stmt.Error
+		}
+
+		bytes := statementSize(stmt.SQL.String(), stmt.Vars)
+		if bytes > maxPacket && end-offset > 1 {
+			middle := offset + (end-offset)/2
+			if err := build(offset, middle); err != nil {
+				return err
+			}
+			return build(middle, end)
+		}
+
+		chunks = append(chunks, BulkChunk{Offset: offset, Rows: end - offset, Bytes: bytes})
+		return nil
+	}
+
+	for offset := 0; offset < rows.Len(); offset += size {
+		end := offset + size
+		if end > rows.Len() {
+			end = rows.Len()
+		}
+		if err := build(offset, end); err != nil {
+			return nil, err
+		}
+	}
+
+	return chunks, nil
+}
+
+// createChunks creates the rows of the chunks, stopping at the first
+// failure if stop, the chunks not run are left out of the report.
+func createChunks(ctx context.Context, db *gorm.DB, rows reflect.Value, clauses []clause.Expression, chunks []BulkChunk, stop bool) BulkReport {
+	var report BulkReport
+	for _, chunk := range chunks {
+		result := db.WithContext(ctx).Session(&gorm.Session{SkipDefaultTransaction: true}).
+			Clauses(clauses...).
+			Create(rows.Slice(chunk.Offset, chunk.Offset+chunk.Rows).Interface())
+
+		chunk.Affected, chunk.Err = result.RowsAffected, result.Error
+		report.Chunks = append(report.Chunks, chunk)
+		report.Affected += chunk.Affected
+
+		if chunk.Err != nil && stop {
+			break
+		}
+	}
+	return report
+}
+
+// statementSize estimates the packet size of a statement, the string
+// parameters are counted as fully escaped.
+func statementSize(sql string, vars []interface{}) int {
+	size := len(sql)
+	for _, v := range vars {
+		switch v := v.(type) {
+		case nil:
+			size += 4
+		case string:
+			size += 2*len(v) + 2
+		case []byte:
+			size += 2*len(v) + 3
+		case time.Time, *time.Time:
+			size += 28
+		default:
+			size += len(fmt.Sprint(v))
+		}
+	}
+	return size
+}
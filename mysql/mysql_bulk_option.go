@@ -0,0 +1,68 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+type BulkOption interface {
+	apply(*bulkOptions)
+}
+
+type bulkOptionFunc func(ops *bulkOptions)
+
+func (o bulkOptionFunc) apply(ops *bulkOptions) {
+	o(ops)
+}
+
+type bulkOptions struct {
+	chunkSize     int
+	maxPacket     int
+	updateColumns []string
+	transaction   bool
+	txOptions     []TxOption
+}
+
+// WithChunkSize Specifies the max rows of a statement. Default is 500.
+func WithChunkSize(size int) BulkOption {
+	return bulkOptionFunc(func(ops *bulkOptions) {
+		ops.chunkSize = size
+	})
+}
+
+// WithMaxPacket Specifies the max size of a statement in bytes. Default
+// is the max_allowed_packet of the server.
+func WithMaxPacket(size int) BulkOption {
+	return bulkOptionFunc(func(ops *bulkOptions) {
+		ops.maxPacket = size
+	})
+}
+
+// WithUpdateColumns Specifies the columns BulkUpsert updates on
+// duplicate keys. Default is every column but the primary keys and
+// the creation times.
+func WithUpdateColumns(columns ...string) BulkOption {
+	return bulkOptionFunc(func(ops *bulkOptions) {
+		ops.updateColumns = columns
+	})
+}
+
+// WithChunkTransaction Specifies whether all the chunks run in a
+// single transaction, rolled back on the first failing chunk, or each
+// on its own so the other chunks are still written. Default is true.
+// The transaction options apply to the single transaction.
+func WithChunkTransaction(enabled bool, ops ...TxOption) BulkOption {
+	return bulkOptionFunc(func(opts *bulkOptions) {
+		opts.transaction = enabled
+		opts.txOptions = ops
+	})
+}
@@ -0,0 +1,197 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/coolstina/connecter"
+	"github.com/coolstina/connecter/audit"
+	"github.com/coolstina/connecter/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// bulkHandler answers max_allowed_packet and affects 1 row per inserted
+// row, failing the statements inserting the name fail.
+func bulkHandler(fail string) fakeHandler {
+	return func(query string, args []interface{}) (driver.Rows, error) {
+		switch {
+		case query == "SELECT @@max_allowed_packet":
+			return &fakeRows{columns: []string{"@@max_allowed_packet"}, values: [][]driver.Value{{int64(4 << 20)}}}, nil
+		case strings.HasPrefix(query, "INSERT"):
+			for _, arg := range args {
+				if arg == fail {
+					return nil, errors.New("fake: chunk failed")
+				}
+			}
+			return &fakeRows{affected: int64(strings.Count(query, "(?")), lastInsertID: 10}, nil
+		}
+		return nil, nil
+	}
+}
+
+func bulkUsers(n int) []user {
+	users := make([]user, n)
+	for i := range users {
+		users[i] = user{ID: uint64(i + 1), Name: strings.Repeat("u", i+1)}
+	}
+	return users
+}
+
+// hookedUser counts the BeforeCreate hooks run.
+type hookedUser struct {
+	ID    uint64
+	Name  string
+	Hooks int `gorm:"-"`
+}
+
+func (u *hookedUser) BeforeCreate(tx *gorm.DB) error {
+	u.Hooks++
+	return nil
+}
+
+func TestBulkInsert_Create(t *testing.T) {
+	fake.reset()
+	events := make(chan audit.Event, 8)
+	limiter := ratelimit.New(ratelimit.WithDefaultRate(1000, 10))
+	db, err := NewConnection(&Config{
+		Database:           "connecter",
+		DriverName:         connecter.DriverName(fakeDriverName),
+		SkipCreateDatabase: true,
+	}, WithAudit(audit.New(audit.NewChannelSink(events))), WithRateLimit(limiter))
+	assert.NoError(t, err)
+
+	fake.reset()
+	fake.handle(bulkHandler(""))
+	users := []hookedUser{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+	report, err := BulkInsert(context.Background(), db, users, WithChunkSize(2), WithMaxPacket(1<<20))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), report.Affected)
+
+	for i, u := range users {
+		assert.Equal(t, 1, u.Hooks, u.Name)
+		assert.NotZero(t, u.ID, u.Name)
+		if i > 0 {
+			assert.NotEqual(t, users[i-1].ID, u.ID)
+		}
+	}
+
+	assert.Len(t, events, 2)
+	event := <-events
+	assert.Equal(t, audit.OperationOfCreate, event.Operation)
+	assert.Equal(t, "hooked_users", event.Target)
+
+	stats := limiter.Stats()
+	assert.Equal(t, uint64(1), stats[ratelimit.ClassOfBulk].Allowed)
+	assert.Equal(t, uint64(1), stats[ratelimit.ClassOfWrite].Allowed)
+}
+
+func TestBulkUpsert(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(bulkHandler(""))
+
+	report, err := BulkUpsert(context.Background(), db, bulkUsers(5), WithChunkSize(2))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), report.Affected)
+	assert.Len(t, report.Chunks, 3)
+	assert.Equal(t, BulkChunk{Offset: 4, Rows: 1, Bytes: report.Chunks[2].Bytes, Affected: 1}, report.Chunks[2])
+
+	queries := queriesOf(fake.recorded())
+	assert.Equal(t, "SELECT @@max_allowed_packet", queries[0])
+	assert.Equal(t, "START TRANSACTION", queries[1])
+	assert.Equal(t, "INSERT INTO `users` (`name`,`id`) VALUES (?,?),(?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)", queries[2])
+	assert.Equal(t, "INSERT INTO `users` (`name`,`id`) VALUES (?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`)", queries[4])
+	assert.Equal(t, "COMMIT", queries[5])
+	assert.Equal(t, []interface{}{"u", int64(1), "uu", int64(2)}, fake.recorded()[2].Args)
+}
+
+func TestBulkUpsert_UpdateColumns(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(bulkHandler(""))
+
+	_, err := BulkUpsert(context.Background(), db, &[]user{{ID: 1, Name: "tom"}},
+		WithUpdateColumns("name", "id"), WithMaxPacket(1<<20), WithChunkTransaction(false),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"INSERT INTO `users` (`name`,`id`) VALUES (?,?) ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`id`=VALUES(`id`)",
+	}, queriesOf(fake.recorded()))
+}
+
+func TestBulkInsert_MaxPacket(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(bulkHandler(""))
+
+	users := bulkUsers(8)
+	size := statementSize("INSERT INTO `users` (`name`,`id`) VALUES (?,?),(?,?)", []interface{}{users[6].Name, users[6].ID, users[7].Name, users[7].ID})
+
+	report, err := BulkInsert(context.Background(), db, users, WithMaxPacket(size+packetOverhead))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(8), report.Affected)
+
+	rows := 0
+	for _, chunk := range report.Chunks {
+		assert.LessOrEqual(t, chunk.Bytes, size)
+		rows += chunk.Rows
+	}
+	assert.Equal(t, 8, rows)
+	assert.True(t, len(report.Chunks) > 1)
+	assert.NotContains(t, queriesOf(fake.recorded())[1], "ON DUPLICATE KEY UPDATE")
+}
+
+func TestBulkUpsert_Failure(t *testing.T) {
+	db := newFakeDB(t)
+	users := bulkUsers(6)
+
+	fake.reset()
+	fake.handle(bulkHandler("uuu"))
+	report, err := BulkUpsert(context.Background(), db, users, WithChunkSize(2), WithMaxPacket(1<<20))
+	assert.Error(t, err)
+	assert.Equal(t, int64(0), report.Affected)
+	assert.Len(t, report.Chunks, 2)
+	assert.Equal(t, err, report.Chunks[1].Err)
+	assert.Equal(t, "ROLLBACK", queriesOf(fake.recorded())[len(fake.recorded())-1])
+
+	fake.reset()
+	fake.handle(bulkHandler("uuu"))
+	report, err = BulkUpsert(context.Background(), db, users, WithChunkSize(2), WithMaxPacket(1<<20), WithChunkTransaction(false))
+	assert.Error(t, err)
+	assert.Equal(t, int64(4), report.Affected)
+	assert.Len(t, report.Chunks, 3)
+	assert.NoError(t, report.Chunks[0].Err)
+	assert.Error(t, report.Chunks[1].Err)
+	assert.NoError(t, report.Chunks[2].Err)
+}
+
+func TestBulkUpsert_Rows(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+
+	_, err := BulkUpsert(context.Background(), db, user{ID: 1})
+	assert.ErrorIs(t, err, ErrNotSlice)
+
+	report, err := BulkUpsert(context.Background(), db, []user{})
+	assert.NoError(t, err)
+	assert.Empty(t, report.Chunks)
+	assert.Empty(t, fake.recorded())
+}
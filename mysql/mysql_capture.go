@@ -0,0 +1,253 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+const (
+	captureName = "connecter:capture"
+	captureKey  = "connecter:capture"
+)
+
+var (
+	// ErrCaptureNotRegistered is returned by CaptureSQL for databases
+	// without the capture callbacks, see RegisterCapture.
+	ErrCaptureNotRegistered = errors.New("mysql: capture callbacks not registered")
+
+	// errCaptureDryRun is returned if a dry run capture pool is reached.
+	errCaptureDryRun = errors.New("mysql: capture dry run does not execute statements")
+)
+
+// CapturedStatement defines a statement recorded by a capture.
+type CapturedStatement struct {
+	// SQL is the statement with its parameters interpolated, or with
+	// its placeholders when redacted.
+	SQL string
+	// Vars are the parameters, nil when redacted.
+	Vars []interface{}
+	// Rows is the number of rows affected, always 0 in a dry run.
+	Rows int64
+	Err  error
+}
+
+// Capture records the statements of gorm connections, such as to
+// review the SQL a code path runs or assert it in tests.
+type Capture struct {
+	mu         sync.Mutex
+	options    *captureOptions
+	statements []CapturedStatement
+}
+
+// NewCapture initialize capture instance, see WithCapture and CaptureSQL.
+func NewCapture(ops ...CaptureOption) *Capture {
+	options := &captureOptions{}
+	for _, o := range ops {
+		o.apply(options)
+	}
+	return &Capture{options: options}
+}
+
+// RegisterCapture registers the gorm callbacks recording the statements
+// of CaptureSQL sessions, connections of NewConnection have them. Call
+// it before db is shared, registering changes the callbacks of db.
+func RegisterCapture(db *gorm.DB) error {
+	return db.Use(&capturePlugin{})
+}
+
+// CaptureSQL returns a session of db recording its statements to a new
+// capture. Unless the capture executes them, the session is a dry run
+// whose transactions do not begin on the database either. db must have
+// the capture callbacks, see RegisterCapture.
+func CaptureSQL(db *gorm.DB, ops ...CaptureOption) (*gorm.DB, *Capture, error) {
+	if _, ok := db.Config.Plugins[captureName]; !ok {
+		return nil, nil, ErrCaptureNotRegistered
+	}
+
+	capture := NewCapture(ops...)
+	tx := db.Session(&gorm.Session{
+		DryRun:                 !capture.options.execute,
+		SkipDefaultTransaction: !capture.options.execute,
+	}).Set(captureKey, capture)
+
+	if !capture.options.execute {
+		tx.Statement.ConnPool = &capturePool{ConnPool: tx.Statement.ConnPool}
+	}
+
+	return tx.Session(&gorm.Session{}), capture, nil
+}
+
+// Statements returns the statements recorded so far.
+func (c *Capture) Statements() []CapturedStatement {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]CapturedStatement{}, c.statements...)
+}
+
+// SQL returns the SQL of the statements recorded so far.
+func (c *Capture) SQL() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	statements := make([]string, len(c.statements))
+	for i, statement := range c.statements {
+		statements[i] = statement.SQL
+	}
+	return statements
+}
+
+// String returns the statements recorded so far, one per line ended
+// with a semicolon.
+func (c *Capture) String() string {
+	var b strings.Builder
+	for _, statement := range c.SQL() {
+		b.WriteString(statement)
+		b.WriteString(";\n")
+	}
+	return b.String()
+}
+
+// Reset forgets the statements recorded so far.
+func (c *Capture) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.statements = nil
+}
+
+func (c *Capture) record(db *gorm.DB) {
+	stmt := db.Statement
+	statement := CapturedStatement{
+		SQL:  stmt.SQL.String(),
+		Rows: db.RowsAffected,
+		Err:  db.Error,
+	}
+
+	if _, redacted := db.Dialector.(redactedDialector); !redacted && !c.options.redact {
+		statement.SQL = db.Dialector.Explain(statement.SQL, stmt.Vars...)
+		statement.Vars = append([]interface{}{}, stmt.Vars...)
+	}
+
+	c.mu.Lock()
+	c.statements = append(c.statements, statement)
+	c.mu.Unlock()
+
+	if c.options.writer != nil {
+		_, _ = io.WriteString(c.options.writer, statement.SQL+";\n")
+	}
+	if c.options.handler != nil {
+		c.options.handler(stmt.Context, statement)
+	}
+}
+
+// capturePlugin records the statements to the capture of their
+// session, or else to the one of the connection.
+type capturePlugin struct {
+	capture *Capture
+}
+
+func (p *capturePlugin) Name() string {
+	return captureName
+}
+
+func (p *capturePlugin) Initialize(db *gorm.DB) error {
+	callbacks := []struct {
+		name     string
+		register func(name string, fn func(*gorm.DB)) error
+	}{
+		{"create", db.Callback().Create().After("gorm:create").Register},
+		{"query", db.Callback().Query().After("gorm:query").Register},
+		{"update", db.Callback().Update().After("gorm:update").Register},
+		{"delete", db.Callback().Delete().After("gorm:delete").Register},
+		{"row", db.Callback().Row().After("gorm:row").Register},
+		{"raw", db.Callback().Raw().After("gorm:raw").Register},
+	}
+
+	for _, callback := range callbacks {
+		if err := callback.register("connecter:capture_"+callback.name, p.record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *capturePlugin) record(db *gorm.DB) {
+	if db.Statement.SQL.Len() == 0 {
+		return
+	}
+
+	capture := p.capture
+	if value, ok := db.Get(captureKey); ok {
+		capture = value.(*Capture)
+	}
+	if capture != nil {
+		capture.record(db)
+	}
+}
+
+// capturePool stands for the connection pool of dry runs, so their
+// transactions begin without reaching the database.
+type capturePool struct {
+	gorm.ConnPool
+}
+
+// GetDBConn implements gorm.GetDBConnector, so that the pool opened
+// with the connection can be configured and closed.
+func (p *capturePool) GetDBConn() (*sql.DB, error) {
+	switch pool := p.ConnPool.(type) {
+	case *sql.DB:
+		return pool, nil
+	case gorm.GetDBConnector:
+		return pool.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+func (p *capturePool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errCaptureDryRun
+}
+
+func (p *capturePool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return nil, errCaptureDryRun
+}
+
+func (p *capturePool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errCaptureDryRun
+}
+
+func (p *capturePool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return &captureTx{capturePool: p}, nil
+}
+
+type captureTx struct {
+	*capturePool
+}
+
+func (t *captureTx) Commit() error {
+	return nil
+}
+
+func (t *captureTx) Rollback() error {
+	return nil
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"io"
+)
+
+type CaptureOption interface {
+	apply(*captureOptions)
+}
+
+type captureOptionFunc func(ops *captureOptions)
+
+func (o captureOptionFunc) apply(ops *captureOptions) {
+	o(ops)
+}
+
+type captureOptions struct {
+	execute bool
+	redact  bool
+	writer  io.Writer
+	handler func(ctx context.Context, statement CapturedStatement)
+}
+
+// WithCaptureExecute Specifies whether the captured statements are
+// executed as well. Default is false, meaning a dry run.
+func WithCaptureExecute(execute bool) CaptureOption {
+	return captureOptionFunc(func(ops *captureOptions) {
+		ops.execute = execute
+	})
+}
+
+// WithCaptureRedact Specifies whether statements are captured with
+// their placeholders instead of the interpolated parameters. They are
+// always redacted on connections made with WithRedactParams.
+func WithCaptureRedact(redact bool) CaptureOption {
+	return captureOptionFunc(func(ops *captureOptions) {
+		ops.redact = redact
+	})
+}
+
+// WithCaptureWriter Specifies a writer each captured statement is
+// written to, one per line ended with a semicolon.
+func WithCaptureWriter(w io.Writer) CaptureOption {
+	return captureOptionFunc(func(ops *captureOptions) {
+		ops.writer = w
+	})
+}
+
+// WithCaptureHandler Specifies a function called with each captured
+// statement.
+func WithCaptureHandler(handler func(ctx context.Context, statement CapturedStatement)) CaptureOption {
+	return captureOptionFunc(func(ops *captureOptions) {
+		ops.handler = handler
+	})
+}
@@ -0,0 +1,115 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/coolstina/connecter"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCaptureSQL(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+
+	_, _, err := CaptureSQL(db)
+	assert.ErrorIs(t, err, ErrCaptureNotRegistered)
+	assert.NoError(t, RegisterCapture(db))
+
+	var b bytes.Buffer
+	var handled []CapturedStatement
+	tx, capture, err := CaptureSQL(db,
+		WithCaptureWriter(&b),
+		WithCaptureHandler(func(ctx context.Context, statement CapturedStatement) {
+			handled = append(handled, statement)
+		}),
+	)
+	assert.NoError(t, err)
+
+	assert.NoError(t, tx.Create(&user{Name: "tom"}).Error)
+	assert.NoError(t, tx.Where("name = ?", "tom").Find(&[]user{}).Error)
+
+	err = WithTx(context.Background(), tx, func(ctx context.Context, tx *gorm.DB) error {
+		return tx.Model(&user{}).Where("id = ?", 1).Update("name", "jerry").Error
+	})
+	assert.NoError(t, err)
+
+	expected := []string{
+		"INSERT INTO `users` (`name`) VALUES (\"tom\")",
+		"SELECT * FROM `users` WHERE name = \"tom\"",
+		"UPDATE `users` SET `name`=\"jerry\" WHERE id = 1",
+	}
+	assert.Equal(t, expected, capture.SQL())
+	assert.Equal(t, []interface{}{"tom"}, capture.Statements()[0].Vars)
+	assert.Len(t, handled, 3)
+	assert.Equal(t, capture.String(), b.String())
+
+	// The dry run never reached the database, not even to begin.
+	assert.Empty(t, fake.recorded())
+
+	// Statements outside the session are not captured.
+	assert.NoError(t, db.Find(&[]user{}).Error)
+	assert.Len(t, capture.SQL(), 3)
+
+	capture.Reset()
+	assert.Empty(t, capture.Statements())
+}
+
+func TestCaptureSQL_Execute(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	assert.NoError(t, RegisterCapture(db))
+
+	tx, capture, err := CaptureSQL(db, WithCaptureExecute(true), WithCaptureRedact(true))
+	assert.NoError(t, err)
+
+	assert.NoError(t, tx.Exec("DELETE FROM users WHERE name = ?", "tom").Error)
+
+	assert.Equal(t, []CapturedStatement{{SQL: "DELETE FROM users WHERE name = ?"}}, capture.Statements())
+	assert.Equal(t, []string{"DELETE FROM users WHERE name = ?"}, queriesOf(fake.recorded()))
+}
+
+func TestNewConnection_Capture(t *testing.T) {
+	fake.reset()
+
+	capture := NewCapture()
+	db, err := NewConnection(&Config{
+		Host:       "127.0.0.1",
+		Username:   "root",
+		Database:   "connecter",
+		DriverName: connecter.DriverName(fakeDriverName),
+	}, WithCapture(capture), WithRedactParams(true))
+	assert.NoError(t, err)
+
+	assert.NoError(t, db.Create(&user{Name: "tom"}).Error)
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return tx.Delete(&user{}, 1).Error
+	}))
+
+	assert.Equal(t, []CapturedStatement{
+		{SQL: "INSERT INTO `users` (`name`) VALUES (?)"},
+		{SQL: "DELETE FROM `users` WHERE `users`.`id` = ?"},
+	}, capture.Statements())
+	assert.Empty(t, fake.recorded())
+
+	// The pool opened with the dry run can still be closed.
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	assert.NoError(t, sqlDB.Close())
+}
@@ -0,0 +1,180 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// ErrUnknownDatabase is returned for database names the cluster has no
+// config for.
+var ErrUnknownDatabase = errors.New("mysql: unknown database")
+
+// Cluster holds the connections of several named databases, such as
+// the schemas a service reads and writes on several servers.
+type Cluster struct {
+	mu  sync.RWMutex
+	dbs map[string]*gorm.DB
+}
+
+// NewCluster initialize cluster instance, opening a connection with
+// NewConnection for each named config. The zero fields of a config
+// take the value of defaults, which may be nil. Connections opened
+// before a failure are closed.
+func NewCluster(defaults *Config, configs map[string]*Config, ops ...Option) (*Cluster, error) {
+	c := &Cluster{dbs: make(map[string]*gorm.DB, len(configs))}
+
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		config := mergeConfig(defaults, configs[name])
+
+		db, err := NewConnection(config, ops...)
+		if err != nil {
+			_ = c.Close()
+			return nil, fmt.Errorf("mysql: open database %q: %w", name, err)
+		}
+		c.dbs[name] = db
+	}
+
+	return c, nil
+}
+
+// DB returns the connection of the named database.
+func (c *Cluster) DB(name string) (*gorm.DB, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	db, ok := c.dbs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDatabase, name)
+	}
+	return db, nil
+}
+
+// Names returns the sorted names of the databases.
+func (c *Cluster) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.names()
+}
+
+func (c *Cluster) names() []string {
+	names := make([]string, 0, len(c.dbs))
+	for name := range c.dbs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats returns the connection pool statistics of every database.
+func (c *Cluster) Stats() map[string]sql.DBStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := make(map[string]sql.DBStats, len(c.dbs))
+	for name, db := range c.dbs {
+		if sqlDB, err := db.DB(); err == nil {
+			stats[name] = sqlDB.Stats()
+		}
+	}
+	return stats
+}
+
+// Close closes every database and its replicas, it returns the first
+// error met.
+func (c *Cluster) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var first error
+	for _, name := range c.names() {
+		if err := Close(c.dbs[name]); err != nil && first == nil {
+			first = fmt.Errorf("mysql: close database %q: %w", name, err)
+		}
+	}
+	c.dbs = map[string]*gorm.DB{}
+	return first
+}
+
+// mergeConfig returns a copy of config whose zero fields take the
+// value of defaults. The replica fields describe the replicas of the
+// default host, so they are only taken along with the host.
+func mergeConfig(defaults, config *Config) *Config {
+	merged := &Config{}
+	if config != nil {
+		*merged = *config
+	}
+	if defaults == nil {
+		return merged
+	}
+
+	if merged.Host == "" {
+		merged.Host = defaults.Host
+		if merged.Replicas == nil {
+			merged.Replicas = defaults.Replicas
+		}
+		if merged.ReplicaCheckInterval == 0 {
+			merged.ReplicaCheckInterval = defaults.ReplicaCheckInterval
+		}
+		if merged.MaxReplicationLag == 0 {
+			merged.MaxReplicationLag = defaults.MaxReplicationLag
+		}
+	}
+	if merged.Username == "" {
+		merged.Username = defaults.Username
+	}
+	if merged.Password == "" {
+		merged.Password = defaults.Password
+	}
+	if merged.Database == "" {
+		merged.Database = defaults.Database
+	}
+	if merged.MaxIdleConnections == 0 {
+		merged.MaxIdleConnections = defaults.MaxIdleConnections
+	}
+	if merged.MaxOpenConnections == 0 {
+		merged.MaxOpenConnections = defaults.MaxOpenConnections
+	}
+	if merged.MaxConnectionLifeTime == 0 {
+		merged.MaxConnectionLifeTime = defaults.MaxConnectionLifeTime
+	}
+	if merged.LogLevel == 0 {
+		merged.LogLevel = defaults.LogLevel
+	}
+	if merged.Logger == nil {
+		merged.Logger = defaults.Logger
+	}
+	if merged.DriverName == "" {
+		merged.DriverName = defaults.DriverName
+	}
+	if !merged.SkipCreateDatabase {
+		merged.SkipCreateDatabase = defaults.SkipCreateDatabase
+	}
+
+	return merged
+}
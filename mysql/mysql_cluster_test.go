@@ -0,0 +1,93 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coolstina/connecter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCluster(t *testing.T) {
+	fake.reset()
+
+	defaults := &Config{
+		Host:                  "10.0.0.1",
+		Username:              "app",
+		Password:              "secret",
+		MaxOpenConnections:    20,
+		MaxConnectionLifeTime: time.Minute,
+		DriverName:            connecter.DriverName(fakeDriverName),
+	}
+
+	cluster, err := NewCluster(defaults, map[string]*Config{
+		"orders":    {Database: "orders"},
+		"users":     {Database: "users", SkipCreateDatabase: true},
+		"analytics": {Host: "10.0.0.2", Username: "reader", Database: "events", MaxOpenConnections: 5},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"analytics", "orders", "users"}, cluster.Names())
+
+	assert.Equal(t, []fakeCall{
+		{DSN: "reader:secret@tcp(10.0.0.2:3306)/?loc=Local&parseTime=true&charset=utf8mb4", Query: "CREATE DATABASE IF NOT EXISTS `events` DEFAULT CHARACTER SET utf8mb4"},
+		{DSN: "reader:secret@tcp(10.0.0.2:3306)/events?loc=Local&parseTime=true&charset=utf8mb4", Query: "SELECT VERSION()"},
+		{DSN: "app:secret@tcp(10.0.0.1:3306)/?loc=Local&parseTime=true&charset=utf8mb4", Query: "CREATE DATABASE IF NOT EXISTS `orders` DEFAULT CHARACTER SET utf8mb4"},
+		{DSN: "app:secret@tcp(10.0.0.1:3306)/orders?loc=Local&parseTime=true&charset=utf8mb4", Query: "SELECT VERSION()"},
+		{DSN: "app:secret@tcp(10.0.0.1:3306)/users?loc=Local&parseTime=true&charset=utf8mb4", Query: "SELECT VERSION()"},
+	}, fake.recorded())
+
+	analytics, err := cluster.DB("analytics")
+	assert.NoError(t, err)
+	sqlDB, err := analytics.DB()
+	assert.NoError(t, err)
+	assert.Equal(t, 5, sqlDB.Stats().MaxOpenConnections)
+
+	stats := cluster.Stats()
+	assert.Len(t, stats, 3)
+	assert.Equal(t, 20, stats["orders"].MaxOpenConnections)
+
+	_, err = cluster.DB("billing")
+	assert.ErrorIs(t, err, ErrUnknownDatabase)
+
+	assert.NoError(t, cluster.Close())
+	assert.Error(t, sqlDB.Ping())
+	assert.Empty(t, cluster.Names())
+}
+
+func TestCluster_OpenFailure(t *testing.T) {
+	fake.reset()
+
+	cluster, err := NewCluster(&Config{DriverName: connecter.DriverName(fakeDriverName)}, map[string]*Config{
+		"orders": {Database: "orders"},
+		"users":  {},
+	})
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+	assert.Contains(t, err.Error(), `"users"`)
+	assert.Nil(t, cluster)
+}
+
+func TestMergeConfig(t *testing.T) {
+	merged := mergeConfig(&Config{Host: "db", LogLevel: 2, Replicas: []Replica{{Host: "replica"}}}, &Config{Database: "orders", LogLevel: 4})
+	assert.Equal(t, &Config{Host: "db", Database: "orders", LogLevel: 4, Replicas: []Replica{{Host: "replica"}}}, merged)
+
+	assert.Equal(t, &Config{Database: "orders"}, mergeConfig(nil, &Config{Database: "orders"}))
+
+	// The replicas of the default host are not the ones of another host.
+	defaults := &Config{Host: "db", Replicas: []Replica{{Host: "replica"}}, ReplicaCheckInterval: time.Second, MaxReplicationLag: time.Minute}
+	merged = mergeConfig(defaults, &Config{Host: "archive", Database: "orders"})
+	assert.Equal(t, &Config{Host: "archive", Database: "orders"}, merged)
+}
@@ -0,0 +1,297 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrUnknownDictionaryFormat is returned for data dictionary formats
+// other than the ones defined below.
+var ErrUnknownDictionaryFormat = errors.New("mysql: unknown data dictionary format")
+
+// DictionaryFormat defines the format of a data dictionary.
+type DictionaryFormat string
+
+func (f DictionaryFormat) String() string {
+	return string(f)
+}
+
+const (
+	DictionaryFormatOfMarkdown DictionaryFormat = "markdown"
+	DictionaryFormatOfCSV      DictionaryFormat = "csv"
+)
+
+// TableCommenter is implemented by models defining their table comment.
+type TableCommenter interface {
+	TableComment() string
+}
+
+// TableComment returns the table comment of model, the one of its
+// TableComment method or else the one tagged on its blank field, such as:
+//
+//	type User struct {
+//		_  struct{} `gorm:"comment:users of the shop"`
+//		ID uint64
+//	}
+func TableComment(model interface{}) (string, bool) {
+	if commenter, ok := model.(TableCommenter); ok {
+		return commenter.TableComment(), true
+	}
+
+	t := reflect.TypeOf(model)
+	for t != nil && (t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", false
+	}
+
+	if commenter, ok := reflect.New(t).Interface().(TableCommenter); ok {
+		return commenter.TableComment(), true
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if field := t.Field(i); field.Name == "_" {
+			if comment, ok := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")["COMMENT"]; ok {
+				return comment, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// tableCommentOf returns the table comment expected for model, the one
+// of TableComment or else the one of the gorm:table_options setting.
+func tableCommentOf(db *gorm.DB, model interface{}) (string, bool) {
+	if comment, ok := TableComment(model); ok {
+		return comment, true
+	}
+	if options, ok := db.Get("gorm:table_options"); ok {
+		return commentOf(fmt.Sprint(options))
+	}
+	return "", false
+}
+
+// AutoMigrate migrates the models as gorm does, creating their tables
+// with the comment of TableComment in the table options.
+func AutoMigrate(db *gorm.DB, models ...interface{}) error {
+	for _, model := range models {
+		tx := db
+		if comment, ok := TableComment(model); ok {
+			options := GormComment(comment)
+			if current, ok := db.Get("gorm:table_options"); ok {
+				options = strings.TrimSpace(commentPattern.ReplaceAllString(fmt.Sprint(current), "") + " " + options)
+			}
+			tx = db.Set("gorm:table_options", options)
+		}
+
+		if err := tx.AutoMigrate(model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SyncComments alters the existing tables of the models so their table
+// and column comments match the ones of the models, leaving the rest of
+// the column definitions as they are. Missing tables and generated
+// columns are left alone. It returns the statements run.
+func SyncComments(ctx context.Context, db *gorm.DB, models ...interface{}) ([]string, error) {
+	actual, err := InspectSchema(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []string
+	for _, model := range models {
+		statement := &gorm.Statement{DB: db}
+		if err := statement.Parse(model); err != nil {
+			return nil, err
+		}
+
+		table, ok := actual.Table(statement.Schema.Table)
+		if !ok {
+			continue
+		}
+
+		var specs []string
+		for _, name := range statement.Schema.DBNames {
+			field := statement.Schema.LookUpField(name)
+			column, ok := table.Column(name)
+			if !ok || field.IgnoreMigration || field.Comment == column.Comment || isGenerated(*column) {
+				continue
+			}
+			specs = append(specs, "MODIFY COLUMN "+columnDefinition(db, *column, field.Comment))
+		}
+
+		if comment, ok := tableCommentOf(db, model); ok && comment != table.Comment {
+			specs = append(specs, "COMMENT = "+quoteString(comment))
+		}
+
+		if len(specs) == 0 {
+			continue
+		}
+
+		sql := fmt.Sprintf("ALTER TABLE %s %s", db.Statement.Quote(table.Name), strings.Join(specs, ", "))
+		if err := db.WithContext(ctx).Exec(sql).Error; err != nil {
+			return statements, err
+		}
+		statements = append(statements, sql)
+	}
+
+	return statements, nil
+}
+
+func isGenerated(column Column) bool {
+	extra := strings.ToUpper(column.Extra)
+	return strings.Contains(extra, "VIRTUAL GENERATED") || strings.Contains(extra, "STORED GENERATED")
+}
+
+// columnDefinition returns the definition of the live column with the
+// given comment, as MODIFY COLUMN needs the whole definition.
+func columnDefinition(db *gorm.DB, column Column, comment string) string {
+	parts := []string{db.Statement.Quote(column.Name), column.Type}
+
+	if column.Collation != "" {
+		parts = append(parts, "COLLATE "+column.Collation)
+	}
+
+	if column.Nullable {
+		parts = append(parts, "NULL")
+	} else {
+		parts = append(parts, "NOT NULL")
+	}
+
+	extra := strings.TrimSpace(strings.Replace(column.Extra, "DEFAULT_GENERATED", "", 1))
+	if column.Default != nil {
+		def := *column.Default
+		switch {
+		case strings.HasPrefix(strings.ToUpper(def), "CURRENT_TIMESTAMP"):
+			parts = append(parts, "DEFAULT "+def)
+		case strings.Contains(column.Extra, "DEFAULT_GENERATED"):
+			parts = append(parts, "DEFAULT ("+def+")")
+		default:
+			parts = append(parts, "DEFAULT "+quoteString(def))
+		}
+	}
+
+	if extra != "" {
+		parts = append(parts, extra)
+	}
+
+	return strings.Join(parts, " ") + " COMMENT " + quoteString(comment)
+}
+
+// ExportDataDictionary writes the data dictionary of the current
+// database in the given format.
+func ExportDataDictionary(ctx context.Context, db *gorm.DB, w io.Writer, format DictionaryFormat) error {
+	s, err := InspectSchema(ctx, db)
+	if err != nil {
+		return err
+	}
+	return WriteDataDictionary(w, s, format)
+}
+
+// WriteDataDictionary writes the tables and columns of s with their
+// comments in the given format.
+func WriteDataDictionary(w io.Writer, s *Schema, format DictionaryFormat) error {
+	switch format {
+	case DictionaryFormatOfMarkdown:
+		return writeMarkdownDictionary(w, s)
+	case DictionaryFormatOfCSV:
+		return writeCSVDictionary(w, s)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownDictionaryFormat, format)
+	}
+}
+
+func writeMarkdownDictionary(w io.Writer, s *Schema) error {
+	var b strings.Builder
+	b.WriteString("# Data Dictionary\n")
+
+	for _, table := range s.Tables {
+		fmt.Fprintf(&b, "\n## %s\n\n", table.Name)
+		if table.Comment != "" {
+			fmt.Fprintf(&b, "%s\n\n", markdownCell(table.Comment))
+		}
+
+		b.WriteString("| Column | Type | Nullable | Default | Comment |\n")
+		b.WriteString("| --- | --- | --- | --- | --- |\n")
+		for _, column := range table.Columns {
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n",
+				markdownCell(column.Name),
+				markdownCell(column.Type),
+				yesNo(column.Nullable),
+				markdownCell(defaultOf(column)),
+				markdownCell(column.Comment),
+			)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeCSVDictionary(w io.Writer, s *Schema) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"table", "table_comment", "column", "type", "nullable", "default", "comment"}); err != nil {
+		return err
+	}
+
+	for _, table := range s.Tables {
+		for _, column := range table.Columns {
+			record := []string{table.Name, table.Comment, column.Name, column.Type, yesNo(column.Nullable), defaultOf(column), column.Comment}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func defaultOf(column Column) string {
+	switch {
+	case column.Default != nil:
+		return *column.Default
+	case column.Nullable:
+		return "NULL"
+	default:
+		return ""
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
+
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>").Replace(s)
+}
@@ -0,0 +1,125 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type commentedOrder struct {
+	_      struct{} `gorm:"comment:orders of users"`
+	ID     uint64
+	UserID uint64 `gorm:"comment:buyer"`
+}
+
+func (commentedOrder) TableName() string { return "orders" }
+
+type commenterProduct struct {
+	ID uint64
+}
+
+func (*commenterProduct) TableComment() string { return "products on sale" }
+
+func TestGormComment(t *testing.T) {
+	assert.Equal(t, "comment 'users of the shop'", GormComment("users of the shop"))
+	assert.Equal(t, `comment 'user\'s \\ shop'`, GormComment(`user's \ shop`))
+
+	comment, ok := commentOf(GormComment(`user's \ shop`))
+	assert.True(t, ok)
+	assert.Equal(t, `user's \ shop`, comment)
+}
+
+func TestTableComment(t *testing.T) {
+	comment, ok := TableComment(&commentedOrder{})
+	assert.True(t, ok)
+	assert.Equal(t, "orders of users", comment)
+
+	comment, ok = TableComment(&[]commentedOrder{})
+	assert.True(t, ok)
+	assert.Equal(t, "orders of users", comment)
+
+	comment, ok = TableComment(commenterProduct{})
+	assert.True(t, ok)
+	assert.Equal(t, "products on sale", comment)
+
+	_, ok = TableComment(&user{})
+	assert.False(t, ok)
+}
+
+func TestSyncComments(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(schemaHandler)
+
+	statements, err := SyncComments(context.Background(), db, &schemaUser{}, &commentedOrder{}, &schemaProduct{})
+	assert.NoError(t, err)
+
+	expected := []string{
+		"ALTER TABLE `users` MODIFY COLUMN `name` varchar(64) COLLATE utf8mb4_general_ci NOT NULL DEFAULT '' COMMENT 'user\\'s nickname'",
+		"ALTER TABLE `orders` MODIFY COLUMN `user_id` bigint(20) unsigned NULL COMMENT 'buyer', COMMENT = 'orders of users'",
+	}
+	assert.Equal(t, expected, statements)
+
+	queries := queriesOf(fake.recorded())
+	assert.Equal(t, expected, queries[len(queries)-2:])
+}
+
+func TestWriteDataDictionary(t *testing.T) {
+	zero := "0"
+	s := &Schema{Tables: []Table{{
+		Name:    "users",
+		Comment: "users of the shop",
+		Columns: []Column{
+			{Name: "id", Type: "bigint unsigned", Extra: "auto_increment"},
+			{Name: "age", Type: "int", Default: &zero, Comment: "age | years"},
+			{Name: "bio", Type: "text", Nullable: true, Comment: "line one\nline two"},
+		},
+	}}}
+
+	var b bytes.Buffer
+	assert.NoError(t, WriteDataDictionary(&b, s, DictionaryFormatOfMarkdown))
+	assert.Equal(t, strings.Join([]string{
+		"# Data Dictionary",
+		"",
+		"## users",
+		"",
+		"users of the shop",
+		"",
+		"| Column | Type | Nullable | Default | Comment |",
+		"| --- | --- | --- | --- | --- |",
+		"| id | bigint unsigned | NO |  |  |",
+		`| age | int | NO | 0 | age \| years |`,
+		"| bio | text | YES | NULL | line one<br>line two |",
+		"",
+	}, "\n"), b.String())
+
+	b.Reset()
+	assert.NoError(t, WriteDataDictionary(&b, s, DictionaryFormatOfCSV))
+	assert.Equal(t, strings.Join([]string{
+		"table,table_comment,column,type,nullable,default,comment",
+		"users,users of the shop,id,bigint unsigned,NO,,",
+		"users,users of the shop,age,int,NO,0,age | years",
+		"users,users of the shop,bio,text,YES,NULL,\"line one\nline two\"",
+		"",
+	}, "\n"), b.String())
+
+	err := WriteDataDictionary(&b, s, "html")
+	assert.ErrorIs(t, err, ErrUnknownDictionaryFormat)
+}
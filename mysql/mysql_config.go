@@ -31,7 +31,41 @@ type Config struct {
 	MaxIdleConnections    int
 	MaxOpenConnections    int
 	MaxConnectionLifeTime time.Duration
-	LogLevel              int
-	Logger                logger.Interface
-	DriverName            connecter.DriverName
+	// LogLevel is the gorm logger.LogLevel of the logger built when
+	// Logger is nil, from 1 for silent to 4 for info. Default is warn.
+	LogLevel int
+	// Logger replaces the logger built from LogLevel and the options.
+	Logger     logger.Interface
+	DriverName connecter.DriverName
+
+	// SkipCreateDatabase skips creating Database on connect, such as
+	// when the account has no CREATE privilege.
+	SkipCreateDatabase bool
+
+	// Replicas receive the reads made outside of transactions,
+	// writes and transactions go to Host.
+	Replicas []Replica
+	// ReplicaCheckInterval is the interval between two replica health
+	// checks. Default is 10 seconds.
+	ReplicaCheckInterval time.Duration
+	// MaxReplicationLag evicts the replicas lagging further behind the
+	// primary until they catch up. The default is 0, meaning no check.
+	MaxReplicationLag time.Duration
+}
+
+// Replica defines a read replica and its share of the reads.
+type Replica struct {
+	Host   string
+	Weight int
+}
+
+// ApplicationUser defines an account created along with the database
+// and granted privileges on it only.
+type ApplicationUser struct {
+	Username string
+	Password string
+	// Host the account connects from. Default is "%", meaning any host.
+	Host string
+	// Privileges granted on the database. Default is ALL PRIVILEGES.
+	Privileges []string
 }
@@ -0,0 +1,186 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+)
+
+// MySQL server error codes classified below, named after the ones of
+// the MySQL reference.
+const (
+	ErrorCodeOfDuplicateKey     uint16 = 1062
+	ErrorCodeOfLockWaitTimeout  uint16 = 1205
+	ErrorCodeOfDeadlock         uint16 = 1213
+	ErrorCodeOfNoSuchTable      uint16 = 1146
+	ErrorCodeOfNoReferencedRow  uint16 = 1216
+	ErrorCodeOfRowIsReferenced  uint16 = 1217
+	ErrorCodeOfRowIsReferenced2 uint16 = 1451
+	ErrorCodeOfNoReferencedRow2 uint16 = 1452
+	ErrorCodeOfOptionPrevents   uint16 = 1290
+	ErrorCodeOfReadOnlyTx       uint16 = 1792
+	ErrorCodeOfServerShutdown   uint16 = 1053
+	ErrorCodeOfConnectionKilled uint16 = 1927
+	ErrorCodeOfServerGone       uint16 = 2006
+	ErrorCodeOfServerLost       uint16 = 2013
+	ErrorCodeOfParse            uint16 = 1064
+)
+
+// Errors returned by ClassifyError, they match the errors of the same
+// class with errors.Is.
+var (
+	ErrDuplicateKey        = errors.New("mysql: duplicate key")
+	ErrDeadlock            = errors.New("mysql: deadlock")
+	ErrLockWaitTimeout     = errors.New("mysql: lock wait timeout")
+	ErrForeignKeyViolation = errors.New("mysql: foreign key violation")
+	ErrReadOnly            = errors.New("mysql: read only")
+	ErrConnectionLost      = errors.New("mysql: connection lost")
+)
+
+// ErrorCode returns the MySQL server error code of err, looking through
+// the errors wrapping it such as the ones of gorm.
+func ErrorCode(err error) (uint16, bool) {
+	var e *mysqldriver.MySQLError
+	if !errors.As(err, &e) {
+		return 0, false
+	}
+	return e.Number, true
+}
+
+func hasErrorCode(err error, codes ...uint16) bool {
+	code, ok := ErrorCode(err)
+	if !ok {
+		return false
+	}
+	for _, c := range codes {
+		if code == c {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDuplicateKey tells if err violates a primary key or unique index.
+func IsDuplicateKey(err error) bool {
+	return errors.Is(err, ErrDuplicateKey) || hasErrorCode(err, ErrorCodeOfDuplicateKey)
+}
+
+// IsDeadlock tells if err is a deadlock, the transaction was rolled back.
+func IsDeadlock(err error) bool {
+	return errors.Is(err, ErrDeadlock) || hasErrorCode(err, ErrorCodeOfDeadlock)
+}
+
+// IsLockWaitTimeout tells if err is a timeout waiting for a row lock.
+func IsLockWaitTimeout(err error) bool {
+	return errors.Is(err, ErrLockWaitTimeout) || hasErrorCode(err, ErrorCodeOfLockWaitTimeout)
+}
+
+// IsForeignKeyViolation tells if err violates a foreign key, either
+// referencing a missing row or deleting a referenced one.
+func IsForeignKeyViolation(err error) bool {
+	return errors.Is(err, ErrForeignKeyViolation) || hasErrorCode(err,
+		ErrorCodeOfNoReferencedRow,
+		ErrorCodeOfRowIsReferenced,
+		ErrorCodeOfRowIsReferenced2,
+		ErrorCodeOfNoReferencedRow2,
+	)
+}
+
+// IsReadOnly tells if err is a write refused by a read only server,
+// such as a replica or a primary after a failover, or transaction.
+func IsReadOnly(err error) bool {
+	return errors.Is(err, ErrReadOnly) || hasErrorCode(err, ErrorCodeOfOptionPrevents, ErrorCodeOfReadOnlyTx)
+}
+
+// IsConnectionLost tells if err is the loss of the connection to the
+// server, the statement may or may not have been run.
+func IsConnectionLost(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, ErrConnectionLost) ||
+		errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, mysqldriver.ErrInvalidConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	// A failed dial never reached the server.
+	var e *net.OpError
+	if errors.As(err, &e) && (e.Op == "read" || e.Op == "write") {
+		return true
+	}
+
+	return hasErrorCode(err,
+		ErrorCodeOfServerShutdown,
+		ErrorCodeOfConnectionKilled,
+		ErrorCodeOfServerGone,
+		ErrorCodeOfServerLost,
+	)
+}
+
+// classifiedError wraps an error with its class.
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() error {
+	return e.err
+}
+
+func (e *classifiedError) Is(target error) bool {
+	return target == e.class
+}
+
+// ClassifyError wraps err so errors.Is matches it to its class, such as
+// ErrDuplicateKey, while errors.As still finds the driver error. Errors
+// of no class are returned as they are.
+func ClassifyError(err error) error {
+	var class error
+	switch {
+	case err == nil:
+		return nil
+	case IsDuplicateKey(err):
+		class = ErrDuplicateKey
+	case IsDeadlock(err):
+		class = ErrDeadlock
+	case IsLockWaitTimeout(err):
+		class = ErrLockWaitTimeout
+	case IsForeignKeyViolation(err):
+		class = ErrForeignKeyViolation
+	case IsReadOnly(err):
+		class = ErrReadOnly
+	case IsConnectionLost(err):
+		class = ErrConnectionLost
+	default:
+		return err
+	}
+
+	if errors.Is(err, class) {
+		return err
+	}
+	return &classifiedError{class: class, err: err}
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func mysqlError(code uint16) error {
+	return &mysqldriver.MySQLError{Number: code, Message: "failed"}
+}
+
+func TestErrorClassification(t *testing.T) {
+	predicates := map[string]func(error) bool{
+		"IsDuplicateKey":        IsDuplicateKey,
+		"IsDeadlock":            IsDeadlock,
+		"IsLockWaitTimeout":     IsLockWaitTimeout,
+		"IsForeignKeyViolation": IsForeignKeyViolation,
+		"IsReadOnly":            IsReadOnly,
+		"IsConnectionLost":      IsConnectionLost,
+	}
+
+	tests := []struct {
+		name  string
+		err   error
+		is    string
+		class error
+	}{
+		{name: "duplicate key", err: mysqlError(1062), is: "IsDuplicateKey", class: ErrDuplicateKey},
+		{name: "deadlock", err: mysqlError(1213), is: "IsDeadlock", class: ErrDeadlock},
+		{name: "lock wait timeout", err: mysqlError(1205), is: "IsLockWaitTimeout", class: ErrLockWaitTimeout},
+		{name: "no referenced row", err: mysqlError(1452), is: "IsForeignKeyViolation", class: ErrForeignKeyViolation},
+		{name: "row is referenced", err: mysqlError(1451), is: "IsForeignKeyViolation", class: ErrForeignKeyViolation},
+		{name: "legacy row is referenced", err: mysqlError(1217), is: "IsForeignKeyViolation", class: ErrForeignKeyViolation},
+		{name: "read only server", err: mysqlError(1290), is: "IsReadOnly", class: ErrReadOnly},
+		{name: "read only transaction", err: mysqlError(1792), is: "IsReadOnly", class: ErrReadOnly},
+		{name: "server gone", err: mysqlError(2006), is: "IsConnectionLost", class: ErrConnectionLost},
+		{name: "connection killed", err: mysqlError(1927), is: "IsConnectionLost", class: ErrConnectionLost},
+		{name: "bad connection", err: driver.ErrBadConn, is: "IsConnectionLost", class: ErrConnectionLost},
+		{name: "invalid connection", err: mysqldriver.ErrInvalidConn, is: "IsConnectionLost", class: ErrConnectionLost},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, is: "IsConnectionLost", class: ErrConnectionLost},
+		{name: "network", err: &net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, is: "IsConnectionLost", class: ErrConnectionLost},
+		{name: "network write", err: &net.OpError{Op: "write", Err: errors.New("broken pipe")}, is: "IsConnectionLost", class: ErrConnectionLost},
+		{name: "dial", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}},
+		{name: "wrapped", err: fmt.Errorf("create user: %w", mysqlError(1062)), is: "IsDuplicateKey", class: ErrDuplicateKey},
+		{name: "gorm wrapped", err: fmt.Errorf("%v; %w", gorm.ErrInvalidTransaction, mysqlError(1213)), is: "IsDeadlock", class: ErrDeadlock},
+		{name: "wrapped bad connection", err: fmt.Errorf("query: %w", driver.ErrBadConn), is: "IsConnectionLost", class: ErrConnectionLost},
+		{name: "sentinel", err: fmt.Errorf("save: %w", ErrReadOnly), is: "IsReadOnly", class: ErrReadOnly},
+		{name: "other code", err: mysqlError(1146)},
+		{name: "record not found", err: gorm.ErrRecordNotFound},
+		{name: "nil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, predicate := range predicates {
+				assert.Equal(t, name == tt.is, predicate(tt.err), name)
+			}
+
+			classified := ClassifyError(tt.err)
+			if tt.class == nil {
+				assert.Equal(t, tt.err, classified)
+				return
+			}
+
+			assert.ErrorIs(t, classified, tt.class)
+			assert.ErrorIs(t, classified, tt.err)
+			assert.Equal(t, tt.err.Error(), classified.Error())
+		})
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	code, ok := ErrorCode(fmt.Errorf("insert: %w", mysqlError(1062)))
+	assert.True(t, ok)
+	assert.Equal(t, ErrorCodeOfDuplicateKey, code)
+
+	_, ok = ErrorCode(errors.New("failed"))
+	assert.False(t, ok)
+
+	var e *mysqldriver.MySQLError
+	assert.ErrorAs(t, ClassifyError(mysqlError(1213)), &e)
+	assert.Equal(t, ErrorCodeOfDeadlock, e.Number)
+}
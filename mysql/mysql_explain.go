@@ -0,0 +1,343 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coolstina/connecter/ratelimit"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// QueryPlan defines the EXPLAIN FORMAT=JSON plan of a slow statement.
+type QueryPlan struct {
+	// JSON is the plan as returned by the server.
+	JSON string
+	// FullScans are the tables read with a full table scan.
+	FullScans []string
+	// Filesort tells the rows are sorted with a filesort.
+	Filesort bool
+	// TemporaryTable tells the statement uses a temporary table.
+	TemporaryTable bool
+	// Err is the error of the EXPLAIN, the other fields are empty then.
+	Err error
+}
+
+// String returns the findings of the plan in short.
+func (p *QueryPlan) String() string {
+	if p.Err != nil {
+		return "failed: " + p.Err.Error()
+	}
+
+	var findings []string
+	if len(p.FullScans) > 0 {
+		findings = append(findings, "full scan of "+strings.Join(p.FullScans, ", "))
+	}
+	if p.Filesort {
+		findings = append(findings, "filesort")
+	}
+	if p.TemporaryTable {
+		findings = append(findings, "temporary table")
+	}
+	if len(findings) == 0 {
+		return "no full scan or filesort"
+	}
+	return strings.Join(findings, "; ")
+}
+
+// parsePlan reads the findings of an EXPLAIN FORMAT=JSON plan.
+func parsePlan(data string) *QueryPlan {
+	plan := &QueryPlan{JSON: data}
+
+	var root interface{}
+	if err := json.Unmarshal([]byte(data), &root); err != nil {
+		plan.Err = fmt.Errorf("mysql: parse plan: %w", err)
+		return plan
+	}
+
+	plan.walk(root)
+	return plan
+}
+
+// walk visits the nodes of the plan, the keys of an object in order so
+// the tables are reported as the plan lists them.
+func (p *QueryPlan) walk(node interface{}) {
+	switch node := node.(type) {
+	case map[string]interface{}:
+		if node["access_type"] == "ALL" {
+			if name, ok := node["table_name"].(string); ok {
+				p.FullScans = append(p.FullScans, name)
+			}
+		}
+		if node["using_filesort"] == true {
+			p.Filesort = true
+		}
+		if node["using_temporary_table"] == true {
+			p.TemporaryTable = true
+		}
+
+		keys := make([]string, 0, len(node))
+		for key := range node {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			p.walk(node[key])
+		}
+	case []interface{}:
+		for _, child := range node {
+			p.walk(child)
+		}
+	}
+}
+
+const explainName = "connecter:explain"
+
+// ErrExplainLogger is returned by NewConnection for WithExplain along
+// with Config.Logger, the plans are logged by the logger of the
+// connection only.
+var ErrExplainLogger = errors.New("mysql: explain needs the logger of the connection")
+
+// errExplainerClosed is returned for the statements explained after
+// the connection closed.
+var errExplainerClosed = errors.New("mysql: explainer closed")
+
+type explainKey struct{}
+
+// explainStatement defines a statement to explain, with its placeholders
+// and parameters as run.
+type explainStatement struct {
+	sql  string
+	vars []interface{}
+}
+
+// withExplainStatement returns ctx carrying the statement to explain.
+func withExplainStatement(ctx context.Context, sql string, vars []interface{}) context.Context {
+	return context.WithValue(ctx, explainKey{}, &explainStatement{sql: sql, vars: vars})
+}
+
+// explainStatementOf returns the statement carried by ctx, the logged
+// SQL has its parameters interpolated for display, never explained.
+func explainStatementOf(ctx context.Context) (*explainStatement, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	statement, ok := ctx.Value(explainKey{}).(*explainStatement)
+	return statement, ok
+}
+
+// explainer explains the sampled slow statements on a pool of its own,
+// a single read only connection to Config.Host opened on first use,
+// whatever the replica that ran them. It is a gorm plugin passing the
+// statements run to the logger, and closed along with the connection.
+type explainer struct {
+	options *explainOptions
+	limiter *ratelimit.Limiter
+	open    func() (*sql.DB, error)
+
+	mu     sync.Mutex
+	db     *sql.DB
+	err    error
+	closed bool
+}
+
+func newExplainer(config *Config, options *options) *explainer {
+	// Only one statement at a time, that cannot write.
+	session := *options
+	session.multiStatements = false
+	session.initStatements = append(append([]string(nil), options.initStatements...), "SET SESSION TRANSACTION READ ONLY")
+	dsn := driverConfig(config.Host, config.Username, config.Password, config.Database, &session).FormatDSN()
+
+	return &explainer{
+		options: options.explain,
+		limiter: ratelimit.New(
+			ratelimit.WithRate(ratelimit.ClassOfRead, options.explain.perSecond, options.explain.burst),
+			ratelimit.WithMode(ratelimit.ModeOfFailFast),
+		),
+		open: func() (*sql.DB, error) {
+			db, err := openDB(driverNameOf(config), dsn, &session)
+			if err != nil {
+				return nil, err
+			}
+			db.SetMaxOpenConns(1)
+			db.SetMaxIdleConns(1)
+			return db, nil
+		},
+	}
+}
+
+// explainerOf returns the explainer of the logger built by newLogger.
+func explainerOf(l logger.Interface) *explainer {
+	switch l := l.(type) {
+	case *handlerLogger:
+		return l.explainer
+	case *explainLogger:
+		return l.explainer
+	}
+	return nil
+}
+
+// Name implements gorm.Plugin.
+func (e *explainer) Name() string {
+	return explainName
+}
+
+// Initialize implements gorm.Plugin, it registers the callbacks passing
+// the SELECT statements run to the logger through their context.
+func (e *explainer) Initialize(db *gorm.DB) error {
+	callbacks := []struct {
+		name     string
+		register func(name string, fn func(*gorm.DB)) error
+	}{
+		{"query", db.Callback().Query().After("gorm:query").Register},
+		{"row", db.Callback().Row().After("gorm:row").Register},
+		{"raw", db.Callback().Raw().After("gorm:raw").Register},
+	}
+
+	for _, callback := range callbacks {
+		if err := callback.register("connecter:explain_"+callback.name, e.statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *explainer) statement(db *gorm.DB) {
+	if db.DryRun || db.Statement.Context == nil || !isSelect(db.Statement.SQL.String()) {
+		return
+	}
+
+	vars := append([]interface{}{}, db.Statement.Vars...)
+	db.Statement.Context = withExplainStatement(db.Statement.Context, db.Statement.SQL.String(), vars)
+}
+
+// Close closes the pool of the explainer, the statements slow later
+// are no longer explained.
+func (e *explainer) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	if e.db == nil {
+		return nil
+	}
+	db := e.db
+	e.db, e.err = nil, errExplainerClosed
+	return db.Close()
+}
+
+// sampled tells whether the statement is a SELECT picked by the sample
+// within the rate.
+func (e *explainer) sampled(statement string) bool {
+	if !isSelect(statement) {
+		return false
+	}
+	if e.options.sample < 1 && rand.Float64() >= e.options.sample {
+		return false
+	}
+	return e.limiter.Allow(ratelimit.ClassOfRead)
+}
+
+// pool returns the pool of the explainer, opening it on first use.
+func (e *explainer) pool() (*sql.DB, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		return nil, errExplainerClosed
+	}
+	if e.db == nil && e.err == nil {
+		e.db, e.err = e.open()
+	}
+	return e.db, e.err
+}
+
+// explain returns the plan of the statement, its error in the plan.
+func (e *explainer) explain(statement *explainStatement) *QueryPlan {
+	db, err := e.pool()
+	if err != nil {
+		return &QueryPlan{Err: err}
+	}
+
+	// The statement may be over already, the plan gets a context of its own.
+	ctx, cancel := context.WithTimeout(context.Background(), e.options.timeout)
+	defer cancel()
+
+	var data string
+	if err := db.QueryRowContext(ctx, "EXPLAIN FORMAT=JSON "+statement.sql, statement.vars...).Scan(&data); err != nil {
+		return &QueryPlan{Err: err}
+	}
+	return parsePlan(data)
+}
+
+// isSelect tells whether the statement is a SELECT, the ones explained,
+// a leading WITH taken as the common table expressions of a SELECT.
+func isSelect(statement string) bool {
+	statement = strings.TrimLeft(statement, " \t\r\n(")
+	if len(statement) > 4 && strings.EqualFold(statement[:4], "WITH") {
+		return strings.ContainsRune(" \t\r\n", rune(statement[4]))
+	}
+	return len(statement) >= 6 && strings.EqualFold(statement[:6], "SELECT")
+}
+
+// explainLogger logs the plans of the slow statements after them for
+// the loggers without entries.
+type explainLogger struct {
+	logger.Interface
+	explainer      *explainer
+	level          logger.LogLevel
+	slowThreshold  time.Duration
+	ignoreNotFound bool
+}
+
+func (l *explainLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.Interface = l.Interface.LogMode(level)
+	clone.level = level
+	return &clone
+}
+
+func (l *explainLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	l.Interface.Trace(ctx, begin, fc, err)
+
+	// Only the statements logged as slow are explained.
+	if err != nil && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.ignoreNotFound) {
+		return
+	}
+	if l.slowThreshold == 0 || elapsed <= l.slowThreshold || l.level < logger.Warn {
+		return
+	}
+
+	statement, ok := explainStatementOf(ctx)
+	if !ok || !l.explainer.sampled(statement.sql) {
+		return
+	}
+
+	go func() {
+		plan := l.explainer.explain(statement)
+		l.Interface.Warn(ctx, "EXPLAIN %s: %s\n%s", plan, statement.sql, plan.JSON)
+	}()
+}
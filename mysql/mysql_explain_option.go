@@ -0,0 +1,60 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import "time"
+
+type ExplainOption interface {
+	apply(*explainOptions)
+}
+
+type explainOptionFunc func(ops *explainOptions)
+
+func (o explainOptionFunc) apply(ops *explainOptions) {
+	o(ops)
+}
+
+type explainOptions struct {
+	sample    float64
+	perSecond float64
+	burst     int
+	timeout   time.Duration
+}
+
+// WithExplainSample Specifies the fraction of the slow SELECT statements
+// explained, between 0 and 1. Default is 1, meaning all of them within
+// the rate.
+func WithExplainSample(sample float64) ExplainOption {
+	return explainOptionFunc(func(ops *explainOptions) {
+		ops.sample = sample
+	})
+}
+
+// WithExplainRate Specifies how many statements are explained per
+// second at most, with bursts of burst. Default is 1 per second.
+func WithExplainRate(perSecond float64, burst int) ExplainOption {
+	return explainOptionFunc(func(ops *explainOptions) {
+		ops.perSecond = perSecond
+		ops.burst = burst
+	})
+}
+
+// WithExplainTimeout Specifies the timeout of a single EXPLAIN. Default
+// is 5s.
+func WithExplainTimeout(timeout time.Duration) ExplainOption {
+	return explainOptionFunc(func(ops *explainOptions) {
+		ops.timeout = timeout
+	})
+}
@@ -0,0 +1,265 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coolstina/connecter"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/logger"
+)
+
+const explainJSON = `{
+  "query_block": {
+    "select_id": 1,
+    "ordering_operation": {
+      "using_filesort": true,
+      "nested_loop": [
+        {"table": {"table_name": "users", "access_type": "ALL", "rows_examined_per_scan": 1000}},
+        {"table": {"table_name": "orders", "access_type": "ref", "key": "idx_user_id"}}
+      ]
+    }
+  }
+}`
+
+func explainHandler(query string, args []interface{}) (driver.Rows, error) {
+	if strings.HasPrefix(query, "EXPLAIN FORMAT=JSON ") {
+		return &fakeRows{columns: []string{"EXPLAIN"}, values: [][]driver.Value{{[]byte(explainJSON)}}}, nil
+	}
+	return nil, nil
+}
+
+func newExplainTestLogger(ops ...Option) logger.Interface {
+	fake.reset()
+	fake.handle(explainHandler)
+	return newLogger(&Config{
+		Database:   "connecter",
+		LogLevel:   int(logger.Warn),
+		DriverName: connecter.DriverName(fakeDriverName),
+	}, resolve(append([]Option{WithSlowThreshold(100 * time.Millisecond)}, ops...)...))
+}
+
+func TestParsePlan(t *testing.T) {
+	plan := parsePlan(explainJSON)
+	assert.NoError(t, plan.Err)
+	assert.Equal(t, []string{"users"}, plan.FullScans)
+	assert.True(t, plan.Filesort)
+	assert.False(t, plan.TemporaryTable)
+	assert.Equal(t, "full scan of users; filesort", plan.String())
+
+	plan = parsePlan(`{"query_block": {"grouping_operation": {"using_temporary_table": true, "table": {"table_name": "orders", "access_type": "range"}}}}`)
+	assert.Empty(t, plan.FullScans)
+	assert.Equal(t, "temporary table", plan.String())
+
+	plan = parsePlan(`{"query_block": {"table": {"table_name": "orders", "access_type": "const"}}}`)
+	assert.Equal(t, "no full scan or filesort", plan.String())
+
+	plan = parsePlan("not json")
+	assert.Error(t, plan.Err)
+	assert.True(t, strings.HasPrefix(plan.String(), "failed: mysql: parse plan"))
+}
+
+func TestIsSelect(t *testing.T) {
+	assert.True(t, isSelect("SELECT * FROM users"))
+	assert.True(t, isSelect("  select id FROM users"))
+	assert.True(t, isSelect("(SELECT id FROM users) UNION (SELECT id FROM admins)"))
+	assert.False(t, isSelect("UPDATE users SET name = 'x'"))
+	assert.False(t, isSelect("SELEC"))
+	assert.True(t, isSelect("WITH recent AS (SELECT id FROM orders) SELECT * FROM recent"))
+	assert.True(t, isSelect("with\nrecent AS (SELECT id FROM orders) SELECT * FROM recent"))
+	assert.False(t, isSelect("WITHDRAW"))
+}
+
+func TestNewConnection_ExplainLogger(t *testing.T) {
+	_, err := NewConnection(&Config{
+		Database:           "connecter",
+		DriverName:         connecter.DriverName(fakeDriverName),
+		SkipCreateDatabase: true,
+		Logger:             logger.Discard,
+	}, WithExplain())
+	assert.ErrorIs(t, err, ErrExplainLogger)
+}
+
+func TestHandlerLogger_Explain(t *testing.T) {
+	entries := make(chan LogEntry, 4)
+	l := newExplainTestLogger(
+		WithLogHandler(func(ctx context.Context, entry LogEntry) {
+			entries <- entry
+		}),
+		WithExplain(WithExplainRate(0.001, 1)),
+	)
+
+	slow := time.Now().Add(-time.Second)
+	ctx := withExplainStatement(context.Background(),
+		"SELECT * FROM users JOIN orders ON orders.user_id = users.id WHERE name = ? ORDER BY name", []interface{}{"tom"},
+	)
+	query := func() (string, int64) {
+		return "SELECT * FROM users JOIN orders ON orders.user_id = users.id WHERE name = 'tom' ORDER BY name", 3
+	}
+
+	// Without the statement as run, the logged SQL is not explained.
+	l.Trace(context.Background(), slow, query, nil)
+	entry := <-entries
+	assert.True(t, entry.Slow)
+	assert.Nil(t, entry.Plan)
+
+	l.Trace(ctx, slow, query, nil)
+	entry = <-entries
+	assert.True(t, entry.Slow)
+	if assert.NotNil(t, entry.Plan) {
+		assert.NoError(t, entry.Plan.Err)
+		assert.Equal(t, []string{"users"}, entry.Plan.FullScans)
+		assert.True(t, entry.Plan.Filesort)
+		assert.Equal(t, explainJSON, entry.Plan.JSON)
+	}
+
+	calls := fake.recorded()
+	assert.Contains(t, queriesOf(calls), "SET SESSION TRANSACTION READ ONLY")
+	explained := calls[len(calls)-1]
+	assert.Equal(t, "EXPLAIN FORMAT=JSON SELECT * FROM users JOIN orders ON orders.user_id = users.id WHERE name = ? ORDER BY name", explained.Query)
+	assert.Equal(t, []interface{}{"tom"}, explained.Args)
+
+	// Over the rate, the entry comes right away without a plan.
+	l.Trace(ctx, slow, query, nil)
+	entry = <-entries
+	assert.True(t, entry.Slow)
+	assert.Nil(t, entry.Plan)
+
+	// Fast statements are left alone.
+	l.Trace(ctx, time.Now(), query, nil)
+	assert.Empty(t, entries)
+}
+
+func TestHandlerLogger_ExplainSelectOnly(t *testing.T) {
+	entries := make(chan LogEntry, 4)
+	l := newExplainTestLogger(
+		WithLogHandler(func(ctx context.Context, entry LogEntry) {
+			entries <- entry
+		}),
+		WithExplain(WithExplainRate(100, 10)),
+	)
+
+	l.Trace(context.Background(), time.Now().Add(-time.Second), func() (string, int64) {
+		return "UPDATE users SET name = 'x'", 1
+	}, nil)
+	entry := <-entries
+	assert.True(t, entry.Slow)
+	assert.Nil(t, entry.Plan)
+
+	l = newExplainTestLogger(WithExplain(WithExplainSample(0)), WithLogHandler(func(ctx context.Context, entry LogEntry) {
+		entries <- entry
+	}))
+	ctx := withExplainStatement(context.Background(), "SELECT * FROM users", nil)
+	l.Trace(ctx, time.Now().Add(-time.Second), func() (string, int64) {
+		return "SELECT * FROM users", 1
+	}, nil)
+	entry = <-entries
+	assert.Nil(t, entry.Plan)
+	assert.Empty(t, fake.recorded())
+
+	l = newExplainTestLogger(WithExplain(), WithRedactParams(true), WithLogHandler(func(context.Context, LogEntry) {}))
+	assert.Nil(t, l.(*handlerLogger).explainer)
+}
+
+// syncBuffer is a buffer written by the explaining goroutine.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestExplainLogger_Trace(t *testing.T) {
+	var buf syncBuffer
+	l := newExplainTestLogger(WithLogWriter(&buf), WithColorful(false), WithExplain(WithExplainRate(100, 10)))
+	_, ok := l.(*explainLogger)
+	assert.True(t, ok)
+
+	query := func() (string, int64) { return "SELECT * FROM users ORDER BY name", 3 }
+	ctx := withExplainStatement(context.Background(), "SELECT * FROM users ORDER BY name", nil)
+	l.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+	assert.Contains(t, buf.String(), "SLOW SQL")
+	assert.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), "EXPLAIN full scan of users; filesort: SELECT * FROM users ORDER BY name")
+	}, time.Second, 10*time.Millisecond)
+	assert.Contains(t, buf.String(), `"using_filesort": true`)
+
+	// Silenced, the plans are neither logged.
+	calls := len(fake.recorded())
+	l.LogMode(logger.Error).Trace(ctx, time.Now().Add(-time.Second), query, nil)
+	assert.Len(t, fake.recorded(), calls)
+}
+
+func TestNewConnection_Explain(t *testing.T) {
+	fake.reset()
+	entries := make(chan LogEntry, 4)
+	db, err := NewConnection(&Config{
+		Database:           "connecter",
+		LogLevel:           int(logger.Warn),
+		DriverName:         connecter.DriverName(fakeDriverName),
+		SkipCreateDatabase: true,
+	},
+		WithSlowThreshold(time.Nanosecond),
+		WithExplain(WithExplainRate(100, 10)),
+		WithLogHandler(func(ctx context.Context, entry LogEntry) {
+			entries <- entry
+		}),
+	)
+	assert.NoError(t, err)
+
+	fake.reset()
+	fake.handle(explainHandler)
+	assert.NoError(t, db.Where("name = ?", "tom").Find(&[]user{}).Error)
+
+	entry := <-entries
+	assert.Equal(t, "SELECT * FROM `users` WHERE name = \"tom\"", entry.SQL)
+	if assert.NotNil(t, entry.Plan) {
+		assert.NoError(t, entry.Plan.Err)
+	}
+
+	var explained []fakeCall
+	for _, call := range fake.recorded() {
+		if strings.HasPrefix(call.Query, "EXPLAIN") {
+			explained = append(explained, call)
+		}
+	}
+	assert.Equal(t, []fakeCall{{
+		DSN:   explained[0].DSN,
+		Query: "EXPLAIN FORMAT=JSON SELECT * FROM `users` WHERE name = ?",
+		Args:  []interface{}{"tom"},
+	}}, explained)
+
+	// The explain connection closes with the connection.
+	assert.NoError(t, Close(db))
+	plan := explainerOf(db.Logger).explain(&explainStatement{sql: "SELECT 1"})
+	assert.Equal(t, errExplainerClosed, plan.Err)
+}
@@ -0,0 +1,318 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// fixtureTimeLayout is the layout of the now and nowAdd functions.
+const fixtureTimeLayout = "2006-01-02 15:04:05"
+
+// fixture defines the rows of a table read from its file.
+type fixture struct {
+	table string
+	rows  []map[string]interface{}
+}
+
+// LoadFixtures replaces the rows of the tables of the fixture files of
+// dir, one <table>.yml, <table>.yaml or <table>.json file per table
+// holding a list of rows. The tables are cleared children first and
+// loaded parents first following their foreign keys, in a transaction.
+// Calling it again, such as before every test, resets the tables.
+//
+// The files are Go templates with the functions:
+//
+//	now            the current time, such as 2021-12-01 09:30:00
+//	nowAdd "-24h"  the current time shifted by a time.Duration
+//	seq "users"    the next number of a named sequence, from 1
+func LoadFixtures(ctx context.Context, db *gorm.DB, fsys fs.FS, dir string, ops ...FixtureOption) error {
+	fixtures, err := readFixtures(fsys, dir, ops...)
+	if err != nil {
+		return err
+	}
+
+	return replaceFixtures(ctx, db, fixtures, true)
+}
+
+// ClearFixtures deletes the rows of the tables of the fixture files of
+// dir, children first.
+func ClearFixtures(ctx context.Context, db *gorm.DB, fsys fs.FS, dir string, ops ...FixtureOption) error {
+	fixtures, err := readFixtures(fsys, dir, ops...)
+	if err != nil {
+		return err
+	}
+
+	return replaceFixtures(ctx, db, fixtures, false)
+}
+
+// replaceFixtures clears the tables of the fixtures and loads their rows
+// if load.
+func replaceFixtures(ctx context.Context, db *gorm.DB, fixtures []fixture, load bool) error {
+	return WithTx(ctx, db, func(ctx context.Context, tx *gorm.DB) (err error) {
+		order, cycle, err := fixtureOrder(tx, fixtures)
+		if err != nil {
+			return err
+		}
+
+		// The session outlives the transaction, the checks are restored
+		// before the connection returns to the pool.
+		if cycle {
+			if err := tx.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+				return err
+			}
+			defer func() {
+				if e := tx.Exec("SET FOREIGN_KEY_CHECKS = 1").Error; e != nil && err == nil {
+					err = e
+				}
+			}()
+		}
+
+		for i := len(order) - 1; i >= 0; i-- {
+			if err := tx.Exec("DELETE FROM " + order[i].table).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, f := range order {
+			if !load || len(f.rows) == 0 {
+				continue
+			}
+			sql, vars := insertFixture(f)
+			if err := tx.Exec(sql, vars...).Error; err != nil {
+				return fmt.Errorf("mysql: fixture %s: %w", f.table, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func readFixtures(fsys fs.FS, dir string, ops ...FixtureOption) ([]fixture, error) {
+	options := &fixtureOptions{now: time.Now, funcs: template.FuncMap{}}
+	for _, o := range ops {
+		o.apply(options)
+	}
+
+	sequences := make(map[string]int)
+	funcs := template.FuncMap{
+		"now": func() string {
+			return options.now().Format(fixtureTimeLayout)
+		},
+		"nowAdd": func(d string) (string, error) {
+			duration, err := time.ParseDuration(d)
+			if err != nil {
+				return "", err
+			}
+			return options.now().Add(duration).Format(fixtureTimeLayout), nil
+		},
+		"seq": func(name string) int {
+			sequences[name]++
+			return sequences[name]
+		},
+	}
+	for name, fn := range options.funcs {
+		funcs[name] = fn
+	}
+
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures []fixture
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yml" && ext != ".yaml" && ext != ".json") {
+			continue
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		t, err := template.New(entry.Name()).Funcs(funcs).Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("mysql: fixture %s: %w", entry.Name(), err)
+		}
+
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, nil); err != nil {
+			return nil, fmt.Errorf("mysql: fixture %s: %w", entry.Name(), err)
+		}
+
+		// JSON is YAML as well.
+		f := fixture{table: strings.TrimSuffix(entry.Name(), ext)}
+		if err := yaml.Unmarshal(buf.Bytes(), &f.rows); err != nil {
+			return nil, fmt.Errorf("mysql: fixture %s: %w", entry.Name(), err)
+		}
+
+		if f.table, err = QuoteIdentifier(f.table); err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, f)
+	}
+
+	return fixtures, nil
+}
+
+// fixtureOrder sorts the fixtures parents first from the foreign keys
+// of the current database, cycle tells the tables reference each other
+// in a cycle no order satisfies.
+func fixtureOrder(tx *gorm.DB, fixtures []fixture) (order []fixture, cycle bool, err error) {
+	rows, err := tx.Raw("SELECT TABLE_NAME, REFERENCED_TABLE_NAME FROM information_schema.KEY_COLUMN_USAGE " +
+		"WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL").Rows()
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	byTable := make(map[string]fixture, len(fixtures))
+	for _, f := range fixtures {
+		byTable[f.table] = f
+	}
+
+	parents := make(map[string]map[string]bool)
+	for rows.Next() {
+		var table, referenced string
+		if err := rows.Scan(&table, &referenced); err != nil {
+			return nil, false, err
+		}
+
+		table, _ = QuoteIdentifier(table)
+		referenced, _ = QuoteIdentifier(referenced)
+		if _, ok := byTable[table]; !ok || table == referenced {
+			continue
+		}
+		if _, ok := byTable[referenced]; !ok {
+			continue
+		}
+
+		if parents[table] == nil {
+			parents[table] = make(map[string]bool)
+		}
+		parents[table][referenced] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	tables := make([]string, 0, len(fixtures))
+	for _, f := range fixtures {
+		tables = append(tables, f.table)
+	}
+	sort.Strings(tables)
+
+	done := make(map[string]bool, len(tables))
+	for len(order) < len(tables) {
+		progressed := false
+		for _, table := range tables {
+			if done[table] {
+				continue
+			}
+
+			ready := true
+			for parent := range parents[table] {
+				ready = ready && done[parent]
+			}
+
+			if ready {
+				order = append(order, byTable[table])
+				done[table] = true
+				progressed = true
+			}
+		}
+
+		// A cycle, the remaining tables load in name order.
+		if !progressed {
+			cycle = true
+			for _, table := range tables {
+				if !done[table] {
+					order = append(order, byTable[table])
+					done[table] = true
+				}
+			}
+		}
+	}
+
+	return order, cycle, nil
+}
+
+// insertFixture builds the INSERT statement of the rows, the columns a
+// row has no value for take their default.
+func insertFixture(f fixture) (string, []interface{}) {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, row := range f.rows {
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = "`" + strings.ReplaceAll(column, "`", "``") + "`"
+	}
+
+	var vars []interface{}
+	values := make([]string, 0, len(f.rows))
+	for _, row := range f.rows {
+		placeholders := make([]string, len(columns))
+		for i, column := range columns {
+			value, ok := row[column]
+			if !ok {
+				placeholders[i] = "DEFAULT"
+				continue
+			}
+			placeholders[i] = "?"
+			vars = append(vars, fixtureValue(value))
+		}
+		values = append(values, "("+strings.Join(placeholders, ",")+")")
+	}
+
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", f.table, strings.Join(quoted, ","), strings.Join(values, ",")), vars
+}
+
+// fixtureValue converts the nested lists and maps to JSON, such as for
+// JSON columns.
+func fixtureValue(value interface{}) interface{} {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Sprint(value)
+		}
+		return string(data)
+	default:
+		return value
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"text/template"
+	"time"
+)
+
+type FixtureOption interface {
+	apply(*fixtureOptions)
+}
+
+type fixtureOptionFunc func(ops *fixtureOptions)
+
+func (o fixtureOptionFunc) apply(ops *fixtureOptions) {
+	o(ops)
+}
+
+type fixtureOptions struct {
+	now   func() time.Time
+	funcs template.FuncMap
+}
+
+// WithFixtureNow Specifies the clock of the now and nowAdd template
+// functions, such as a fixed time. Default is time.Now.
+func WithFixtureNow(now func() time.Time) FixtureOption {
+	return fixtureOptionFunc(func(ops *fixtureOptions) {
+		ops.now = now
+	})
+}
+
+// WithFixtureFuncs Specifies extra template functions of the fixture
+// files, they replace the built-in functions of the same name.
+func WithFixtureFuncs(funcs template.FuncMap) FixtureOption {
+	return fixtureOptionFunc(func(ops *fixtureOptions) {
+		for name, fn := range funcs {
+			ops.funcs[name] = fn
+		}
+	})
+}
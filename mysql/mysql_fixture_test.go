@@ -0,0 +1,138 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// foreignKeysHandler answers the foreign keys query with the pairs of
+// table and referenced table.
+func foreignKeysHandler(pairs ...string) fakeHandler {
+	return func(query string, args []interface{}) (driver.Rows, error) {
+		if !strings.Contains(query, "information_schema.KEY_COLUMN_USAGE") {
+			return nil, nil
+		}
+
+		rows := &fakeRows{columns: []string{"TABLE_NAME", "REFERENCED_TABLE_NAME"}}
+		for i := 0; i+1 < len(pairs); i += 2 {
+			rows.values = append(rows.values, []driver.Value{pairs[i], pairs[i+1]})
+		}
+		return rows, nil
+	}
+}
+
+func fixtureNow() time.Time {
+	return time.Date(2021, 12, 1, 9, 30, 0, 0, time.UTC)
+}
+
+func TestLoadFixtures(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(foreignKeysHandler("orders", "users", "users", "users", "orders", "products"))
+
+	err := LoadFixtures(context.Background(), db, os.DirFS("../test/data/mysql"), "fixtures", WithFixtureNow(fixtureNow))
+	assert.NoError(t, err)
+
+	calls := fake.recorded()
+	queries := queriesOf(calls)
+	assert.Equal(t, []string{
+		"START TRANSACTION",
+		"SELECT TABLE_NAME, REFERENCED_TABLE_NAME FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL",
+		"DELETE FROM `orders`",
+		"DELETE FROM `users`",
+		"INSERT INTO `users` (`created_at`,`id`,`name`,`profile`) VALUES (?,?,?,DEFAULT),(?,?,?,?)",
+		"INSERT INTO `orders` (`amount`,`id`,`user_id`) VALUES (?,?,?),(DEFAULT,?,?)",
+		"COMMIT",
+	}, queries)
+
+	assert.Equal(t, []interface{}{
+		"2021-12-01 09:30:00", int64(1), "tom",
+		"2021-11-30 09:30:00", int64(2), "jerry", `{"age":3}`,
+	}, calls[4].Args)
+	assert.Equal(t, []interface{}{9.5, int64(1), int64(1), int64(2), int64(2)}, calls[5].Args)
+}
+
+func TestLoadFixtures_Cycle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fixtures/a.yml":   {Data: []byte("- id: {{ seq \"a\" }}\n- id: {{ seq \"a\" }}\n")},
+		"fixtures/b.yaml":  {Data: []byte("- id: {{ code }}\n")},
+		"fixtures/c.json":  {Data: []byte("[]")},
+		"fixtures/notes.t": {Data: []byte("ignored")},
+	}
+
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(foreignKeysHandler("a", "b", "b", "a"))
+
+	err := LoadFixtures(context.Background(), db, fsys, "fixtures", WithFixtureFuncs(template.FuncMap{
+		"code": func() string { return "B-1" },
+	}))
+	assert.NoError(t, err)
+
+	calls := fake.recorded()
+	assert.Equal(t, []string{
+		"SET FOREIGN_KEY_CHECKS = 0",
+		"DELETE FROM `b`",
+		"DELETE FROM `a`",
+		"DELETE FROM `c`",
+		"INSERT INTO `a` (`id`) VALUES (?),(?)",
+		"INSERT INTO `b` (`id`) VALUES (?)",
+		"SET FOREIGN_KEY_CHECKS = 1",
+		"COMMIT",
+	}, queriesOf(calls)[2:])
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, calls[6].Args)
+	assert.Equal(t, []interface{}{"B-1"}, calls[7].Args)
+}
+
+func TestClearFixtures(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(foreignKeysHandler("orders", "users"))
+
+	err := ClearFixtures(context.Background(), db, os.DirFS("../test/data/mysql"), "fixtures")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"DELETE FROM `orders`",
+		"DELETE FROM `users`",
+		"COMMIT",
+	}, queriesOf(fake.recorded())[2:])
+}
+
+func TestLoadFixtures_Errors(t *testing.T) {
+	db := newFakeDB(t)
+	grid := map[string]fstest.MapFS{
+		"template": {"fixtures/users.yml": {Data: []byte("- id: {{ unknown }}")}},
+		"duration": {"fixtures/users.yml": {Data: []byte("- at: {{ nowAdd \"1 day\" }}")}},
+		"yaml":     {"fixtures/users.yml": {Data: []byte("id: 1")}},
+		"table":    {"fixtures/.yml": {Data: []byte("[]")}},
+	}
+
+	for name, fsys := range grid {
+		fake.reset()
+		err := LoadFixtures(context.Background(), db, fsys, "fixtures")
+		assert.Error(t, err, name)
+		assert.Empty(t, fake.recorded(), name)
+	}
+}
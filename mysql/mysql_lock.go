@@ -0,0 +1,242 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"math"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrLockNotAcquired is returned when the lock is held by another
+	// session until the timeout.
+	ErrLockNotAcquired = errors.New("mysql: lock not acquired")
+
+	// ErrLockLost is returned by Release when the lock was no longer held.
+	ErrLockLost = errors.New("mysql: lock lost")
+
+	// ErrLockNoDatabase is returned when locking without a selected
+	// database, lock names are scoped to it.
+	ErrLockNoDatabase = errors.New("mysql: lock needs a selected database")
+)
+
+// maxLockNameLength is the maximum length of a lock name, longer names
+// are hashed.
+const maxLockNameLength = 64
+
+// AdvisoryLock is a lock taken with GET_LOCK, it is held by the
+// connection it pins until released or the connection is lost.
+type AdvisoryLock struct {
+	name string
+	// key is the name of the lock on the server.
+	key  string
+	conn *sql.Conn
+	// id is the connection id of conn, the owner of the lock.
+	id   int64
+	lost chan struct{}
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// Lock acquires the named lock, waiting up to timeout for other
+// sessions to release it, a negative timeout waits forever and other
+// timeouts are rounded up to the second. Names are scoped to the
+// current database, those longer than 64 characters once scoped are
+// hashed. The lock pins a connection of db until released.
+func Lock(ctx context.Context, db *gorm.DB, name string, timeout time.Duration, ops ...LockOption) (*AdvisoryLock, error) {
+	options := &lockOptions{
+		checkInterval: 5 * time.Second,
+	}
+
+	for _, o := range ops {
+		o.apply(options)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seconds := int64(math.Ceil(timeout.Seconds()))
+	if timeout < 0 {
+		seconds = -1
+	}
+
+	l := &AdvisoryLock{
+		name: name,
+		conn: conn,
+		lost: make(chan struct{}),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	var database sql.NullString
+	if err := conn.QueryRowContext(ctx, "SELECT DATABASE(), CONNECTION_ID()").Scan(&database, &l.id); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	if !database.Valid {
+		_ = conn.Close()
+		return nil, ErrLockNoDatabase
+	}
+	l.key = lockKey(database.String, name)
+
+	var acquired sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", l.key, seconds).Scan(&acquired)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	if !acquired.Valid || acquired.Int64 != 1 {
+		_ = conn.Close()
+		return nil, ErrLockNotAcquired
+	}
+
+	if options.checkInterval <= 0 {
+		close(l.done)
+		return l, nil
+	}
+	go l.watch(options.checkInterval)
+
+	return l, nil
+}
+
+// TryLock acquires the named lock if no other session holds it, it
+// returns ErrLockNotAcquired otherwise.
+func TryLock(ctx context.Context, db *gorm.DB, name string, ops ...LockOption) (*AdvisoryLock, error) {
+	return Lock(ctx, db, name, 0, ops...)
+}
+
+// Name returns the name of the lock.
+func (l *AdvisoryLock) Name() string {
+	return l.name
+}
+
+// Conn returns the connection holding the lock, such as for work that
+// must stop if the lock is lost along with the connection. The checks
+// of the lock run on it between the statements of the caller, rows
+// must not be left open across checks.
+func (l *AdvisoryLock) Conn() *sql.Conn {
+	return l.conn
+}
+
+// Lost returns a channel closed when the lock is found no longer held,
+// such as after the connection was lost.
+func (l *AdvisoryLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Release releases the lock and returns its connection to the pool,
+// even if the context of Lock is done. Only the first call releases.
+func (l *AdvisoryLock) Release() error {
+	var err error
+	l.once.Do(func() {
+		err = l.release()
+	})
+	return err
+}
+
+func (l *AdvisoryLock) release() error {
+	close(l.stop)
+	<-l.done
+
+	var released sql.NullInt64
+	err := l.conn.QueryRowContext(context.Background(),
+		"SELECT RELEASE_LOCK(?)", l.key,
+	).Scan(&released)
+	if err == nil && (!released.Valid || released.Int64 != 1) {
+		err = ErrLockLost
+	}
+
+	// The connection of a lost lock may be gone too.
+	select {
+	case <-l.lost:
+		if err != nil {
+			err = ErrLockLost
+		}
+	default:
+	}
+
+	if closeErr := l.conn.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (l *AdvisoryLock) watch(interval time.Duration) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if held, ok := l.held(); ok && !held {
+				close(l.lost)
+				return
+			}
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// held tells if the connection still holds the lock, checking on the
+// connection itself so the check needs no other connection of the
+// pool. The lock is lost along with the connection. ok is false when
+// the check failed otherwise, the lock may still be held then.
+func (l *AdvisoryLock) held() (held bool, ok bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var owner sql.NullInt64
+	err := l.conn.QueryRowContext(ctx, "SELECT IS_USED_LOCK(?)", l.key).Scan(&owner)
+	if IsConnectionLost(err) {
+		return false, true
+	}
+	if err != nil {
+		return false, false
+	}
+	return owner.Valid && owner.Int64 == l.id, true
+}
+
+// lockKey get the name of a lock on the server, scoped to the database
+// and hashed when too long.
+func lockKey(database, name string) string {
+	key := database + "." + name
+	if utf8.RuneCountInString(key) <= maxLockNameLength {
+		return key
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,41 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"time"
+)
+
+type LockOption interface {
+	apply(*lockOptions)
+}
+
+type lockOptionFunc func(ops *lockOptions)
+
+func (o lockOptionFunc) apply(ops *lockOptions) {
+	o(ops)
+}
+
+type lockOptions struct {
+	checkInterval time.Duration
+}
+
+// WithLockCheckInterval Specifies the interval between two checks that
+// the lock is still held, 0 disables the checks. Default is 5 seconds.
+func WithLockCheckInterval(interval time.Duration) LockOption {
+	return lockOptionFunc(func(ops *lockOptions) {
+		ops.checkInterval = interval
+	})
+}
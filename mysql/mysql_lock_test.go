@@ -0,0 +1,187 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// The states of a lock answered by lockHandler.
+const (
+	lockOfFree int64 = iota
+	lockOfHeld
+	lockOfOther
+	lockOfUnknown
+	lockOfConnectionLost
+)
+
+// lockHandler answers the lock queries, GET_LOCK with acquired, the
+// lock connection is 7, as answered by the fake driver, and
+// IS_USED_LOCK answers with the state.
+func lockHandler(acquired int64, state *int64) fakeHandler {
+	return func(query string, args []interface{}) (driver.Rows, error) {
+		switch {
+		case strings.HasPrefix(query, "SELECT GET_LOCK"):
+			return &fakeRows{columns: []string{"lock"}, values: [][]driver.Value{{acquired}}}, nil
+		case strings.HasPrefix(query, "SELECT IS_USED_LOCK"):
+			owner := map[int64]driver.Value{lockOfFree: nil, lockOfHeld: int64(7), lockOfOther: int64(9)}
+			s := atomic.LoadInt64(state)
+			switch s {
+			case lockOfUnknown:
+				return nil, context.DeadlineExceeded
+			case lockOfConnectionLost:
+				return nil, driver.ErrBadConn
+			}
+			return &fakeRows{columns: []string{"owner"}, values: [][]driver.Value{{owner[s]}}}, nil
+		case strings.HasPrefix(query, "SELECT RELEASE_LOCK"):
+			released := int64(0)
+			if atomic.LoadInt64(state) == lockOfHeld {
+				released = 1
+			}
+			return &fakeRows{columns: []string{"released"}, values: [][]driver.Value{{released}}}, nil
+		}
+		return nil, nil
+	}
+}
+
+func TestLock(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	held := lockOfHeld
+	fake.handle(lockHandler(1, &held))
+
+	lock, err := Lock(context.Background(), db, "cron.report", 10*time.Second, WithLockCheckInterval(0))
+	assert.NoError(t, err)
+	assert.Equal(t, "cron.report", lock.Name())
+
+	assert.NoError(t, lock.Release())
+	assert.NoError(t, lock.Release())
+
+	calls := fake.recorded()
+	assert.Equal(t, []string{
+		"SELECT DATABASE(), CONNECTION_ID()",
+		"SELECT GET_LOCK(?, ?)",
+		"SELECT RELEASE_LOCK(?)",
+	}, queriesOf(calls))
+	assert.Equal(t, []interface{}{"connecter.cron.report", int64(10)}, calls[1].Args)
+	assert.Equal(t, []interface{}{"connecter.cron.report"}, calls[2].Args)
+}
+
+func TestLock_Name(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	held := lockOfHeld
+	fake.handle(lockHandler(1, &held))
+
+	// Sub-second timeouts wait a second rather than not at all.
+	name := strings.Repeat("report.", 10)
+	lock, err := Lock(context.Background(), db, name, 100*time.Millisecond, WithLockCheckInterval(0))
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Release())
+
+	key := lockKey("connecter", name)
+	assert.Len(t, key, 64)
+	assert.NotEqual(t, key, lockKey("connecter", name+"x"))
+	assert.Equal(t, []interface{}{key, int64(1)}, fake.recorded()[1].Args)
+	assert.Equal(t, "connecter.cron.report", lockKey("connecter", "cron.report"))
+}
+
+func TestLock_NoDatabase(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(func(query string, args []interface{}) (driver.Rows, error) {
+		if query == "SELECT DATABASE(), CONNECTION_ID()" {
+			return &fakeRows{columns: []string{"DATABASE()", "CONNECTION_ID()"}, values: [][]driver.Value{{nil, int64(7)}}}, nil
+		}
+		return nil, nil
+	})
+
+	lock, err := Lock(context.Background(), db, "cron.report", time.Second)
+	assert.Equal(t, ErrLockNoDatabase, err)
+	assert.Nil(t, lock)
+	assert.Equal(t, []string{"SELECT DATABASE(), CONNECTION_ID()"}, queriesOf(fake.recorded()))
+}
+
+func TestLock_Wait(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	held := lockOfHeld
+	fake.handle(lockHandler(1, &held))
+
+	lock, err := Lock(context.Background(), db, "cron.report", -1, WithLockCheckInterval(0))
+	assert.NoError(t, err)
+	defer lock.Release()
+
+	assert.Equal(t, []interface{}{"connecter.cron.report", int64(-1)}, fake.recorded()[1].Args)
+}
+
+func TestTryLock(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	held := lockOfFree
+	fake.handle(lockHandler(0, &held))
+
+	lock, err := TryLock(context.Background(), db, "cron.report")
+	assert.ErrorIs(t, err, ErrLockNotAcquired)
+	assert.Nil(t, lock)
+	assert.Equal(t, []interface{}{"connecter.cron.report", int64(0)}, fake.recorded()[1].Args)
+}
+
+func TestLock_Lost(t *testing.T) {
+	grid := []struct {
+		name  string
+		state int64
+	}{
+		{name: "free", state: lockOfFree},
+		{name: "other connection", state: lockOfOther},
+		{name: "connection lost", state: lockOfConnectionLost},
+	}
+
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			db := newFakeDB(t)
+			fake.reset()
+			state := lockOfHeld
+			fake.handle(lockHandler(1, &state))
+
+			lock, err := Lock(context.Background(), db, "cron.report", time.Second, WithLockCheckInterval(10*time.Millisecond))
+			assert.NoError(t, err)
+
+			// A failed check doesn't tell the lock is lost.
+			atomic.StoreInt64(&state, lockOfUnknown)
+			select {
+			case <-lock.Lost():
+				t.Fatal("lock lost while held")
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			atomic.StoreInt64(&state, g.state)
+			select {
+			case <-lock.Lost():
+			case <-time.After(time.Second):
+				t.Fatal("lost lock not reported")
+			}
+
+			assert.ErrorIs(t, lock.Release(), ErrLockLost)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils"
+)
+
+// LogEntry defines a log record of the gorm logger.
+type LogEntry struct {
+	Level   logger.LogLevel
+	Message string
+	// SQL, Rows and Elapsed are set for the statements only, Rows is -1
+	// when unknown.
+	SQL     string
+	Rows    int64
+	Elapsed time.Duration
+	// Slow tells the statement took longer than the slow threshold.
+	Slow   bool
+	Err    error
+	Caller string
+	// Plan is the EXPLAIN of a slow SELECT sampled by WithExplain.
+	Plan *QueryPlan
+}
+
+// LogHandler receives the log entries, such as to forward them to a
+// structured logger.
+type LogHandler func(ctx context.Context, entry LogEntry)
+
+// newLogger returns the logger of the config, or builds one from its
+// LogLevel and the options, explaining the slow statements with
+// WithExplain. NewConnection rejects WithExplain along with the logger
+// of the config, which would not explain them.
+func newLogger(config *Config, options *options) logger.Interface {
+	if config.Logger != nil {
+		return config.Logger
+	}
+
+	level := logger.LogLevel(config.LogLevel)
+	if level == 0 {
+		level = logger.Warn
+	}
+
+	var explainer *explainer
+	if options.explain != nil && !options.redactParams {
+		explainer = newExplainer(config, options)
+	}
+
+	if options.logHandler != nil {
+		return &handlerLogger{
+			level:          level,
+			slowThreshold:  options.slowThreshold,
+			ignoreNotFound: options.ignoreRecordNotFound,
+			handler:        options.logHandler,
+			explainer:      explainer,
+		}
+	}
+
+	w := options.logWriter
+	if w == nil {
+		w = os.Stdout
+	}
+
+	l := logger.New(log.New(w, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold:             options.slowThreshold,
+		Colorful:                  options.colorful,
+		IgnoreRecordNotFoundError: options.ignoreRecordNotFound,
+		LogLevel:                  level,
+	})
+
+	if explainer == nil {
+		return l
+	}
+
+	return &explainLogger{
+		Interface:      l,
+		explainer:      explainer,
+		level:          level,
+		slowThreshold:  options.slowThreshold,
+		ignoreNotFound: options.ignoreRecordNotFound,
+	}
+}
+
+// handlerLogger passes the log to a LogHandler with the same levels
+// and rules as the gorm logger.
+type handlerLogger struct {
+	level          logger.LogLevel
+	slowThreshold  time.Duration
+	ignoreNotFound bool
+	handler        LogHandler
+	explainer      *explainer
+}
+
+func (l *handlerLogger) LogMode(level logger.LogLevel) logger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *handlerLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	l.log(ctx, logger.Info, msg, data...)
+}
+
+func (l *handlerLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	l.log(ctx, logger.Warn, msg, data...)
+}
+
+func (l *handlerLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	l.log(ctx, logger.Error, msg, data...)
+}
+
+func (l *handlerLogger) log(ctx context.Context, level logger.LogLevel, msg string, data ...interface{}) {
+	if l.level >= level {
+		l.handler(ctx, LogEntry{
+			Level:   level,
+			Message: fmt.Sprintf(msg, data...),
+			Caller:  caller(),
+		})
+	}
+}
+
+// loggerFile is the file of the logger, skipped with the gorm frames
+// when looking for the caller.
+var loggerFile = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return file
+}()
+
+// caller returns the first caller outside of the logger and gorm, the
+// same frame utils.FileWithLineNum finds for Trace.
+func caller() string {
+	for i := 1; i < 16; i++ {
+		_, file, line, ok := runtime.Caller(i)
+		if !ok {
+			break
+		}
+		if file == loggerFile || strings.Contains(file, "gorm.io/gorm") && !strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		return file + ":" + strconv.Itoa(line)
+	}
+	return ""
+}
+
+func (l *handlerLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+
+	entry := LogEntry{Elapsed: time.Since(begin)}
+	switch {
+	case err != nil && l.level >= logger.Error && (!errors.Is(err, gorm.ErrRecordNotFound) || !l.ignoreNotFound):
+		entry.Level, entry.Message, entry.Err = logger.Error, err.Error(), err
+	case entry.Elapsed > l.slowThreshold && l.slowThreshold != 0 && l.level >= logger.Warn:
+		entry.Level, entry.Message, entry.Slow = logger.Warn, fmt.Sprintf("SLOW SQL >= %v", l.slowThreshold), true
+	case l.level == logger.Info:
+		entry.Level = logger.Info
+	default:
+		return
+	}
+
+	entry.SQL, entry.Rows = fc()
+	entry.Caller = utils.FileWithLineNum()
+
+	// The entry waits for the plan, not the statement.
+	if entry.Slow && l.explainer != nil {
+		if statement, ok := explainStatementOf(ctx); ok && l.explainer.sampled(statement.sql) {
+			go func() {
+				entry.Plan = l.explainer.explain(statement)
+				l.handler(ctx, entry)
+			}()
+			return
+		}
+	}
+
+	l.handler(ctx, entry)
+}
+
+// redactedDialector explains the statements with their placeholders,
+// the parameters stay out of the log.
+type redactedDialector struct {
+	*mysql.Dialector
+}
+
+func (d redactedDialector) Explain(sql string, vars ...interface{}) string {
+	return sql
+}
@@ -0,0 +1,129 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coolstina/connecter"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newLoggerTestDB(t *testing.T, level logger.LogLevel, ops ...Option) *gorm.DB {
+	fake.reset()
+	db, err := NewConnection(&Config{
+		Database:           "connecter",
+		LogLevel:           int(level),
+		DriverName:         connecter.DriverName(fakeDriverName),
+		SkipCreateDatabase: true,
+	}, ops...)
+	assert.NoError(t, err)
+	return db
+}
+
+func TestNewLogger_Handler(t *testing.T) {
+	var entries []LogEntry
+	handler := func(ctx context.Context, entry LogEntry) {
+		entries = append(entries, entry)
+	}
+
+	db := newLoggerTestDB(t, logger.Info, WithLogHandler(handler))
+	entries = nil
+	assert.NoError(t, db.Exec("UPDATE users SET password = ? WHERE id = ?", "secret", 1).Error)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, logger.Info, entries[0].Level)
+	assert.Contains(t, entries[0].SQL, "secret")
+	assert.NotEmpty(t, entries[0].Caller)
+
+	db = newLoggerTestDB(t, logger.Info, WithLogHandler(handler), WithRedactParams(true))
+	entries = nil
+	assert.NoError(t, db.Exec("UPDATE users SET password = ? WHERE id = ?", "secret", 1).Error)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "UPDATE users SET password = ? WHERE id = ?", entries[0].SQL)
+
+	db = newLoggerTestDB(t, logger.Warn, WithLogHandler(handler))
+	entries = nil
+	assert.NoError(t, db.Exec("UPDATE users SET password = ? WHERE id = ?", "secret", 1).Error)
+	assert.Empty(t, entries)
+}
+
+func TestNewLogger_Writer(t *testing.T) {
+	var buf bytes.Buffer
+	db := newLoggerTestDB(t, logger.Info, WithLogWriter(&buf), WithColorful(false))
+	assert.NoError(t, db.Exec("DELETE FROM users WHERE id = ?", 7).Error)
+	assert.Contains(t, buf.String(), "DELETE FROM users WHERE id = 7")
+	assert.NotContains(t, buf.String(), "\033[")
+
+	buf.Reset()
+	db = newLoggerTestDB(t, logger.Silent, WithLogWriter(&buf))
+	assert.NoError(t, db.Exec("DELETE FROM users WHERE id = ?", 7).Error)
+	assert.Empty(t, buf.String())
+}
+
+func TestNewLogger_Config(t *testing.T) {
+	assert.Equal(t, logger.Discard, newLogger(&Config{Logger: logger.Discard}, resolve(WithLogWriter(&bytes.Buffer{}))))
+
+	l := newLogger(&Config{}, resolve(WithLogHandler(func(context.Context, LogEntry) {})))
+	assert.Equal(t, logger.Warn, l.(*handlerLogger).level)
+	assert.Equal(t, 200*time.Millisecond, l.(*handlerLogger).slowThreshold)
+}
+
+func TestHandlerLogger_Trace(t *testing.T) {
+	var entries []LogEntry
+	l := newLogger(&Config{LogLevel: int(logger.Warn)}, resolve(
+		WithSlowThreshold(100*time.Millisecond),
+		WithIgnoreRecordNotFound(true),
+		WithLogHandler(func(ctx context.Context, entry LogEntry) {
+			entries = append(entries, entry)
+		}),
+	))
+
+	sql := func() (string, int64) { return "SELECT * FROM users", -1 }
+	ctx := context.Background()
+
+	l.Trace(ctx, time.Now(), sql, nil)
+	l.Trace(ctx, time.Now(), sql, gorm.ErrRecordNotFound)
+	assert.Empty(t, entries)
+
+	l.Trace(ctx, time.Now(), sql, gorm.ErrInvalidData)
+	l.Trace(ctx, time.Now().Add(-time.Second), sql, nil)
+	assert.Len(t, entries, 2)
+	assert.Equal(t, logger.Error, entries[0].Level)
+	assert.Equal(t, gorm.ErrInvalidData, entries[0].Err)
+	assert.Equal(t, int64(-1), entries[0].Rows)
+	assert.Equal(t, logger.Warn, entries[1].Level)
+	assert.True(t, entries[1].Slow)
+	assert.Equal(t, "SELECT * FROM users", entries[1].SQL)
+
+	entries = nil
+	l.LogMode(logger.Info).Info(ctx, "hello %s", "world")
+	l.Info(ctx, "dropped")
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "hello world", entries[0].Message)
+	assert.Contains(t, entries[0].Caller, "mysql_logger_test.go:")
+
+	entries = nil
+	l.Error(ctx, "failed")
+	l.Trace(ctx, time.Now(), sql, gorm.ErrInvalidData)
+	assert.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.Contains(t, entry.Caller, "mysql_logger_test.go:")
+	}
+}
@@ -0,0 +1,449 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrMigrationChanged is returned when an applied migration file
+	// was edited afterwards.
+	ErrMigrationChanged = errors.New("mysql: applied migration has changed")
+	// ErrMigrationMissing is returned when an applied migration has
+	// no file anymore.
+	ErrMigrationMissing = errors.New("mysql: applied migration is missing")
+	// ErrMigrationDirty is returned when a migration failed halfway,
+	// the database has to be fixed and the record removed by hand.
+	ErrMigrationDirty = errors.New("mysql: migration is dirty")
+	// ErrNoDownMigration is returned when rolling back a migration
+	// without down file.
+	ErrNoDownMigration = errors.New("mysql: migration has no down file")
+	// ErrMigrationsLocked is returned when another process kept the
+	// migrations lock past the lock timeout.
+	ErrMigrationsLocked = errors.New("mysql: migrations are locked")
+)
+
+// migrationFilePattern matches migration file names, such as
+// 20211201093000_create_users.up.sql.
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Direction of a migration step.
+type Direction string
+
+func (d Direction) String() string {
+	return string(d)
+}
+
+const (
+	DirectionOfUp   Direction = "up"
+	DirectionOfDown Direction = "down"
+)
+
+// Migration defines a version read from its up and down files.
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string
+	Down    string
+	// Checksum is the SHA-256 of the up file, combined with the one of
+	// the down file when present, recorded when applied.
+	Checksum string
+}
+
+// Step defines a migration run, or planned in dry-run, in a direction.
+type Step struct {
+	Version    uint64
+	Name       string
+	Direction  Direction
+	Statements []string
+	Elapsed    time.Duration
+}
+
+// appliedMigration defines a row of the migrations table.
+type appliedMigration struct {
+	version  uint64
+	checksum string
+	dirty    bool
+}
+
+// LoadMigrations reads the migrations of dir ordered by version, other
+// files than <version>_<name>.up.sql and <version>_<name>.down.sql are
+// ignored.
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make(map[uint64]*Migration)
+	ups := make(map[uint64]bool)
+	for _, entry := range entries {
+		matches := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("mysql: migration %s: %w", entry.Name(), err)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := migrations[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: matches[2]}
+			migrations[version] = migration
+		}
+
+		if migration.Name != matches[2] {
+			return nil, fmt.Errorf("mysql: migration %d is named both %s and %s", version, migration.Name, matches[2])
+		}
+
+		if matches[3] == DirectionOfUp.String() {
+			migration.Up = string(data)
+			ups[version] = true
+		} else {
+			migration.Down = string(data)
+		}
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if !ups[migration.Version] {
+			return nil, fmt.Errorf("mysql: migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migration.Checksum = migrationChecksum(migration.Up, migration.Down)
+		result = append(result, *migration)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// migrationChecksum get the checksum of the migration files, the SHA-256
+// of the up file alone, or of the up and down file checksums when the
+// down file is present.
+func migrationChecksum(up, down string) string {
+	sum := sha256.Sum256([]byte(up))
+	if down == "" {
+		return hex.EncodeToString(sum[:])
+	}
+
+	downSum := sha256.Sum256([]byte(down))
+	sum = sha256.Sum256(append(sum[:], downSum[:]...))
+	return hex.EncodeToString(sum[:])
+}
+
+// Migrator applies the versioned migrations of a file system, such as
+// an embed.FS, and records them in the migrations table. Migrations
+// run on a single connection holding a GET_LOCK advisory lock, so only
+// one process migrates a database at a time.
+type Migrator struct {
+	db      *gorm.DB
+	fsys    fs.FS
+	options *migrateOptions
+}
+
+// NewMigrator create a new migrator with the given options.
+func NewMigrator(db *gorm.DB, fsys fs.FS, ops ...MigrateOption) *Migrator {
+	options := &migrateOptions{
+		dir:         ".",
+		table:       "schema_migrations",
+		lockTimeout: time.Minute,
+	}
+
+	for _, o := range ops {
+		o.apply(options)
+	}
+
+	return &Migrator{db: db, fsys: fsys, options: options}
+}
+
+// Up applies all the pending migrations.
+func (m *Migrator) Up(ctx context.Context) ([]Step, error) {
+	return m.migrate(ctx, true, func(migrations []Migration, applied []appliedMigration) uint64 {
+		if len(migrations) == 0 {
+			return 0
+		}
+		return migrations[len(migrations)-1].Version
+	})
+}
+
+// Down rolls back the latest applied migration.
+func (m *Migrator) Down(ctx context.Context) ([]Step, error) {
+	return m.migrate(ctx, false, func(migrations []Migration, applied []appliedMigration) uint64 {
+		if len(applied) < 2 {
+			return 0
+		}
+		return applied[len(applied)-2].version
+	})
+}
+
+// To applies the pending migrations up to version and rolls back the
+// applied ones after it.
+func (m *Migrator) To(ctx context.Context, version uint64) ([]Step, error) {
+	return m.migrate(ctx, true, func([]Migration, []appliedMigration) uint64 {
+		return version
+	})
+}
+
+func (m *Migrator) migrate(ctx context.Context, up bool, target func([]Migration, []appliedMigration) uint64) ([]Step, error) {
+	table, err := QuoteIdentifier(m.options.table)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations, err := LoadMigrations(m.fsys, m.options.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// The lock belongs to its connection, the whole run stays on it. A
+	// lost connection fails the run itself, it needs no checks.
+	lock, err := Lock(ctx, m.db, m.options.table, m.options.lockTimeout, WithLockCheckInterval(0))
+	if errors.Is(err, ErrLockNotAcquired) {
+		return nil, ErrMigrationsLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Release()
+
+	conn := lock.Conn()
+
+	if !m.options.dryRun {
+		if _, err := conn.ExecContext(ctx, createMigrationsTable(table)); err != nil {
+			return nil, err
+		}
+	}
+
+	applied, err := m.applied(ctx, conn, table)
+	if err != nil {
+		return nil, err
+	}
+
+	steps, err := plan(migrations, applied, target(migrations, applied), up)
+	if err != nil || m.options.dryRun {
+		return steps, err
+	}
+
+	for i := range steps {
+		start := time.Now()
+		if err := run(ctx, conn, table, migrations, steps[i]); err != nil {
+			return steps[:i], fmt.Errorf("mysql: migration %d_%s %s: %w", steps[i].Version, steps[i].Name, steps[i].Direction, err)
+		}
+		steps[i].Elapsed = time.Since(start)
+	}
+
+	return steps, nil
+}
+
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn, table string) ([]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum, dirty FROM "+table+" ORDER BY version")
+	if err != nil {
+		// A dry run does not create the table, nothing is applied yet.
+		if m.options.dryRun && hasErrorCode(err, ErrorCodeOfNoSuchTable) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer rows.Close()
+
+	var applied []appliedMigration
+	for rows.Next() {
+		var a appliedMigration
+		if err := rows.Scan(&a.version, &a.checksum, &a.dirty); err != nil {
+			return nil, err
+		}
+		applied = append(applied, a)
+	}
+
+	return applied, rows.Err()
+}
+
+func createMigrationsTable(table string) string {
+	return "CREATE TABLE IF NOT EXISTS " + table + " (" +
+		"version BIGINT UNSIGNED NOT NULL PRIMARY KEY, " +
+		"name VARCHAR(255) NOT NULL, " +
+		"checksum CHAR(64) NOT NULL, " +
+		"dirty TINYINT(1) NOT NULL DEFAULT 0, " +
+		"applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+}
+
+// plan verifies the applied migrations against the files and returns
+// the steps reaching target, rollbacks from the latest version first.
+// The pending migrations are skipped unless up.
+func plan(migrations []Migration, applied []appliedMigration, target uint64, up bool) ([]Step, error) {
+	byVersion := make(map[uint64]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	done := make(map[uint64]bool, len(applied))
+	for _, a := range applied {
+		migration, ok := byVersion[a.version]
+		switch {
+		case a.dirty:
+			return nil, fmt.Errorf("%w: version %d", ErrMigrationDirty, a.version)
+		case !ok:
+			return nil, fmt.Errorf("%w: version %d", ErrMigrationMissing, a.version)
+		case migration.Checksum != a.checksum:
+			return nil, fmt.Errorf("%w: %d_%s", ErrMigrationChanged, migration.Version, migration.Name)
+		}
+		done[a.version] = true
+	}
+
+	var steps []Step
+	for i := len(applied) - 1; i >= 0; i-- {
+		migration := byVersion[applied[i].version]
+		if migration.Version <= target {
+			continue
+		}
+		if strings.TrimSpace(migration.Down) == "" {
+			return nil, fmt.Errorf("%w: %d_%s", ErrNoDownMigration, migration.Version, migration.Name)
+		}
+		steps = append(steps, Step{
+			Version:    migration.Version,
+			Name:       migration.Name,
+			Direction:  DirectionOfDown,
+			Statements: splitStatements(migration.Down),
+		})
+	}
+
+	for _, migration := range migrations {
+		if !up || migration.Version > target || done[migration.Version] {
+			continue
+		}
+		steps = append(steps, Step{
+			Version:    migration.Version,
+			Name:       migration.Name,
+			Direction:  DirectionOfUp,
+			Statements: splitStatements(migration.Up),
+		})
+	}
+
+	return steps, nil
+}
+
+// run executes a step, the record stays dirty if a statement fails
+// since MySQL commits DDL statements implicitly.
+func run(ctx context.Context, conn *sql.Conn, table string, migrations []Migration, step Step) error {
+	var checksum string
+	for _, migration := range migrations {
+		if migration.Version == step.Version {
+			checksum = migration.Checksum
+		}
+	}
+
+	var err error
+	if step.Direction == DirectionOfUp {
+		_, err = conn.ExecContext(ctx, "INSERT INTO "+table+" (version, name, checksum, dirty) VALUES (?, ?, ?, 1)",
+			step.Version, step.Name, checksum)
+	} else {
+		_, err = conn.ExecContext(ctx, "UPDATE "+table+" SET dirty = 1 WHERE version = ?", step.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, s := range step.Statements {
+		if _, err := conn.ExecContext(ctx, s); err != nil {
+			return err
+		}
+	}
+
+	if step.Direction == DirectionOfUp {
+		_, err = conn.ExecContext(ctx, "UPDATE "+table+" SET dirty = 0 WHERE version = ?", step.Version)
+	} else {
+		_, err = conn.ExecContext(ctx, "DELETE FROM "+table+" WHERE version = ?", step.Version)
+	}
+
+	return err
+}
+
+// splitStatements splits a migration file on the semicolons outside of
+// quotes and comments, DELIMITER is not supported.
+func splitStatements(s string) []string {
+	var (
+		statements []string
+		start      int
+		quote      byte
+		code       bool
+	)
+
+	flush := func(end int) {
+		if code {
+			statements = append(statements, strings.TrimSpace(s[start:end]))
+		}
+		start, code = end+1, false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote, code = c, true
+		case c == '#', strings.HasPrefix(s[i:], "-- "), strings.HasPrefix(s[i:], "--\n"):
+			if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(s)
+			}
+		case strings.HasPrefix(s[i:], "/*"):
+			// Executable comments, such as /*!80016 ... */, are code.
+			code = code || strings.HasPrefix(s[i:], "/*!")
+			if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(s)
+			}
+		case c == ';':
+			flush(i)
+		case c != ' ' && c != '\t' && c != '\n' && c != '\r':
+			code = true
+		}
+	}
+	flush(len(s))
+
+	return statements
+}
@@ -0,0 +1,68 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"time"
+)
+
+type MigrateOption interface {
+	apply(*migrateOptions)
+}
+
+type migrateOptionFunc func(ops *migrateOptions)
+
+func (o migrateOptionFunc) apply(ops *migrateOptions) {
+	o(ops)
+}
+
+type migrateOptions struct {
+	dir         string
+	table       string
+	lockTimeout time.Duration
+	dryRun      bool
+}
+
+// WithMigrationsDir Specifies the directory of the migration files
+// within the file system. Default is the root.
+func WithMigrationsDir(dir string) MigrateOption {
+	return migrateOptionFunc(func(ops *migrateOptions) {
+		ops.dir = dir
+	})
+}
+
+// WithMigrationsTable Specifies the table recording the applied
+// migrations. Default is schema_migrations.
+func WithMigrationsTable(table string) MigrateOption {
+	return migrateOptionFunc(func(ops *migrateOptions) {
+		ops.table = table
+	})
+}
+
+// WithMigrationsLockTimeout Specifies how long to wait for another
+// process to finish migrating. Default is 1 minute.
+func WithMigrationsLockTimeout(timeout time.Duration) MigrateOption {
+	return migrateOptionFunc(func(ops *migrateOptions) {
+		ops.lockTimeout = timeout
+	})
+}
+
+// WithDryRun Specifies whether the migrations are only planned,
+// the steps are returned without being executed or recorded.
+func WithDryRun(enabled bool) MigrateOption {
+	return migrateOptionFunc(func(ops *migrateOptions) {
+		ops.dryRun = enabled
+	})
+}
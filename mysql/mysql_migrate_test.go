@@ -0,0 +1,261 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+var migrationsFS = fstest.MapFS{
+	"migrations/1_create_users.up.sql":     {Data: []byte("CREATE TABLE users (id BIGINT PRIMARY KEY);\nCREATE INDEX idx_id ON users (id);\n")},
+	"migrations/1_create_users.down.sql":   {Data: []byte("DROP TABLE users;")},
+	"migrations/2_add_name.up.sql":         {Data: []byte("ALTER TABLE users ADD COLUMN name VARCHAR(64);")},
+	"migrations/2_add_name.down.sql":       {Data: []byte("ALTER TABLE users DROP COLUMN name;")},
+	"migrations/10_rename_name.up.sql":     {Data: []byte("ALTER TABLE users RENAME COLUMN name TO full_name;")},
+	"migrations/README.md":                 {Data: []byte("ignored")},
+	"migrations/drafts/3_draft.up.sql":     {Data: []byte("ignored")},
+	"broken/1_create_users.down.sql":       {Data: []byte("DROP TABLE users;")},
+	"conflict/1_create_users.up.sql":       {Data: []byte("SELECT 1;")},
+	"conflict/1_create_accounts.down.sql":  {Data: []byte("SELECT 1;")},
+	"migrations_empty/.keep":               {Data: []byte{}},
+	"migrations_empty/notes/1_x.up.sql.bk": {Data: []byte{}},
+}
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations(migrationsFS, "migrations")
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 3)
+
+	var versions []uint64
+	for _, migration := range migrations {
+		versions = append(versions, migration.Version)
+	}
+	assert.Equal(t, []uint64{1, 2, 10}, versions)
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.Equal(t, "DROP TABLE users;", migrations[0].Down)
+	assert.Len(t, migrations[0].Checksum, 64)
+	assert.Empty(t, migrations[2].Down)
+
+	// Editing the down file changes the checksum.
+	assert.Equal(t, migrationChecksum(migrations[0].Up, migrations[0].Down), migrations[0].Checksum)
+	assert.NotEqual(t, migrationChecksum(migrations[0].Up, "DROP TABLE IF EXISTS users;"), migrations[0].Checksum)
+	assert.NotEqual(t, migrationChecksum(migrations[0].Up, ""), migrations[0].Checksum)
+
+	migrations, err = LoadMigrations(migrationsFS, "migrations_empty")
+	assert.NoError(t, err)
+	assert.Empty(t, migrations)
+
+	_, err = LoadMigrations(migrationsFS, "broken")
+	assert.Error(t, err)
+
+	_, err = LoadMigrations(migrationsFS, "conflict")
+	assert.Error(t, err)
+}
+
+func TestSplitStatements(t *testing.T) {
+	grid := []struct {
+		sql      string
+		expected []string
+	}{
+		{sql: "", expected: nil},
+		{sql: "SELECT 1", expected: []string{"SELECT 1"}},
+		{sql: "SELECT 1;\n\nSELECT 2;\n", expected: []string{"SELECT 1", "SELECT 2"}},
+		{sql: "INSERT INTO t VALUES ('a;b', \"c;d\", `e;f`);", expected: []string{"INSERT INTO t VALUES ('a;b', \"c;d\", `e;f`)"}},
+		{sql: `INSERT INTO t VALUES ('it\'s;');`, expected: []string{`INSERT INTO t VALUES ('it\'s;')`}},
+		{sql: "-- create; table\nCREATE TABLE t (id INT); # trailing; comment\n/* block; */", expected: []string{"-- create; table\nCREATE TABLE t (id INT)"}},
+		{sql: "/*!80016 SET x = 1 */;", expected: []string{"/*!80016 SET x = 1 */"}},
+		{sql: "SELECT 1 --1;", expected: []string{"SELECT 1 --1"}},
+	}
+
+	for _, g := range grid {
+		assert.Equal(t, g.expected, splitStatements(g.sql), g.sql)
+	}
+}
+
+// migrationsHandler answers the lock and applied migrations queries of
+// the migrator from the given rows, such as {1, checksum, 0}.
+func migrationsHandler(lock int64, applied [][]driver.Value, fail string) fakeHandler {
+	return func(query string, args []interface{}) (driver.Rows, error) {
+		switch {
+		case strings.HasPrefix(query, "SELECT GET_LOCK"):
+			return &fakeRows{columns: []string{"lock"}, values: [][]driver.Value{{lock}}}, nil
+		case strings.HasPrefix(query, "SELECT version, checksum, dirty"):
+			if applied == nil {
+				return nil, &mysqldriver.MySQLError{Number: 1146, Message: "Table doesn't exist"}
+			}
+			return &fakeRows{columns: []string{"version", "checksum", "dirty"}, values: applied}, nil
+		case fail != "" && query == fail:
+			return nil, errors.New("fake: statement failed")
+		}
+		return nil, nil
+	}
+}
+
+func checksumOf(t *testing.T, version uint64) string {
+	migrations, err := LoadMigrations(migrationsFS, "migrations")
+	assert.NoError(t, err)
+	for _, migration := range migrations {
+		if migration.Version == version {
+			return migration.Checksum
+		}
+	}
+	return ""
+}
+
+func TestMigrator_Up(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(migrationsHandler(1, [][]driver.Value{{int64(1), checksumOf(t, 1), int64(0)}}, ""))
+
+	steps, err := NewMigrator(db, migrationsFS, WithMigrationsDir("migrations")).Up(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, steps, 2)
+	assert.Equal(t, uint64(2), steps[0].Version)
+	assert.Equal(t, DirectionOfUp, steps[0].Direction)
+	assert.Equal(t, uint64(10), steps[1].Version)
+
+	queries := queriesOf(fake.recorded())
+	assert.Equal(t, []string{
+		"SELECT DATABASE(), CONNECTION_ID()",
+		"SELECT GET_LOCK(?, ?)",
+		createMigrationsTable("`schema_migrations`"),
+		"SELECT version, checksum, dirty FROM `schema_migrations` ORDER BY version",
+		"INSERT INTO `schema_migrations` (version, name, checksum, dirty) VALUES (?, ?, ?, 1)",
+		"ALTER TABLE users ADD COLUMN name VARCHAR(64)",
+		"UPDATE `schema_migrations` SET dirty = 0 WHERE version = ?",
+		"INSERT INTO `schema_migrations` (version, name, checksum, dirty) VALUES (?, ?, ?, 1)",
+		"ALTER TABLE users RENAME COLUMN name TO full_name",
+		"UPDATE `schema_migrations` SET dirty = 0 WHERE version = ?",
+		"SELECT RELEASE_LOCK(?)",
+	}, queries)
+
+	calls := fake.recorded()
+	assert.Equal(t, []interface{}{"connecter.schema_migrations", int64(60)}, calls[1].Args)
+	assert.Equal(t, []interface{}{int64(2), "add_name", checksumOf(t, 2)}, calls[4].Args)
+}
+
+func TestMigrator_To(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(migrationsHandler(1, [][]driver.Value{
+		{int64(1), checksumOf(t, 1), int64(0)},
+		{int64(2), checksumOf(t, 2), int64(0)},
+	}, ""))
+
+	steps, err := NewMigrator(db, migrationsFS, WithMigrationsDir("migrations")).To(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Len(t, steps, 1)
+	assert.Equal(t, DirectionOfDown, steps[0].Direction)
+	assert.Equal(t, []string{"ALTER TABLE users DROP COLUMN name"}, steps[0].Statements)
+
+	queries := queriesOf(fake.recorded())
+	assert.Equal(t, []string{
+		"UPDATE `schema_migrations` SET dirty = 1 WHERE version = ?",
+		"ALTER TABLE users DROP COLUMN name",
+		"DELETE FROM `schema_migrations` WHERE version = ?",
+	}, queries[4:7])
+}
+
+func TestMigrator_Down(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(migrationsHandler(1, [][]driver.Value{{int64(1), checksumOf(t, 1), int64(0)}}, ""))
+
+	steps, err := NewMigrator(db, migrationsFS, WithMigrationsDir("migrations")).Down(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, steps, 1)
+	assert.Equal(t, uint64(1), steps[0].Version)
+	assert.Equal(t, DirectionOfDown, steps[0].Direction)
+}
+
+func TestMigrator_DryRun(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(migrationsHandler(1, nil, ""))
+
+	steps, err := NewMigrator(db, migrationsFS,
+		WithMigrationsDir("migrations"),
+		WithDryRun(true),
+	).To(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Len(t, steps, 2)
+	assert.Equal(t, []string{
+		"CREATE TABLE users (id BIGINT PRIMARY KEY)",
+		"CREATE INDEX idx_id ON users (id)",
+	}, steps[0].Statements)
+
+	assert.Equal(t, []string{
+		"SELECT DATABASE(), CONNECTION_ID()",
+		"SELECT GET_LOCK(?, ?)",
+		"SELECT version, checksum, dirty FROM `schema_migrations` ORDER BY version",
+		"SELECT RELEASE_LOCK(?)",
+	}, queriesOf(fake.recorded()))
+}
+
+func TestMigrator_Errors(t *testing.T) {
+	db := newFakeDB(t)
+	grid := []struct {
+		name    string
+		lock    int64
+		applied [][]driver.Value
+		target  uint64
+		err     error
+	}{
+		{name: "locked", lock: 0, err: ErrMigrationsLocked},
+		{name: "changed", lock: 1, applied: [][]driver.Value{{int64(1), "edited", int64(0)}}, target: 10, err: ErrMigrationChanged},
+		{name: "missing", lock: 1, applied: [][]driver.Value{{int64(5), "gone", int64(0)}}, target: 10, err: ErrMigrationMissing},
+		{name: "dirty", lock: 1, applied: [][]driver.Value{{int64(1), checksumOf(t, 1), int64(1)}}, target: 10, err: ErrMigrationDirty},
+		{name: "no down", lock: 1, applied: [][]driver.Value{
+			{int64(1), checksumOf(t, 1), int64(0)},
+			{int64(2), checksumOf(t, 2), int64(0)},
+			{int64(10), checksumOf(t, 10), int64(0)},
+		}, target: 2, err: ErrNoDownMigration},
+	}
+
+	for _, g := range grid {
+		fake.reset()
+		fake.handle(migrationsHandler(g.lock, g.applied, ""))
+
+		steps, err := NewMigrator(db, migrationsFS, WithMigrationsDir("migrations")).To(context.Background(), g.target)
+		assert.ErrorIs(t, err, g.err, g.name)
+		assert.Empty(t, steps, g.name)
+
+		for _, query := range queriesOf(fake.recorded()) {
+			assert.False(t, strings.HasPrefix(query, "ALTER") || strings.HasPrefix(query, "INSERT"), g.name)
+		}
+	}
+}
+
+func TestMigrator_Failure(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(migrationsHandler(1, [][]driver.Value{}, "CREATE INDEX idx_id ON users (id)"))
+
+	steps, err := NewMigrator(db, migrationsFS, WithMigrationsDir("migrations")).Up(context.Background())
+	assert.Error(t, err)
+	assert.Empty(t, steps)
+
+	queries := queriesOf(fake.recorded())
+	assert.Equal(t, "CREATE INDEX idx_id ON users (id)", queries[len(queries)-2])
+	assert.Equal(t, "SELECT RELEASE_LOCK(?)", queries[len(queries)-1])
+}
@@ -14,6 +14,15 @@
 
 package mysql
 
+import (
+	"io"
+	"time"
+
+	"github.com/coolstina/connecter"
+	"github.com/coolstina/connecter/audit"
+	"github.com/coolstina/connecter/ratelimit"
+)
+
 type Option interface {
 	apply(*options)
 }
@@ -25,9 +34,60 @@ func (o optionFunc) apply(ops *options) {
 }
 
 type options struct {
-	charset   string
-	parseTime bool
-	location  string
+	charset              string
+	collation            string
+	parseTime            bool
+	location             string
+	timeout              time.Duration
+	readTimeout          time.Duration
+	writeTimeout         time.Duration
+	tls                  string
+	interpolateParams    bool
+	multiStatements      bool
+	maxAllowedPacket     *int
+	allowNativePasswords *bool
+	socket               string
+	params               map[string]string
+	encryption           *bool
+	applicationUser      *ApplicationUser
+	slowThreshold        time.Duration
+	colorful             bool
+	ignoreRecordNotFound bool
+	redactParams         bool
+	logWriter            io.Writer
+	logHandler           LogHandler
+	sessionVariables     []sessionVariable
+	initStatements       []string
+	auditor              *audit.Auditor
+	limiter              *ratelimit.Limiter
+	warmUp               *warmUp
+	capture              *Capture
+	explain              *explainOptions
+}
+
+type warmUp struct {
+	connections int
+	timeout     time.Duration
+	report      func(report connecter.WarmUpReport)
+}
+
+// resolve applies the given options over the defaults.
+func resolve(ops ...Option) *options {
+	opts := &options{
+		charset:   "utf8mb4",
+		parseTime: true,
+		location:  "Local",
+		params:    make(map[string]string),
+
+		slowThreshold: 200 * time.Millisecond,
+		colorful:      true,
+	}
+
+	for _, o := range ops {
+		o.apply(opts)
+	}
+
+	return opts
 }
 
 func WithCharset(charset string) Option {
@@ -47,3 +107,227 @@ func WithLocation(loc string) Option {
 		ops.location = loc
 	})
 }
+
+// WithCollation Specifies the connection collation, such as
+// utf8mb4_unicode_ci. Default is the driver's utf8mb4_general_ci.
+func WithCollation(collation string) Option {
+	return optionFunc(func(ops *options) {
+		ops.collation = collation
+	})
+}
+
+// WithTimeout Specifies the timeout for establishing connections.
+func WithTimeout(timeout time.Duration) Option {
+	return optionFunc(func(ops *options) {
+		ops.timeout = timeout
+	})
+}
+
+// WithReadTimeout Specifies the I/O read timeout.
+func WithReadTimeout(timeout time.Duration) Option {
+	return optionFunc(func(ops *options) {
+		ops.readTimeout = timeout
+	})
+}
+
+// WithWriteTimeout Specifies the I/O write timeout.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return optionFunc(func(ops *options) {
+		ops.writeTimeout = timeout
+	})
+}
+
+// WithTLS Specifies the TLS mode, either true, false, skip-verify,
+// preferred or the name of a config registered with the driver's
+// RegisterTLSConfig.
+func WithTLS(name string) Option {
+	return optionFunc(func(ops *options) {
+		ops.tls = name
+	})
+}
+
+// WithInterpolateParams Specifies whether placeholders are
+// interpolated into the query string instead of prepared.
+func WithInterpolateParams(enabled bool) Option {
+	return optionFunc(func(ops *options) {
+		ops.interpolateParams = enabled
+	})
+}
+
+// WithMultiStatements Specifies whether multiple statements
+// are allowed in one query.
+func WithMultiStatements(enabled bool) Option {
+	return optionFunc(func(ops *options) {
+		ops.multiStatements = enabled
+	})
+}
+
+// WithMaxAllowedPacket Specifies the max packet size allowed in bytes,
+// 0 fetches it from the server. Default is 4 MiB.
+func WithMaxAllowedPacket(size int) Option {
+	return optionFunc(func(ops *options) {
+		ops.maxAllowedPacket = &size
+	})
+}
+
+// WithAllowNativePasswords Specifies whether the native password
+// authentication method is allowed. Default is true.
+func WithAllowNativePasswords(allowed bool) Option {
+	return optionFunc(func(ops *options) {
+		ops.allowNativePasswords = &allowed
+	})
+}
+
+// WithUnixSocket Specifies the unix socket path to connect through,
+// the host is ignored.
+func WithUnixSocket(path string) Option {
+	return optionFunc(func(ops *options) {
+		ops.socket = path
+	})
+}
+
+// WithParam Specifies a connection parameter, parameters unknown to
+// the driver are set as session system variables on connect, such as
+// WithParam("time_zone", "'+00:00'").
+func WithParam(name, value string) Option {
+	return optionFunc(func(ops *options) {
+		ops.params[name] = value
+	})
+}
+
+// WithEncryption Specifies the DEFAULT ENCRYPTION of the database
+// created on connect, requires MySQL 8.0.16 or later.
+func WithEncryption(enabled bool) Option {
+	return optionFunc(func(ops *options) {
+		ops.encryption = &enabled
+	})
+}
+
+// WithApplicationUser Specifies an account to create along with the
+// database, the connection itself keeps using the config credentials.
+func WithApplicationUser(user ApplicationUser) Option {
+	return optionFunc(func(ops *options) {
+		ops.applicationUser = &user
+	})
+}
+
+// WithSlowThreshold Specifies the elapsed time above which queries are
+// logged as slow at warn level, 0 disables it. Default is 200ms.
+func WithSlowThreshold(threshold time.Duration) Option {
+	return optionFunc(func(ops *options) {
+		ops.slowThreshold = threshold
+	})
+}
+
+// WithColorful Specifies whether the log is colored. Default is true.
+func WithColorful(colorful bool) Option {
+	return optionFunc(func(ops *options) {
+		ops.colorful = colorful
+	})
+}
+
+// WithIgnoreRecordNotFound Specifies whether gorm.ErrRecordNotFound
+// errors are left out of the log.
+func WithIgnoreRecordNotFound(ignore bool) Option {
+	return optionFunc(func(ops *options) {
+		ops.ignoreRecordNotFound = ignore
+	})
+}
+
+// WithRedactParams Specifies whether statements are logged with their
+// placeholders instead of the interpolated parameters.
+func WithRedactParams(redact bool) Option {
+	return optionFunc(func(ops *options) {
+		ops.redactParams = redact
+	})
+}
+
+// WithLogWriter Specifies the writer of the log. Default is os.Stdout.
+func WithLogWriter(w io.Writer) Option {
+	return optionFunc(func(ops *options) {
+		ops.logWriter = w
+	})
+}
+
+// WithLogHandler Specifies the handler receiving the log as entries
+// instead of a writer, such as for a structured logger.
+func WithLogHandler(handler LogHandler) Option {
+	return optionFunc(func(ops *options) {
+		ops.logHandler = handler
+	})
+}
+
+// WithSessionVariable Specifies a session variable set on every new
+// connection, such as WithSessionVariable("time_zone", "+00:00"). The
+// value is sent as a string literal if it is a string, as is otherwise.
+func WithSessionVariable(name string, value interface{}) Option {
+	return optionFunc(func(ops *options) {
+		ops.sessionVariables = append(ops.sessionVariables, sessionVariable{name: name, value: value})
+	})
+}
+
+// WithInitStatement Specifies a statement run on every new connection,
+// after the session variables.
+func WithInitStatement(statement string) Option {
+	return optionFunc(func(ops *options) {
+		ops.initStatements = append(ops.initStatements, statement)
+	})
+}
+
+// WithAudit Specifies the auditor that records create, update
+// and delete operations, along with the writes run by Exec, see
+// RegisterAudit.
+func WithAudit(auditor *audit.Auditor) Option {
+	return optionFunc(func(ops *options) {
+		ops.auditor = auditor
+	})
+}
+
+// WithRateLimit Specifies the limiter that throttles statements
+// by operation class, see RegisterRateLimit.
+func WithRateLimit(limiter *ratelimit.Limiter) Option {
+	return optionFunc(func(ops *options) {
+		ops.limiter = limiter
+	})
+}
+
+// WithWarmUp Specifies the number of connections NewConnection opens
+// in parallel before returning, bounded by the timeout. The report
+// tells how many succeeded, MaxIdleConnections must be at least
+// connections for them to stay in the pool.
+func WithWarmUp(connections int, timeout time.Duration, report func(report connecter.WarmUpReport)) Option {
+	return optionFunc(func(ops *options) {
+		ops.warmUp = &warmUp{connections: connections, timeout: timeout, report: report}
+	})
+}
+
+// WithCapture Specifies the capture recording the statements of the
+// connection. Unless the capture executes them, the connection is a
+// dry run that never touches the database, see NewCapture.
+func WithCapture(capture *Capture) Option {
+	return optionFunc(func(ops *options) {
+		ops.capture = capture
+	})
+}
+
+// WithExplain Specifies that slow SELECT statements are explained with
+// EXPLAIN FORMAT=JSON on a separate read only connection, sampled and
+// rate limited by the explain options. The statements are explained
+// with their placeholders and parameters as run. The plan comes with
+// the slow statement's LogEntry, or follows it in the log of the
+// writer. Nothing is explained with WithRedactParams, the plans show
+// the parameters. The explain connection is closed with Close.
+//
+// The statements are explained on Config.Host, including the ones run
+// by a replica of a ReplicaSet, so their plans may differ from the
+// replica's. WithExplain fails NewConnection with ErrExplainLogger when
+// Config.Logger is set, the plans need the logger of the connection.
+func WithExplain(ops ...ExplainOption) Option {
+	return optionFunc(func(opts *options) {
+		explain := &explainOptions{sample: 1, perSecond: 1, burst: 1, timeout: 5 * time.Second}
+		for _, o := range ops {
+			o.apply(explain)
+		}
+		opts.explain = explain
+	})
+}
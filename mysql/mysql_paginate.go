@@ -0,0 +1,281 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCursor is returned for cursors not made by Paginate for the
+// same sort columns.
+var ErrInvalidCursor = errors.New("mysql: invalid cursor")
+
+// Order defines a sort column of a page. The columns must not be NULL
+// and the last one unique, such as the primary key.
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// Asc sorts by column in ascending order.
+func Asc(column string) Order {
+	return Order{Column: column}
+}
+
+// Desc sorts by column in descending order.
+func Desc(column string) Order {
+	return Order{Column: column, Desc: true}
+}
+
+// Page defines the cursors of the pages around the one found, empty
+// when there is no such page.
+type Page struct {
+	Next string
+	Prev string
+}
+
+// HasNext tells if there is a next page.
+func (p *Page) HasNext() bool {
+	return p.Next != ""
+}
+
+// HasPrev tells if there is a previous page.
+func (p *Page) HasPrev() bool {
+	return p.Prev != ""
+}
+
+type cursor struct {
+	Columns []string      `json:"c"`
+	Values  []cursorValue `json:"v"`
+	Prev    bool          `json:"p,omitempty"`
+}
+
+// cursorValue keeps times apart as JSON would turn them into strings.
+type cursorValue struct {
+	Time  *time.Time  `json:"t,omitempty"`
+	Value interface{} `json:"v,omitempty"`
+}
+
+func (v cursorValue) value() interface{} {
+	if v.Time != nil {
+		return *v.Time
+	}
+	if n, ok := v.Value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i
+		}
+		f, _ := n.Float64()
+		return f
+	}
+	return v.Value
+}
+
+// Paginate finds into dest, a pointer to a slice of models, up to limit
+// rows of query after the cursor, or the first ones when it is empty.
+// The query must not be ordered already.
+// The rows are sorted by the orders and selected by keyset, such as
+// WHERE (a, b) > (?, ?), so no rows are skipped with an offset.
+func Paginate(query *gorm.DB, dest interface{}, cursorOf string, limit int, orders ...Order) (*Page, error) {
+	if len(orders) == 0 {
+		return nil, errors.New("mysql: paginate needs at least one order")
+	}
+	if limit < 1 {
+		return nil, fmt.Errorf("mysql: invalid page limit %d", limit)
+	}
+
+	columns := make([]string, len(orders))
+	for i, order := range orders {
+		columns[i] = order.Column
+	}
+
+	var c *cursor
+	if cursorOf != "" {
+		var err error
+		if c, err = decodeCursor(cursorOf, columns); err != nil {
+			return nil, err
+		}
+	}
+
+	prev := c != nil && c.Prev
+
+	tx := query.Session(&gorm.Session{})
+	if c != nil {
+		values := make([]interface{}, len(c.Values))
+		for i, v := range c.Values {
+			values[i] = v.value()
+		}
+		tx = tx.Where(keysetCondition(orders, values, prev))
+	}
+
+	by := clause.OrderBy{}
+	for _, order := range orders {
+		by.Columns = append(by.Columns, clause.OrderByColumn{
+			Column: clause.Column{Name: order.Column},
+			Desc:   order.Desc != prev,
+		})
+	}
+
+	result := tx.Clauses(by).Limit(limit + 1).Find(dest)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	rows := reflect.Indirect(reflect.ValueOf(dest))
+	if rows.Kind() != reflect.Slice {
+		return nil, ErrNotSlice
+	}
+
+	more := rows.Len() > limit
+	if more {
+		rows.Set(rows.Slice(0, limit))
+	}
+	if prev {
+		reverse(rows)
+	}
+
+	page := &Page{}
+	if rows.Len() == 0 {
+		return page, nil
+	}
+
+	var err error
+	if more || prev {
+		if page.Next, err = encodeCursor(result, rows.Index(rows.Len()-1), columns, false); err != nil {
+			return nil, err
+		}
+	}
+	if (more && prev) || (c != nil && !prev) {
+		if page.Prev, err = encodeCursor(result, rows.Index(0), columns, true); err != nil {
+			return nil, err
+		}
+	}
+
+	return page, nil
+}
+
+// keysetCondition selects the rows after values in the order, or
+// before them for the previous page. Orders of the same direction
+// compare as a row, mixed ones expand to (a > ?) OR (a = ? AND b < ?).
+func keysetCondition(orders []Order, values []interface{}, prev bool) clause.Expr {
+	operator := func(order Order) string {
+		if order.Desc != prev {
+			return "<"
+		}
+		return ">"
+	}
+
+	mixed := false
+	for _, order := range orders[1:] {
+		if order.Desc != orders[0].Desc {
+			mixed = true
+		}
+	}
+
+	if !mixed {
+		expr := clause.Expr{}
+		placeholders := make([]string, len(orders))
+		for i, order := range orders {
+			placeholders[i] = "?"
+			expr.Vars = append(expr.Vars, clause.Column{Name: order.Column})
+		}
+		expr.Vars = append(expr.Vars, values...)
+		expr.SQL = fmt.Sprintf("(%s) %s (%s)", strings.Join(placeholders, ", "), operator(orders[0]), strings.Join(placeholders, ", "))
+		return expr
+	}
+
+	expr := clause.Expr{}
+	terms := make([]string, len(orders))
+	for i, order := range orders {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, "? = ?")
+			expr.Vars = append(expr.Vars, clause.Column{Name: orders[j].Column}, values[j])
+		}
+		parts = append(parts, "? "+operator(order)+" ?")
+		expr.Vars = append(expr.Vars, clause.Column{Name: order.Column}, values[i])
+		terms[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+	expr.SQL = "(" + strings.Join(terms, " OR ") + ")"
+	return expr
+}
+
+func encodeCursor(result *gorm.DB, row reflect.Value, columns []string, prev bool) (string, error) {
+	row = reflect.Indirect(row)
+	s := result.Statement.Schema
+
+	c := cursor{Columns: columns, Prev: prev}
+	for _, column := range columns {
+		field := s.LookUpField(column)
+		if field == nil {
+			return "", fmt.Errorf("mysql: paginate column %q is not a field of %s", column, s.Name)
+		}
+
+		value, _ := field.ValueOf(row)
+		if t, ok := value.(time.Time); ok {
+			c.Values = append(c.Values, cursorValue{Time: &t})
+			continue
+		}
+		c.Values = append(c.Values, cursorValue{Value: value})
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(s string, columns []string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	c := &cursor{}
+	if err := decoder.Decode(c); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+
+	if len(c.Columns) != len(columns) || len(c.Values) != len(columns) {
+		return nil, ErrInvalidCursor
+	}
+	for i := range columns {
+		if c.Columns[i] != columns[i] {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return c, nil
+}
+
+func reverse(rows reflect.Value) {
+	swap := reflect.Swapper(rows.Interface())
+	for i, j := 0, rows.Len()-1; i < j; i, j = i+1, j-1 {
+		swap(i, j)
+	}
+}
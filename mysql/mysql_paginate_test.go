@@ -0,0 +1,268 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/coolstina/connecter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	_ "modernc.org/sqlite"
+)
+
+type article struct {
+	ID        uint64
+	Score     int
+	CreatedAt time.Time
+}
+
+// newArticlesDB creates the articles table of an in-memory SQLite database
+// and stores the articles with the given scores, ids start from 1.
+func newArticlesDB(t *testing.T, scores ...int) *gorm.DB {
+	db, err := gorm.Open(sqlite.Dialector{
+		DriverName: connecter.DriverNameOfSQLite.String(),
+		DSN:        "file::memory:",
+	}, &gorm.Config{Logger: logger.Discard})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Every connection of an in-memory database sees its own database.
+	pool, err := db.DB()
+	assert.NoError(t, err)
+	pool.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = pool.Close() })
+
+	assert.NoError(t, db.AutoMigrate(&article{}))
+
+	created := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	articles := make([]article, 0, len(scores))
+	for i, score := range scores {
+		articles = append(articles, article{ID: uint64(i + 1), Score: score, CreatedAt: created})
+	}
+	assert.NoError(t, db.Create(&articles).Error)
+
+	return db.Model(&article{}).Session(&gorm.Session{})
+}
+
+func TestPaginateSuite(t *testing.T) {
+	suite.Run(t, new(PaginateSuite))
+}
+
+type PaginateSuite struct {
+	suite.Suite
+	db *gorm.DB
+}
+
+func (s *PaginateSuite) SetupTest() {
+	s.db = newArticlesDB(s.T(), 10, 30, 20, 20, 10)
+}
+
+func (s *PaginateSuite) page(cursor string) ([]uint64, *Page) {
+	var articles []article
+	page, err := Paginate(s.db, &articles, cursor, 2, Desc("score"), Asc("id"))
+	s.NoError(err)
+
+	ids := make([]uint64, 0, len(articles))
+	for _, a := range articles {
+		ids = append(ids, a.ID)
+	}
+	return ids, page
+}
+
+func (s *PaginateSuite) Test_Forward() {
+	ids, first := s.page("")
+	s.Equal([]uint64{2, 3}, ids)
+	s.False(first.HasPrev())
+	s.True(first.HasNext())
+
+	ids, second := s.page(first.Next)
+	s.Equal([]uint64{4, 1}, ids)
+	s.True(second.HasPrev())
+	s.True(second.HasNext())
+
+	ids, last := s.page(second.Next)
+	s.Equal([]uint64{5}, ids)
+	s.True(last.HasPrev())
+	s.False(last.HasNext())
+
+	ids, back := s.page(last.Prev)
+	s.Equal([]uint64{4, 1}, ids)
+	s.True(back.HasPrev())
+	s.True(back.HasNext())
+
+	ids, start := s.page(back.Prev)
+	s.Equal([]uint64{2, 3}, ids)
+	s.False(start.HasPrev())
+	s.True(start.HasNext())
+}
+
+func (s *PaginateSuite) Test_Query() {
+	_, first := s.page("")
+
+	db := newFakeDB(s.T()).WithContext(context.Background())
+	fake.reset()
+
+	var articles []article
+	_, err := Paginate(db.Model(&article{}), &articles, "", 2, Desc("score"), Asc("id"))
+	s.NoError(err)
+	_, err = Paginate(db.Model(&article{}), &articles, first.Next, 2, Desc("score"), Asc("id"))
+	s.NoError(err)
+
+	calls := fake.recorded()
+	s.Equal([]string{
+		"SELECT * FROM `articles` ORDER BY `score` DESC,`id` LIMIT 3",
+		"SELECT * FROM `articles` WHERE ((`score` < ?) OR (`score` = ? AND `id` > ?)) ORDER BY `score` DESC,`id` LIMIT 3",
+	}, queriesOf(calls))
+	s.Equal([]interface{}{int64(20), int64(20), int64(3)}, calls[1].Args[:3])
+}
+
+func (s *PaginateSuite) Test_InvalidCursor() {
+	var articles []article
+	_, err := Paginate(s.db, &articles, "not a cursor", 2, Desc("score"), Asc("id"))
+	s.ErrorIs(err, ErrInvalidCursor)
+
+	_, first := s.page("")
+	_, err = Paginate(s.db, &articles, first.Next, 2, Asc("id"))
+	s.ErrorIs(err, ErrInvalidCursor)
+}
+
+func TestPaginate_Walk(t *testing.T) {
+	scores := []int{10, 30, 20, 20, 10, 30, 20, 10}
+	db := newArticlesDB(t, scores...)
+
+	articles := make([]article, 0, len(scores))
+	for i, score := range scores {
+		articles = append(articles, article{ID: uint64(i + 1), Score: score})
+	}
+
+	grid := []struct {
+		name   string
+		orders []Order
+		less   func(a, b article) bool
+	}{
+		{
+			name:   "score desc, id asc",
+			orders: []Order{Desc("score"), Asc("id")},
+			less: func(a, b article) bool {
+				return a.Score > b.Score || (a.Score == b.Score && a.ID < b.ID)
+			},
+		},
+		{
+			name:   "score asc, id desc",
+			orders: []Order{Asc("score"), Desc("id")},
+			less: func(a, b article) bool {
+				return a.Score < b.Score || (a.Score == b.Score && a.ID > b.ID)
+			},
+		},
+		{
+			name:   "score desc, id desc",
+			orders: []Order{Desc("score"), Desc("id")},
+			less: func(a, b article) bool {
+				return a.Score > b.Score || (a.Score == b.Score && a.ID > b.ID)
+			},
+		},
+	}
+
+	for _, g := range grid {
+		t.Run(g.name, func(t *testing.T) {
+			expected := append([]article{}, articles...)
+			sort.Slice(expected, func(i, j int) bool { return g.less(expected[i], expected[j]) })
+
+			page := func(cursor string) ([]uint64, *Page) {
+				var rows []article
+				p, err := Paginate(db, &rows, cursor, 3, g.orders...)
+				assert.NoError(t, err)
+
+				ids := make([]uint64, 0, len(rows))
+				for _, a := range rows {
+					ids = append(ids, a.ID)
+				}
+				return ids, p
+			}
+
+			var pages [][]uint64
+			var last *Page
+			for cursor := ""; ; cursor = last.Next {
+				var ids []uint64
+				ids, last = page(cursor)
+				assert.Equal(t, cursor != "", last.HasPrev())
+				pages = append(pages, ids)
+				if !last.HasNext() {
+					break
+				}
+			}
+
+			var walked []uint64
+			for _, ids := range pages {
+				walked = append(walked, ids...)
+			}
+			ids := make([]uint64, 0, len(expected))
+			for _, a := range expected {
+				ids = append(ids, a.ID)
+			}
+			assert.Equal(t, ids, walked)
+			assert.Len(t, pages, 3)
+
+			// Going back returns the same pages.
+			back := last
+			for i := len(pages) - 2; i >= 0; i-- {
+				var ids []uint64
+				ids, back = page(back.Prev)
+				assert.Equal(t, pages[i], ids)
+				assert.True(t, back.HasNext())
+				assert.Equal(t, i > 0, back.HasPrev())
+			}
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	db := newDryRunDB(t)
+
+	tx := db.Where(keysetCondition([]Order{Asc("created_at"), Asc("id")}, []interface{}{"2021-12-01", 7}, false)).Find(&[]article{})
+	assert.Equal(t, "SELECT * FROM `articles` WHERE (`created_at`, `id`) > (?, ?)", tx.Statement.SQL.String())
+	assert.Equal(t, []interface{}{"2021-12-01", 7}, tx.Statement.Vars)
+
+	tx = db.Where(keysetCondition([]Order{Desc("created_at"), Desc("id")}, []interface{}{"2021-12-01", 7}, true)).Find(&[]article{})
+	assert.Equal(t, "SELECT * FROM `articles` WHERE (`created_at`, `id`) > (?, ?)", tx.Statement.SQL.String())
+}
+
+func TestCursor(t *testing.T) {
+	result := newDryRunDB(t).Find(&[]article{})
+	created := time.Date(2021, 12, 1, 9, 30, 0, 0, time.UTC)
+	columns := []string{"created_at", "score", "id"}
+
+	s, err := encodeCursor(result, reflect.ValueOf(article{ID: 7, Score: 20, CreatedAt: created}), columns, true)
+	assert.NoError(t, err)
+
+	c, err := decodeCursor(s, columns)
+	assert.NoError(t, err)
+	assert.True(t, c.Prev)
+	assert.Equal(t, created, c.Values[0].value())
+	assert.Equal(t, int64(20), c.Values[1].value())
+	assert.Equal(t, int64(7), c.Values[2].value())
+
+	_, err = decodeCursor(s, []string{"created_at", "id"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
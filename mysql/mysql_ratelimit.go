@@ -0,0 +1,88 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/coolstina/connecter/ratelimit"
+	"gorm.io/gorm"
+)
+
+// readStatements defines the statement prefixes classified as reads.
+var readStatements = []string{"SELECT", "SHOW", "EXPLAIN", "DESCRIBE", "DESC", "WITH"}
+
+// RegisterRateLimit registers gorm callbacks that take a token from
+// the limiter before every statement, writes wait before their
+// transaction begins so no connection is held while throttled. Queries are reads, creates of
+// more than one row are bulk, other statements are writes; raw
+// statements are classified by their leading keyword.
+func RegisterRateLimit(db *gorm.DB, limiter *ratelimit.Limiter) error {
+	callbacks := []struct {
+		name     string
+		register func(name string, fn func(*gorm.DB)) error
+		classify func(db *gorm.DB) ratelimit.Class
+	}{
+		{"gorm:query", db.Callback().Query().Before("gorm:query").Register, classOf(ratelimit.ClassOfRead)},
+		{"gorm:row", db.Callback().Row().Before("gorm:row").Register, classOf(ratelimit.ClassOfRead)},
+		{"gorm:create", db.Callback().Create().Before("gorm:begin_transaction").Register, createClass},
+		{"gorm:update", db.Callback().Update().Before("gorm:begin_transaction").Register, classOf(ratelimit.ClassOfWrite)},
+		{"gorm:delete", db.Callback().Delete().Before("gorm:begin_transaction").Register, classOf(ratelimit.ClassOfWrite)},
+		{"gorm:raw", db.Callback().Raw().Before("gorm:raw").Register, rawClass},
+	}
+
+	for _, callback := range callbacks {
+		classify := callback.classify
+		name := "connecter:ratelimit_" + strings.TrimPrefix(callback.name, "gorm:")
+		err := callback.register(name, func(db *gorm.DB) {
+			if db.Error != nil || db.DryRun {
+				return
+			}
+			if err := limiter.Take(db.Statement.Context, classify(db)); err != nil {
+				_ = db.AddError(err)
+			}
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func classOf(class ratelimit.Class) func(db *gorm.DB) ratelimit.Class {
+	return func(db *gorm.DB) ratelimit.Class {
+		return class
+	}
+}
+
+func createClass(db *gorm.DB) ratelimit.Class {
+	value := db.Statement.ReflectValue
+	if (value.Kind() == reflect.Slice || value.Kind() == reflect.Array) && value.Len() > 1 {
+		return ratelimit.ClassOfBulk
+	}
+	return ratelimit.ClassOfWrite
+}
+
+func rawClass(db *gorm.DB) ratelimit.Class {
+	statement := strings.ToUpper(strings.TrimLeft(db.Statement.SQL.String(), " \t\r\n("))
+	for _, prefix := range readStatements {
+		if strings.HasPrefix(statement, prefix) {
+			return ratelimit.ClassOfRead
+		}
+	}
+	return ratelimit.ClassOfWrite
+}
@@ -0,0 +1,88 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/coolstina/connecter"
+	"github.com/coolstina/connecter/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterRateLimit(t *testing.T) {
+	fake.reset()
+	limiter := ratelimit.New(
+		ratelimit.WithDefaultRate(0.001, 1),
+		ratelimit.WithMode(ratelimit.ModeOfFailFast),
+	)
+	db, err := NewConnection(&Config{
+		Database:           "connecter",
+		DriverName:         connecter.DriverName(fakeDriverName),
+		SkipCreateDatabase: true,
+	}, WithRateLimit(limiter))
+	assert.NoError(t, err)
+
+	fake.reset()
+	assert.NoError(t, db.Find(&[]user{}).Error)
+	assert.Equal(t, ratelimit.ErrLimited, db.Find(&[]user{}).Error)
+
+	assert.NoError(t, db.Create(&user{Name: "helloshaohua"}).Error)
+	assert.Equal(t, ratelimit.ErrLimited, db.Exec("UPDATE users SET name = ?", "coolstina").Error)
+
+	assert.NoError(t, db.Create(&[]user{{Name: "a"}, {Name: "b"}}).Error)
+	assert.Equal(t, ratelimit.ErrLimited, db.Create(&[]user{{Name: "c"}, {Name: "d"}}).Error)
+
+	// Rejected statements never reach the database.
+	var selects, inserts int
+	for _, query := range queriesOf(fake.recorded()) {
+		switch {
+		case strings.HasPrefix(query, "SELECT"):
+			selects++
+		case strings.HasPrefix(query, "INSERT"):
+			inserts++
+		case strings.HasPrefix(query, "UPDATE"):
+			t.Errorf("unexpected %s", query)
+		}
+	}
+	assert.Equal(t, 1, selects)
+	assert.Equal(t, 2, inserts)
+
+	stats := limiter.Stats()
+	assert.Equal(t, uint64(1), stats[ratelimit.ClassOfRead].Rejected)
+	assert.Equal(t, uint64(1), stats[ratelimit.ClassOfWrite].Rejected)
+	assert.Equal(t, uint64(1), stats[ratelimit.ClassOfBulk].Rejected)
+}
+
+func TestRawClass(t *testing.T) {
+	grids := []struct {
+		statement string
+		expected  ratelimit.Class
+	}{
+		{statement: "SELECT * FROM users", expected: ratelimit.ClassOfRead},
+		{statement: "  (select 1)", expected: ratelimit.ClassOfRead},
+		{statement: "SHOW TABLES", expected: ratelimit.ClassOfRead},
+		{statement: "WITH t AS (SELECT 1) SELECT * FROM t", expected: ratelimit.ClassOfRead},
+		{statement: "UPDATE users SET name = 'a'", expected: ratelimit.ClassOfWrite},
+		{statement: "DELETE FROM users", expected: ratelimit.ClassOfWrite},
+	}
+
+	for _, grid := range grids {
+		db := newFakeDB(t)
+		db.Statement.SQL.WriteString(grid.statement)
+		assert.Equal(t, grid.expected, rawClass(db), grid.statement)
+	}
+}
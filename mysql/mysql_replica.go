@@ -0,0 +1,500 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	replicaSetName = "connecter:replicas"
+	// replicaKey keys the replica picked for a statement in its settings.
+	replicaKey = "connecter:replica"
+)
+
+type forcePrimaryKey struct{}
+
+// ForcePrimary returns a copy of ctx whose reads go to the primary,
+// such as reads that must see a write made just before.
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+func isForcePrimary(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	force, _ := ctx.Value(forcePrimaryKey{}).(bool)
+	return force
+}
+
+// PinnedPool is implemented by connection pools bound to a single
+// transaction, such as the ones of mysqltest.NewDB, their reads are
+// never routed to replicas.
+type PinnedPool interface {
+	gorm.ConnPool
+	Pinned()
+}
+
+// ReplicaStatus defines the health of a replica.
+type ReplicaStatus struct {
+	Host    string
+	Weight  int
+	Healthy bool
+	Lag     time.Duration
+	Err     error
+}
+
+type replica struct {
+	host   string
+	weight int
+	db     *sql.DB
+	status ReplicaStatus
+	// inflight counts the statements routed to the replica and not
+	// finished yet, a retired replica is closed once it drops to 0.
+	inflight int
+	retired  bool
+}
+
+// ReplicaSet is a gorm plugin that sends the reads made outside of
+// transactions to healthy replicas, picked at random by weight. Reads
+// go to the primary when no replica is healthy, when they lock rows or
+// when their context comes from ForcePrimary.
+type ReplicaSet struct {
+	mu       sync.RWMutex
+	config   *Config
+	ops      []Option
+	replicas []*replica
+	// retired holds the replicas removed while statements still use them.
+	retired []*replica
+	closed  bool
+	random  *rand.Rand
+	stop    chan struct{}
+	once    sync.Once
+	// update serializes the endpoint updates.
+	update sync.Mutex
+}
+
+// NewReplicaSet initialize replica set instance for the replicas of config.
+func NewReplicaSet(config *Config, ops ...Option) *ReplicaSet {
+	return &ReplicaSet{
+		config: config,
+		ops:    ops,
+		random: rand.New(rand.NewSource(time.Now().UnixNano())),
+		stop:   make(chan struct{}),
+	}
+}
+
+// Replicas get the replica set registered on db by NewConnection.
+func Replicas(db *gorm.DB) (*ReplicaSet, bool) {
+	plugin, ok := db.Config.Plugins[replicaSetName]
+	if !ok {
+		return nil, false
+	}
+	set, ok := plugin.(*ReplicaSet)
+	return set, ok
+}
+
+// Name implements gorm.Plugin.
+func (s *ReplicaSet) Name() string {
+	return replicaSetName
+}
+
+// Initialize implements gorm.Plugin, it opens the replicas, checks
+// their health once and keeps checking it in the background.
+func (s *ReplicaSet) Initialize(db *gorm.DB) error {
+	s.mu.Lock()
+	for _, r := range s.config.Replicas {
+		replica, err := s.open(r)
+		if err != nil {
+			s.mu.Unlock()
+			_ = s.Close()
+			return err
+		}
+		s.replicas = append(s.replicas, replica)
+	}
+	s.mu.Unlock()
+
+	if err := s.register(db); err != nil {
+		_ = s.Close()
+		return err
+	}
+
+	s.check()
+	go s.watch()
+
+	return nil
+}
+
+// register registers the callbacks routing reads to the replicas and
+// releasing them once the reads are done.
+func (s *ReplicaSet) register(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register("connecter:replica_query", s.route); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("connecter:replica_row", s.route); err != nil {
+		return err
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("connecter:replica_query_release", s.release); err != nil {
+		return err
+	}
+	return db.Callback().Row().After("gorm:row").Register("connecter:replica_row_release", s.release)
+}
+
+// errReplicaSetClosed is returned when updating a closed replica set.
+var errReplicaSetClosed = errors.New("mysql: replica set is closed")
+
+// UpdateEndpoints implements discovery.Updater, it replaces the
+// replicas with the given hosts keeping the weight of known ones.
+// Removed replicas are closed once the statements using them finish.
+func (s *ReplicaSet) UpdateEndpoints(hosts []string) error {
+	s.update.Lock()
+	defer s.update.Unlock()
+
+	s.mu.RLock()
+	known := make(map[string]bool, len(s.replicas))
+	for _, r := range s.replicas {
+		known[r.host] = true
+	}
+	s.mu.RUnlock()
+
+	// Opened outside of the lock, reads keep being routed meanwhile.
+	opened := make(map[string]*replica)
+	for _, host := range hosts {
+		if known[host] || opened[host] != nil {
+			continue
+		}
+
+		r, err := s.open(Replica{Host: host, Weight: 1})
+		if err != nil {
+			for _, r := range opened {
+				_ = r.db.Close()
+			}
+			return err
+		}
+		opened[host] = r
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		for _, r := range opened {
+			_ = r.db.Close()
+		}
+		return errReplicaSetClosed
+	}
+
+	current := make(map[string]*replica, len(s.replicas))
+	for _, r := range s.replicas {
+		current[r.host] = r
+	}
+
+	replicas := make([]*replica, 0, len(hosts))
+	for _, host := range hosts {
+		if r, ok := current[host]; ok {
+			replicas = append(replicas, r)
+			delete(current, host)
+			continue
+		}
+		if r, ok := opened[host]; ok {
+			replicas = append(replicas, r)
+			delete(opened, host)
+		}
+	}
+	s.replicas = replicas
+
+	var closing []*replica
+	for _, r := range current {
+		r.retired = true
+		if r.inflight == 0 {
+			closing = append(closing, r)
+			continue
+		}
+		s.retired = append(s.retired, r)
+	}
+	s.mu.Unlock()
+
+	for _, r := range closing {
+		_ = r.db.Close()
+	}
+
+	s.check()
+	return nil
+}
+
+// Status returns the health of every replica.
+func (s *ReplicaSet) Status() []ReplicaStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	statuses := make([]ReplicaStatus, 0, len(s.replicas))
+	for _, r := range s.replicas {
+		statuses = append(statuses, r.status)
+	}
+	return statuses
+}
+
+// Close stops the health checks and closes the replica connections.
+func (s *ReplicaSet) Close() error {
+	s.once.Do(func() { close(s.stop) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var first error
+	for _, r := range append(s.replicas, s.retired...) {
+		if err := r.db.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	s.replicas, s.retired, s.closed = nil, nil, true
+	return first
+}
+
+func (s *ReplicaSet) open(r Replica) (*replica, error) {
+	dsn := NewDataSourceName(r.Host, s.config.Username, s.config.Password, s.config.Database, s.ops...)
+	db, err := openDB(driverNameOf(s.config), dsn, resolve(s.ops...))
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(s.config.MaxOpenConnections)
+	db.SetConnMaxLifetime(s.config.MaxConnectionLifeTime)
+	db.SetMaxIdleConns(s.config.MaxIdleConnections)
+
+	weight := r.Weight
+	if weight < 1 {
+		weight = 1
+	}
+
+	return &replica{
+		host:   r.Host,
+		weight: weight,
+		db:     db,
+		status: ReplicaStatus{Host: r.Host, Weight: weight},
+	}, nil
+}
+
+// route switches the connection of reads to a replica.
+func (s *ReplicaSet) route(db *gorm.DB) {
+	if db.Error != nil || isForcePrimary(db.Statement.Context) {
+		return
+	}
+
+	// Reads inside a transaction must see its writes.
+	switch db.Statement.ConnPool.(type) {
+	case gorm.TxCommitter, PinnedPool:
+		return
+	}
+
+	if _, ok := db.Statement.Clauses["FOR"]; ok {
+		return
+	}
+
+	statement := strings.ToUpper(db.Statement.SQL.String())
+	if strings.Contains(statement, "FOR UPDATE") || strings.Contains(statement, "LOCK IN SHARE MODE") {
+		return
+	}
+
+	if r := s.acquire(); r != nil {
+		db.Statement.ConnPool = r.db
+		db.Statement.Settings.Store(replicaKey, r)
+	}
+}
+
+// release ends the use of the replica picked by route, closing it
+// when it was retired meanwhile.
+func (s *ReplicaSet) release(db *gorm.DB) {
+	value, ok := db.Statement.Settings.Load(replicaKey)
+	if !ok {
+		return
+	}
+	db.Statement.Settings.Delete(replicaKey)
+	r := value.(*replica)
+
+	s.mu.Lock()
+	r.inflight--
+	closing := r.retired && r.inflight == 0
+	if closing {
+		for i, retired := range s.retired {
+			if retired == r {
+				s.retired = append(s.retired[:i], s.retired[i+1:]...)
+				break
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	if closing {
+		_ = r.db.Close()
+	}
+}
+
+// acquire picks a replica and counts the statement using it.
+func (s *ReplicaSet) acquire() *replica {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.pickLocked()
+	if r != nil {
+		r.inflight++
+	}
+	return r
+}
+
+func (s *ReplicaSet) pick() *replica {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.pickLocked()
+}
+
+// pickLocked picks a healthy replica by weight, the set mutex must be
+// held.
+func (s *ReplicaSet) pickLocked() *replica {
+	total := 0
+	for _, r := range s.replicas {
+		if r.status.Healthy {
+			total += r.weight
+		}
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	n := s.random.Intn(total)
+	for _, r := range s.replicas {
+		if !r.status.Healthy {
+			continue
+		}
+		if n < r.weight {
+			return r
+		}
+		n -= r.weight
+	}
+
+	return nil
+}
+
+func (s *ReplicaSet) watch() {
+	interval := s.config.ReplicaCheckInterval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.check()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// check pings every replica and, when a maximum lag is configured,
+// evicts the ones lagging further behind or not replicating.
+func (s *ReplicaSet) check() {
+	s.mu.RLock()
+	replicas := append([]*replica{}, s.replicas...)
+	s.mu.RUnlock()
+
+	for _, r := range replicas {
+		status := ReplicaStatus{Host: r.host, Weight: r.weight, Healthy: true}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		status.Err = r.db.PingContext(ctx)
+		if status.Err == nil && s.config.MaxReplicationLag > 0 {
+			status.Lag, status.Err = replicationLag(ctx, r.db)
+			if status.Err == nil && status.Lag > s.config.MaxReplicationLag {
+				status.Healthy = false
+			}
+		}
+		cancel()
+
+		if status.Err != nil {
+			status.Healthy = false
+		}
+
+		s.mu.Lock()
+		r.status = status
+		s.mu.Unlock()
+	}
+}
+
+// errNotReplicating is reported for replicas whose replication is stopped.
+var errNotReplicating = errors.New("replica is not replicating")
+
+// replicationLag reads the lag of the replica from its status,
+// supporting both the replica and the former slave statements and
+// column names. SHOW SLAVE STATUS is only sent to servers rejecting
+// SHOW REPLICA STATUS as a syntax error, it's deprecated since 8.0.22.
+func replicationLag(ctx context.Context, db *sql.DB) (time.Duration, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if hasErrorCode(err, ErrorCodeOfParse) {
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	if !rows.Next() {
+		return 0, errNotReplicating
+	}
+
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := rows.Scan(dest...); err != nil {
+		return 0, err
+	}
+
+	for i, column := range columns {
+		if column != "Seconds_Behind_Master" && column != "Seconds_Behind_Source" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, errNotReplicating
+		}
+		seconds, err := strconv.Atoi(string(values[i]))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	return 0, errNotReplicating
+}
@@ -0,0 +1,185 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type user struct {
+	ID   uint64
+	Name string
+}
+
+// pinnedPool is a connection pool bound to a transaction.
+type pinnedPool struct {
+	gorm.ConnPool
+}
+
+func (pinnedPool) Pinned() {}
+
+func newTestReplicaSet(t *testing.T, replicas ...Replica) *ReplicaSet {
+	config := *def
+	config.Replicas = replicas
+
+	set := NewReplicaSet(&config)
+	for _, r := range replicas {
+		replica, err := set.open(r)
+		assert.NoError(t, err)
+		replica.status.Healthy = true
+		set.replicas = append(set.replicas, replica)
+	}
+	return set
+}
+
+func TestReplicaSet_Route(t *testing.T) {
+	db := newDryRunDB(t)
+	set := newTestReplicaSet(t, Replica{Host: "127.0.0.1:3307", Weight: 1})
+	defer set.Close()
+
+	err := db.Callback().Query().Before("gorm:query").Register("connecter:replica_query", set.route)
+	assert.NoError(t, err)
+
+	replica := set.replicas[0].db
+	ctx := context.Background()
+
+	tx := db.WithContext(ctx).Find(&[]user{})
+	assert.Equal(t, replica, tx.Statement.ConnPool)
+
+	tx = db.WithContext(ForcePrimary(ctx)).Find(&[]user{})
+	assert.Equal(t, db.ConnPool, tx.Statement.ConnPool)
+
+	tx = db.WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).Find(&[]user{})
+	assert.Equal(t, db.ConnPool, tx.Statement.ConnPool)
+
+	// Reads inside a transaction stay on it.
+	transaction := &sql.Tx{}
+	tx = db.WithContext(ctx)
+	tx.Statement.ConnPool = transaction
+	tx = tx.Find(&[]user{})
+	assert.Equal(t, transaction, tx.Statement.ConnPool)
+
+	pool := pinnedPool{ConnPool: transaction}
+	tx = db.WithContext(ctx)
+	tx.Statement.ConnPool = pool
+	tx = tx.Find(&[]user{})
+	assert.Equal(t, pool, tx.Statement.ConnPool)
+
+	set.replicas[0].status.Healthy = false
+	tx = db.WithContext(ctx).Find(&[]user{})
+	assert.Equal(t, db.ConnPool, tx.Statement.ConnPool)
+}
+
+func TestReplicaSet_Pick(t *testing.T) {
+	set := newTestReplicaSet(t,
+		Replica{Host: "127.0.0.1:3307", Weight: 3},
+		Replica{Host: "127.0.0.1:3308", Weight: 1},
+		Replica{Host: "127.0.0.1:3309", Weight: 10},
+	)
+	defer set.Close()
+
+	set.replicas[2].status.Healthy = false
+
+	picked := make(map[string]int)
+	for i := 0; i < 4000; i++ {
+		picked[set.pick().host]++
+	}
+
+	assert.Zero(t, picked["127.0.0.1:3309"])
+	assert.InDelta(t, 3000, picked["127.0.0.1:3307"], 200)
+	assert.InDelta(t, 1000, picked["127.0.0.1:3308"], 200)
+}
+
+func TestReplicationLag(t *testing.T) {
+	fake.reset()
+	defer fake.reset()
+
+	status := func(column string, lag driver.Value) driver.Rows {
+		return &fakeRows{columns: []string{"Replica_IO_State", column}, values: [][]driver.Value{{"Waiting", lag}}}
+	}
+
+	db, err := sql.Open(fakeDriverName, "replica")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// Servers before 8.0.22 only know the slave statement.
+	fake.handle(func(query string, args []interface{}) (driver.Rows, error) {
+		if query == "SHOW REPLICA STATUS" {
+			return nil, &mysqldriver.MySQLError{Number: ErrorCodeOfParse}
+		}
+		return status("Seconds_Behind_Master", "3"), nil
+	})
+	lag, err := replicationLag(context.Background(), db)
+	assert.NoError(t, err)
+	assert.Equal(t, 3*time.Second, lag)
+	assert.Equal(t, []string{"SHOW REPLICA STATUS", "SHOW SLAVE STATUS"}, queriesOf(fake.recorded()))
+
+	fake.reset()
+	fake.handle(func(query string, args []interface{}) (driver.Rows, error) {
+		return status("Seconds_Behind_Source", nil), nil
+	})
+	_, err = replicationLag(context.Background(), db)
+	assert.Equal(t, errNotReplicating, err)
+	assert.Equal(t, []string{"SHOW REPLICA STATUS"}, queriesOf(fake.recorded()))
+
+	// Other errors are reported as they are.
+	denied := &mysqldriver.MySQLError{Number: 1227}
+	fake.handle(func(query string, args []interface{}) (driver.Rows, error) {
+		return nil, denied
+	})
+	_, err = replicationLag(context.Background(), db)
+	assert.Equal(t, denied, err)
+}
+
+func TestReplicaSet_UpdateEndpoints(t *testing.T) {
+	fake.reset()
+	defer fake.reset()
+
+	config := *def
+	config.DriverName = fakeDriverName
+	config.Replicas = []Replica{{Host: "127.0.0.1:3307", Weight: 2}}
+
+	db := newDryRunDB(t)
+	set := NewReplicaSet(&config)
+	assert.NoError(t, set.Initialize(db))
+	defer set.Close()
+
+	// A statement routed to the replica holds it across the update.
+	removed := set.replicas[0]
+	tx := db.Session(&gorm.Session{NewDB: true})
+	set.route(tx)
+	assert.Equal(t, removed.db, tx.Statement.ConnPool)
+
+	assert.NoError(t, set.UpdateEndpoints([]string{"127.0.0.1:3308", "127.0.0.1:3308"}))
+	assert.Len(t, set.replicas, 1)
+	assert.Equal(t, []*replica{removed}, set.retired)
+	assert.NoError(t, removed.db.Ping())
+
+	set.release(tx)
+	assert.Empty(t, set.retired)
+	assert.Error(t, removed.db.Ping())
+
+	assert.NoError(t, set.Close())
+	assert.Equal(t, errReplicaSetClosed, set.UpdateEndpoints([]string{"127.0.0.1:3309"}))
+}
@@ -0,0 +1,609 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Schema defines the tables of a database.
+type Schema struct {
+	Tables []Table
+}
+
+// Table returns the table of the given name.
+func (s *Schema) Table(name string) (*Table, bool) {
+	for i := range s.Tables {
+		if s.Tables[i].Name == name {
+			return &s.Tables[i], true
+		}
+	}
+	return nil, false
+}
+
+// Table defines a table and its columns, indexes and foreign keys.
+type Table struct {
+	Name        string
+	Engine      string
+	Collation   string
+	Comment     string
+	Columns     []Column
+	Indexes     []Index
+	ForeignKeys []ForeignKey
+}
+
+// Column returns the column of the given name.
+func (t *Table) Column(name string) (*Column, bool) {
+	for i := range t.Columns {
+		if t.Columns[i].Name == name {
+			return &t.Columns[i], true
+		}
+	}
+	return nil, false
+}
+
+// Index returns the index of the given name.
+func (t *Table) Index(name string) (*Index, bool) {
+	for i := range t.Indexes {
+		if t.Indexes[i].Name == name {
+			return &t.Indexes[i], true
+		}
+	}
+	return nil, false
+}
+
+// Column defines a column, Type is the full type such as
+// varchar(191) or bigint unsigned.
+type Column struct {
+	Name     string
+	Type     string
+	Nullable bool
+	Default  *string
+	// Extra holds attributes such as auto_increment.
+	Extra     string
+	Collation string
+	Comment   string
+}
+
+// Index defines an index, the primary key is named PRIMARY. Functional
+// key parts, indexing an expression instead of a column, are left out
+// of the columns.
+type Index struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+// ForeignKey defines a foreign key constraint.
+type ForeignKey struct {
+	Name              string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+}
+
+// InspectSchema reads the tables of the current database from
+// information_schema.
+func InspectSchema(ctx context.Context, db *gorm.DB) (*Schema, error) {
+	db = db.WithContext(ctx)
+	s := &Schema{}
+	tables := make(map[string]*Table)
+
+	err := scanRows(db, "SELECT TABLE_NAME, COALESCE(ENGINE, ''), COALESCE(TABLE_COLLATION, ''), TABLE_COMMENT "+
+		"FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME",
+		func(rows *sql.Rows) error {
+			var t Table
+			if err := rows.Scan(&t.Name, &t.Engine, &t.Collation, &t.Comment); err != nil {
+				return err
+			}
+			s.Tables = append(s.Tables, t)
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range s.Tables {
+		tables[s.Tables[i].Name] = &s.Tables[i]
+	}
+
+	err = scanRows(db, "SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA, COALESCE(COLLATION_NAME, ''), COLUMN_COMMENT "+
+		"FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME, ORDINAL_POSITION",
+		func(rows *sql.Rows) error {
+			var (
+				table, nullable string
+				def             sql.NullString
+				c               Column
+			)
+			if err := rows.Scan(&table, &c.Name, &c.Type, &nullable, &def, &c.Extra, &c.Collation, &c.Comment); err != nil {
+				return err
+			}
+			c.Nullable = nullable == "YES"
+			if def.Valid {
+				c.Default = &def.String
+			}
+			if t, ok := tables[table]; ok {
+				t.Columns = append(t.Columns, c)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanRows(db, "SELECT TABLE_NAME, INDEX_NAME, NON_UNIQUE, COLUMN_NAME "+
+		"FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX",
+		func(rows *sql.Rows) error {
+			var (
+				table, name string
+				nonUnique   int
+				column      sql.NullString
+			)
+			if err := rows.Scan(&table, &name, &nonUnique, &column); err != nil {
+				return err
+			}
+			t, ok := tables[table]
+			if !ok {
+				return nil
+			}
+			index, ok := t.Index(name)
+			if !ok {
+				t.Indexes = append(t.Indexes, Index{Name: name, Unique: nonUnique == 0})
+				index = &t.Indexes[len(t.Indexes)-1]
+			}
+			// The column of functional key parts is NULL.
+			if column.Valid {
+				index.Columns = append(index.Columns, column.String)
+			}
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanRows(db, "SELECT TABLE_NAME, CONSTRAINT_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME "+
+		"FROM information_schema.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL "+
+		"ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION",
+		func(rows *sql.Rows) error {
+			var table, name, column, referencedTable, referencedColumn string
+			if err := rows.Scan(&table, &name, &column, &referencedTable, &referencedColumn); err != nil {
+				return err
+			}
+			t, ok := tables[table]
+			if !ok {
+				return nil
+			}
+			for i := range t.ForeignKeys {
+				if fk := &t.ForeignKeys[i]; fk.Name == name {
+					fk.Columns = append(fk.Columns, column)
+					fk.ReferencedColumns = append(fk.ReferencedColumns, referencedColumn)
+					return nil
+				}
+			}
+			t.ForeignKeys = append(t.ForeignKeys, ForeignKey{
+				Name:              name,
+				Columns:           []string{column},
+				ReferencedTable:   referencedTable,
+				ReferencedColumns: []string{referencedColumn},
+			})
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func scanRows(db *gorm.DB, query string, scan func(rows *sql.Rows) error) error {
+	rows, err := db.Raw(query).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// DriftKind defines the kind of difference between a model and its table.
+type DriftKind string
+
+func (k DriftKind) String() string {
+	return string(k)
+}
+
+const (
+	DriftOfMissingTable   DriftKind = "missing table"
+	DriftOfMissingColumn  DriftKind = "missing column"
+	DriftOfExtraColumn    DriftKind = "extra column"
+	DriftOfColumnType     DriftKind = "column type"
+	DriftOfColumnNullable DriftKind = "column nullable"
+	DriftOfColumnComment  DriftKind = "column comment"
+	DriftOfMissingIndex   DriftKind = "missing index"
+	DriftOfIndex          DriftKind = "index"
+	DriftOfTableComment   DriftKind = "table comment"
+)
+
+// Drift defines a difference between a model and its table, DDL is the
+// statement suggested to bring the table in line with the model.
+type Drift struct {
+	Table    string
+	Kind     DriftKind
+	Name     string
+	Expected string
+	Actual   string
+	DDL      string
+}
+
+func (d Drift) String() string {
+	var b strings.Builder
+	b.WriteString(d.Table)
+	if d.Name != "" {
+		b.WriteString(".")
+		b.WriteString(d.Name)
+	}
+	b.WriteString(": ")
+	b.WriteString(d.Kind.String())
+	if d.Expected != "" || d.Actual != "" {
+		fmt.Fprintf(&b, ", want %q, got %q", d.Expected, d.Actual)
+	}
+	return b.String()
+}
+
+// SchemaDiff defines the differences between models and a schema.
+type SchemaDiff struct {
+	Drifts []Drift
+}
+
+// Empty reports whether the models match the schema.
+func (d *SchemaDiff) Empty() bool {
+	return len(d.Drifts) == 0
+}
+
+// Report returns the differences one per line.
+func (d *SchemaDiff) Report() string {
+	if d.Empty() {
+		return "no drift\n"
+	}
+
+	var b strings.Builder
+	for _, drift := range d.Drifts {
+		b.WriteString(drift.String())
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// DDL returns the statements suggested to resolve the differences.
+// Extra columns are dropped, review them before running.
+func (d *SchemaDiff) DDL() []string {
+	statements := make([]string, 0, len(d.Drifts))
+	seen := make(map[string]bool, len(d.Drifts))
+	for _, drift := range d.Drifts {
+		if drift.DDL == "" || seen[drift.DDL] {
+			continue
+		}
+		seen[drift.DDL] = true
+		statements = append(statements, drift.DDL)
+	}
+	return statements
+}
+
+// DiffSchema compares the tables of the models to actual, such as one
+// read by InspectSchema. The table comment expected for a model is the
+// one of TableComment, or else the one of the gorm:table_options
+// setting of db, such as one set to GormComment.
+func DiffSchema(db *gorm.DB, actual *Schema, models ...interface{}) (*SchemaDiff, error) {
+	migrator, ok := db.Migrator().(interface {
+		FullDataTypeOf(*schema.Field) clause.Expr
+	})
+	if !ok {
+		return nil, fmt.Errorf("migrator %T does not support data types", db.Migrator())
+	}
+
+	diff := &SchemaDiff{}
+	for _, model := range models {
+		statement := &gorm.Statement{DB: db}
+		if err := statement.Parse(model); err != nil {
+			return nil, err
+		}
+
+		d := &differ{
+			db:       db,
+			migrator: migrator,
+			schema:   statement.Schema,
+			table:    statement.Schema.Table,
+		}
+
+		tableComment, hasTableComment := tableCommentOf(db, model)
+
+		table, ok := actual.Table(d.table)
+		if !ok {
+			diff.Drifts = append(diff.Drifts, Drift{
+				Table: d.table,
+				Kind:  DriftOfMissingTable,
+				DDL:   d.createTable(tableComment),
+			})
+			continue
+		}
+
+		diff.Drifts = append(diff.Drifts, d.columns(table)...)
+		diff.Drifts = append(diff.Drifts, d.indexes(table)...)
+
+		if hasTableComment && table.Comment != tableComment {
+			diff.Drifts = append(diff.Drifts, Drift{
+				Table:    d.table,
+				Kind:     DriftOfTableComment,
+				Expected: tableComment,
+				Actual:   table.Comment,
+				DDL:      fmt.Sprintf("ALTER TABLE %s COMMENT = %s", d.quote(d.table), quoteString(tableComment)),
+			})
+		}
+	}
+
+	return diff, nil
+}
+
+type differ struct {
+	db       *gorm.DB
+	migrator interface {
+		FullDataTypeOf(*schema.Field) clause.Expr
+	}
+	schema *schema.Schema
+	table  string
+}
+
+func (d *differ) quote(name string) string {
+	return d.db.Statement.Quote(name)
+}
+
+// definition returns the column definition of field, its comment is
+// quoted here as the dialector explains it with double quotes.
+func (d *differ) definition(field *schema.Field) string {
+	f := *field
+	f.TagSettings = make(map[string]string, len(field.TagSettings))
+	for k, v := range field.TagSettings {
+		if k != "COMMENT" {
+			f.TagSettings[k] = v
+		}
+	}
+
+	definition := d.quote(field.DBName) + " " + d.migrator.FullDataTypeOf(&f).SQL
+	if field.Comment != "" {
+		definition += " COMMENT " + quoteString(field.Comment)
+	}
+	return definition
+}
+
+func (d *differ) fields() []*schema.Field {
+	fields := make([]*schema.Field, 0, len(d.schema.DBNames))
+	for _, name := range d.schema.DBNames {
+		if field := d.schema.LookUpField(name); field != nil && !field.IgnoreMigration {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func (d *differ) columns(table *Table) []Drift {
+	var drifts []Drift
+	expected := make(map[string]bool)
+
+	for _, field := range d.fields() {
+		expected[field.DBName] = true
+
+		column, ok := table.Column(field.DBName)
+		if !ok {
+			drifts = append(drifts, Drift{
+				Table: d.table,
+				Kind:  DriftOfMissingColumn,
+				Name:  field.DBName,
+				DDL:   fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", d.quote(d.table), d.definition(field)),
+			})
+			continue
+		}
+
+		modify := fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s", d.quote(d.table), d.definition(field))
+
+		want, got := normalizeType(d.db.Dialector.DataTypeOf(field)), normalizeType(column.Type)
+		if want != got {
+			drifts = append(drifts, Drift{Table: d.table, Kind: DriftOfColumnType, Name: field.DBName, Expected: want, Actual: got, DDL: modify})
+		}
+
+		if nullable := !field.NotNull && !field.PrimaryKey; nullable != column.Nullable {
+			drifts = append(drifts, Drift{
+				Table:    d.table,
+				Kind:     DriftOfColumnNullable,
+				Name:     field.DBName,
+				Expected: fmt.Sprint(nullable),
+				Actual:   fmt.Sprint(column.Nullable),
+				DDL:      modify,
+			})
+		}
+
+		if field.Comment != column.Comment {
+			drifts = append(drifts, Drift{Table: d.table, Kind: DriftOfColumnComment, Name: field.DBName, Expected: field.Comment, Actual: column.Comment, DDL: modify})
+		}
+	}
+
+	for _, column := range table.Columns {
+		if !expected[column.Name] {
+			drifts = append(drifts, Drift{
+				Table:  d.table,
+				Kind:   DriftOfExtraColumn,
+				Name:   column.Name,
+				Actual: column.Type,
+				DDL:    fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", d.quote(d.table), d.quote(column.Name)),
+			})
+		}
+	}
+
+	return drifts
+}
+
+// expectedIndexes returns the indexes of the model, including the
+// ones MySQL creates for unique columns and the primary key.
+func (d *differ) expectedIndexes() []Index {
+	var indexes []Index
+
+	if len(d.schema.PrimaryFieldDBNames) > 0 {
+		indexes = append(indexes, Index{Name: "PRIMARY", Unique: true, Columns: d.schema.PrimaryFieldDBNames})
+	}
+
+	for _, field := range d.fields() {
+		if field.Unique {
+			indexes = append(indexes, Index{Name: field.DBName, Unique: true, Columns: []string{field.DBName}})
+		}
+	}
+
+	parsed := d.schema.ParseIndexes()
+	names := make([]string, 0, len(parsed))
+	for name := range parsed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		index := Index{Name: name, Unique: parsed[name].Class == "UNIQUE"}
+		for _, option := range parsed[name].Fields {
+			index.Columns = append(index.Columns, option.DBName)
+		}
+		indexes = append(indexes, index)
+	}
+
+	return indexes
+}
+
+func (d *differ) indexes(table *Table) []Drift {
+	var drifts []Drift
+
+	for _, want := range d.expectedIndexes() {
+		got, ok := table.Index(want.Name)
+		if !ok {
+			drifts = append(drifts, Drift{Table: d.table, Kind: DriftOfMissingIndex, Name: want.Name, Expected: describeIndex(want), DDL: d.createIndex(want)})
+			continue
+		}
+
+		if describeIndex(want) != describeIndex(*got) {
+			drift := Drift{Table: d.table, Kind: DriftOfIndex, Name: want.Name, Expected: describeIndex(want), Actual: describeIndex(*got)}
+			if want.Name != "PRIMARY" {
+				drift.DDL = fmt.Sprintf("ALTER TABLE %s DROP INDEX %s, ADD %s", d.quote(d.table), d.quote(want.Name), d.indexDefinition(want))
+			}
+			drifts = append(drifts, drift)
+		}
+	}
+
+	return drifts
+}
+
+func (d *differ) indexColumns(index Index) string {
+	columns := make([]string, 0, len(index.Columns))
+	for _, column := range index.Columns {
+		columns = append(columns, d.quote(column))
+	}
+	return strings.Join(columns, ", ")
+}
+
+func (d *differ) indexDefinition(index Index) string {
+	switch {
+	case index.Name == "PRIMARY":
+		return fmt.Sprintf("PRIMARY KEY (%s)", d.indexColumns(index))
+	case index.Unique:
+		return fmt.Sprintf("UNIQUE INDEX %s (%s)", d.quote(index.Name), d.indexColumns(index))
+	default:
+		return fmt.Sprintf("INDEX %s (%s)", d.quote(index.Name), d.indexColumns(index))
+	}
+}
+
+func (d *differ) createIndex(index Index) string {
+	if index.Name == "PRIMARY" {
+		return fmt.Sprintf("ALTER TABLE %s ADD %s", d.quote(d.table), d.indexDefinition(index))
+	}
+
+	unique := ""
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s)", unique, d.quote(index.Name), d.quote(d.table), d.indexColumns(index))
+}
+
+func (d *differ) createTable(comment string) string {
+	var definitions []string
+	for _, field := range d.fields() {
+		f := *field
+		// Unique columns are covered by the indexes below.
+		f.Unique = false
+		definitions = append(definitions, d.definition(&f))
+	}
+	for _, index := range d.expectedIndexes() {
+		definitions = append(definitions, d.indexDefinition(index))
+	}
+
+	statement := fmt.Sprintf("CREATE TABLE %s (%s)", d.quote(d.table), strings.Join(definitions, ", "))
+	if comment != "" {
+		statement += " COMMENT = " + quoteString(comment)
+	}
+	return statement
+}
+
+func describeIndex(index Index) string {
+	kind := "index"
+	if index.Unique {
+		kind = "unique"
+	}
+	return kind + " (" + strings.Join(index.Columns, ", ") + ")"
+}
+
+var (
+	integerWidthPattern = regexp.MustCompile(`\b(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+	commentPattern      = regexp.MustCompile(`(?i)\bcomment\s*=?\s*'((?:[^'\\]|\\.|'')*)'`)
+)
+
+// normalizeType returns the type MySQL reports for a column of type t,
+// without integer display widths as MySQL 8 no longer reports them.
+func normalizeType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(t))
+	t = strings.TrimSuffix(t, " auto_increment")
+	t = strings.TrimSuffix(t, " null")
+	if t == "boolean" || t == "bool" {
+		t = "tinyint"
+	}
+	t = integerWidthPattern.ReplaceAllString(t, "$1")
+	return strings.ReplaceAll(t, ", ", ",")
+}
+
+// commentOf returns the comment of table options, such as the ones of
+// GormComment.
+func commentOf(options string) (string, bool) {
+	match := commentPattern.FindStringSubmatch(options)
+	if match == nil {
+		return "", false
+	}
+	return strings.NewReplacer(`''`, `'`, `\'`, `'`, `\\`, `\`, `\0`, "\x00").Replace(match[1]), true
+}
@@ -0,0 +1,183 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// schemaHandler answers the information_schema queries of InspectSchema.
+func schemaHandler(query string, args []interface{}) (driver.Rows, error) {
+	switch {
+	case strings.Contains(query, "information_schema.TABLES"):
+		return &fakeRows{
+			columns: []string{"TABLE_NAME", "ENGINE", "TABLE_COLLATION", "TABLE_COMMENT"},
+			values: [][]driver.Value{
+				{"orders", "InnoDB", "utf8mb4_general_ci", ""},
+				{"users", "InnoDB", "utf8mb4_general_ci", "users of the shop"},
+			},
+		}, nil
+	case strings.Contains(query, "information_schema.COLUMNS"):
+		return &fakeRows{
+			columns: []string{"TABLE_NAME", "COLUMN_NAME", "COLUMN_TYPE", "IS_NULLABLE", "COLUMN_DEFAULT", "EXTRA", "COLLATION_NAME", "COLUMN_COMMENT"},
+			values: [][]driver.Value{
+				{"orders", "id", "bigint(20) unsigned", "NO", nil, "auto_increment", "", ""},
+				{"orders", "user_id", "bigint(20) unsigned", "YES", nil, "", "", ""},
+				{"users", "id", "bigint unsigned", "NO", nil, "auto_increment", "", ""},
+				{"users", "name", "varchar(64)", "NO", "", "", "utf8mb4_general_ci", "nickname"},
+			},
+		}, nil
+	case strings.Contains(query, "information_schema.STATISTICS"):
+		return &fakeRows{
+			columns: []string{"TABLE_NAME", "INDEX_NAME", "NON_UNIQUE", "COLUMN_NAME"},
+			values: [][]driver.Value{
+				{"orders", "PRIMARY", int64(0), "id"},
+				{"orders", "idx_user", int64(1), "user_id"},
+				{"orders", "idx_user", int64(1), "id"},
+				{"orders", "idx_year", int64(1), nil},
+				{"orders", "idx_year", int64(1), "user_id"},
+				{"users", "PRIMARY", int64(0), "id"},
+			},
+		}, nil
+	case strings.Contains(query, "information_schema.KEY_COLUMN_USAGE"):
+		return &fakeRows{
+			columns: []string{"TABLE_NAME", "CONSTRAINT_NAME", "COLUMN_NAME", "REFERENCED_TABLE_NAME", "REFERENCED_COLUMN_NAME"},
+			values:  [][]driver.Value{{"orders", "fk_orders_user", "user_id", "users", "id"}},
+		}, nil
+	}
+	return nil, nil
+}
+
+func TestInspectSchema(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+	fake.handle(schemaHandler)
+
+	s, err := InspectSchema(context.Background(), db)
+	assert.NoError(t, err)
+	assert.Len(t, s.Tables, 2)
+
+	users, ok := s.Table("users")
+	assert.True(t, ok)
+	assert.Equal(t, "users of the shop", users.Comment)
+	assert.Equal(t, []Column{
+		{Name: "id", Type: "bigint unsigned", Extra: "auto_increment"},
+		{Name: "name", Type: "varchar(64)", Default: new(string), Collation: "utf8mb4_general_ci", Comment: "nickname"},
+	}, users.Columns)
+
+	orders, ok := s.Table("orders")
+	assert.True(t, ok)
+	assert.Equal(t, []Index{
+		{Name: "PRIMARY", Unique: true, Columns: []string{"id"}},
+		{Name: "idx_user", Columns: []string{"user_id", "id"}},
+		{Name: "idx_year", Columns: []string{"user_id"}},
+	}, orders.Indexes)
+	assert.Equal(t, []ForeignKey{
+		{Name: "fk_orders_user", Columns: []string{"user_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+	}, orders.ForeignKeys)
+
+	_, ok = s.Table("products")
+	assert.False(t, ok)
+}
+
+type schemaUser struct {
+	ID    uint64
+	Name  string `gorm:"size:64;not null;comment:user's nickname"`
+	Email string `gorm:"size:128;uniqueIndex"`
+}
+
+func (schemaUser) TableName() string { return "users" }
+
+type schemaProduct struct {
+	ID    uint64
+	Title string `gorm:"size:100;not null"`
+}
+
+func (schemaProduct) TableName() string { return "products" }
+
+func TestDiffSchema(t *testing.T) {
+	actual := &Schema{Tables: []Table{{
+		Name:    "users",
+		Comment: "users",
+		Columns: []Column{
+			{Name: "id", Type: "bigint(20) unsigned", Extra: "auto_increment"},
+			{Name: "name", Type: "varchar(32)", Nullable: true, Comment: "nickname"},
+			{Name: "age", Type: "int", Nullable: true},
+		},
+		Indexes: []Index{{Name: "PRIMARY", Unique: true, Columns: []string{"id"}}},
+	}}}
+
+	db := newDryRunDB(t).Set("gorm:table_options", GormComment("users of the shop"))
+
+	diff, err := DiffSchema(db, actual, &schemaUser{}, &schemaProduct{})
+	assert.NoError(t, err)
+	assert.False(t, diff.Empty())
+
+	kinds := make([]string, 0, len(diff.Drifts))
+	for _, drift := range diff.Drifts {
+		kinds = append(kinds, drift.Name+" "+drift.Kind.String())
+	}
+	assert.Equal(t, []string{
+		"name column type",
+		"name column nullable",
+		"name column comment",
+		"email missing column",
+		"age extra column",
+		"idx_users_email missing index",
+		" table comment",
+		" missing table",
+	}, kinds)
+
+	assert.Equal(t, []string{
+		"ALTER TABLE `users` MODIFY COLUMN `name` varchar(64) NOT NULL COMMENT 'user\\'s nickname'",
+		"ALTER TABLE `users` ADD COLUMN `email` varchar(128)",
+		"ALTER TABLE `users` DROP COLUMN `age`",
+		"CREATE UNIQUE INDEX `idx_users_email` ON `users` (`email`)",
+		"ALTER TABLE `users` COMMENT = 'users of the shop'",
+		"CREATE TABLE `products` (`id` bigint unsigned AUTO_INCREMENT, `title` varchar(100) NOT NULL, PRIMARY KEY (`id`)) COMMENT = 'users of the shop'",
+	}, diff.DDL())
+
+	assert.Contains(t, diff.Report(), `users.name: column type, want "varchar(64)", got "varchar(32)"`)
+}
+
+func TestDiffSchema_NoDrift(t *testing.T) {
+	actual := &Schema{Tables: []Table{{
+		Name: "products",
+		Columns: []Column{
+			{Name: "id", Type: "bigint unsigned", Extra: "auto_increment"},
+			{Name: "title", Type: "varchar(100)"},
+		},
+		Indexes: []Index{{Name: "PRIMARY", Unique: true, Columns: []string{"id"}}},
+	}}}
+
+	diff, err := DiffSchema(newDryRunDB(t), actual, &schemaProduct{})
+	assert.NoError(t, err)
+	assert.True(t, diff.Empty())
+	assert.Equal(t, "no drift\n", diff.Report())
+}
+
+func TestNormalizeType(t *testing.T) {
+	assert.Equal(t, "bigint unsigned", normalizeType("bigint(20) unsigned"))
+	assert.Equal(t, "bigint unsigned", normalizeType("bigint unsigned AUTO_INCREMENT"))
+	assert.Equal(t, "tinyint", normalizeType("boolean"))
+	assert.Equal(t, "tinyint", normalizeType("tinyint(1)"))
+	assert.Equal(t, "decimal(10,2)", normalizeType("decimal(10, 2)"))
+	assert.Equal(t, "datetime(3)", normalizeType("datetime(3) NULL"))
+}
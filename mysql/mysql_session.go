@@ -0,0 +1,263 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrSessionMismatch is returned when a session variable read back on
+// startup differs from the configured value. Combination sql_mode
+// values, such as TRADITIONAL, are expanded by the server and not
+// compared, the other modes configured along them are.
+var ErrSessionMismatch = errors.New("mysql: session variable mismatch")
+
+// sessionVariable defines a variable set on every new connection.
+type sessionVariable struct {
+	name  string
+	value interface{}
+}
+
+// literal returns the value as an SQL literal.
+func (v sessionVariable) literal() string {
+	switch value := v.value.(type) {
+	case string:
+		return quoteString(value)
+	case bool:
+		if value {
+			return "1"
+		}
+		return "0"
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// sessionStatements returns the statements run on every new connection,
+// the variables first.
+func sessionStatements(options *options) ([]string, error) {
+	var statements []string
+	if len(options.sessionVariables) > 0 {
+		assignments := make([]string, 0, len(options.sessionVariables))
+		for _, v := range options.sessionVariables {
+			name, err := keyword("session variable", v.name)
+			if err != nil {
+				return nil, err
+			}
+			assignments = append(assignments, name+" = "+v.literal())
+		}
+		statements = append(statements, "SET SESSION "+strings.Join(assignments, ", "))
+	}
+
+	return append(statements, options.initStatements...), nil
+}
+
+// openDB opens the pool of the data source name, the connections run
+// the session statements of the options when they are established.
+func openDB(driverName, dataSourceName string, options *options) (*sql.DB, error) {
+	statements, err := sessionStatements(options)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(statements) == 0 {
+		return sql.Open(driverName, dataSourceName)
+	}
+
+	// The driver is only reachable through a pool.
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	_ = db.Close()
+
+	var connector driver.Connector = dsnConnector{driver: d, dsn: dataSourceName}
+	if dc, ok := d.(driver.DriverContext); ok {
+		if connector, err = dc.OpenConnector(dataSourceName); err != nil {
+			return nil, err
+		}
+	}
+
+	return sql.OpenDB(&sessionConnector{Connector: connector, statements: statements}), nil
+}
+
+// dsnConnector opens connections of drivers without connector.
+type dsnConnector struct {
+	driver driver.Driver
+	dsn    string
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// sessionConnector initializes the sessions of the connections it
+// establishes, a connection failing to is closed and never pooled.
+type sessionConnector struct {
+	driver.Connector
+	statements []string
+}
+
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range c.statements {
+		if err := execConn(ctx, conn, s); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("mysql: session init %q: %w", s, err)
+		}
+	}
+
+	return conn, nil
+}
+
+func execConn(ctx context.Context, conn driver.Conn, query string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, nil)
+		return err
+	}
+
+	_, err = stmt.Exec(nil) // nolint:staticcheck
+	return err
+}
+
+// verifySession reads the session variables back on a connection of
+// the pool, such as on startup, to catch the values the server ignored
+// or rewrote.
+func verifySession(ctx context.Context, db *sql.DB, options *options) error {
+	if len(options.sessionVariables) == 0 {
+		return nil
+	}
+
+	selects := make([]string, 0, len(options.sessionVariables))
+	for _, v := range options.sessionVariables {
+		selects = append(selects, "@@SESSION."+v.name)
+	}
+
+	values := make([]sql.NullString, len(selects))
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := db.QueryRowContext(ctx, "SELECT "+strings.Join(selects, ", ")).Scan(dest...); err != nil {
+		return err
+	}
+
+	for i, v := range options.sessionVariables {
+		same := sameSessionValue
+		if strings.EqualFold(v.name, "sql_mode") {
+			same = sameSQLMode
+		}
+		if expected := fmt.Sprint(v.value); !same(expected, values[i].String) {
+			return fmt.Errorf("%w: %s is %q, expected %q", ErrSessionMismatch, v.name, values[i].String, expected)
+		}
+	}
+
+	return nil
+}
+
+// sameSessionValue compares session values regardless of case and of
+// the order of lists, such as sql_mode.
+func sameSessionValue(expected, actual string) bool {
+	normalize := func(s string) string {
+		items := strings.Split(strings.ToUpper(s), ",")
+		for i := range items {
+			items[i] = strings.TrimSpace(items[i])
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	}
+
+	switch strings.ToUpper(expected) {
+	case "TRUE", "ON":
+		expected = "1"
+	case "FALSE", "OFF":
+		expected = "0"
+	}
+
+	return normalize(expected) == normalize(actual)
+}
+
+// sqlModeCombinations defines the sql_mode values the server expands
+// into a list of modes, which depends on its version.
+var sqlModeCombinations = map[string]struct{}{
+	"ANSI": {}, "DB2": {}, "MAXDB": {}, "MSSQL": {}, "MYSQL323": {},
+	"MYSQL40": {}, "ORACLE": {}, "POSTGRESQL": {}, "TRADITIONAL": {},
+}
+
+// sameSQLMode compares sql_mode values like sameSessionValue, unless
+// combination modes such as TRADITIONAL are expected: their expansion
+// can't be compared, only the other modes expected must be set.
+func sameSQLMode(expected, actual string) bool {
+	modes := func(s string) map[string]bool {
+		set := make(map[string]bool)
+		for _, mode := range strings.Split(strings.ToUpper(s), ",") {
+			if mode = strings.TrimSpace(mode); mode != "" {
+				set[mode] = true
+			}
+		}
+		return set
+	}
+
+	expectedModes, actualModes := modes(expected), modes(actual)
+
+	combined := false
+	for mode := range expectedModes {
+		if _, ok := sqlModeCombinations[mode]; ok {
+			combined = true
+		}
+	}
+	if !combined {
+		return sameSessionValue(expected, actual)
+	}
+
+	for mode := range expectedModes {
+		if _, ok := sqlModeCombinations[mode]; ok {
+			continue
+		}
+		if !actualModes[mode] {
+			return false
+		}
+	}
+	return true
+}
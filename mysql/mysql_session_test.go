@@ -0,0 +1,159 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/coolstina/connecter"
+	"github.com/stretchr/testify/assert"
+)
+
+var sessionConfig = &Config{
+	Database:           "connecter",
+	DriverName:         connecter.DriverName(fakeDriverName),
+	SkipCreateDatabase: true,
+	MaxOpenConnections: 4,
+	MaxIdleConnections: 4,
+}
+
+var sessionOptions = []Option{
+	WithSessionVariable("sql_mode", "STRICT_TRANS_TABLES,NO_ZERO_DATE"),
+	WithSessionVariable("time_zone", "+00:00"),
+	WithSessionVariable("group_concat_max_len", 1048576),
+	WithInitStatement("SET NAMES utf8mb4 COLLATE utf8mb4_unicode_ci"),
+}
+
+const sessionSelect = "SELECT @@SESSION.sql_mode, @@SESSION.time_zone, @@SESSION.group_concat_max_len"
+
+// sessionHandler answers the session verification with values and
+// fails the statement fail.
+func sessionHandler(values []driver.Value, fail string) fakeHandler {
+	return func(query string, args []interface{}) (driver.Rows, error) {
+		switch query {
+		case sessionSelect:
+			return &fakeRows{columns: strings.Split(strings.TrimPrefix(query, "SELECT "), ", "), values: [][]driver.Value{values}}, nil
+		case fail:
+			return nil, errors.New("fake: statement failed")
+		}
+		return nil, nil
+	}
+}
+
+func TestNewConnection_Session(t *testing.T) {
+	fake.reset()
+	fake.handle(sessionHandler([]driver.Value{"NO_ZERO_DATE,STRICT_TRANS_TABLES", "+00:00", "1048576"}, ""))
+
+	db, err := NewConnection(sessionConfig, sessionOptions...)
+	assert.NoError(t, err)
+
+	queries := queriesOf(fake.recorded())
+	assert.Equal(t, []string{
+		"SET SESSION sql_mode = 'STRICT_TRANS_TABLES,NO_ZERO_DATE', time_zone = '+00:00', group_concat_max_len = 1048576",
+		"SET NAMES utf8mb4 COLLATE utf8mb4_unicode_ci",
+		"SELECT VERSION()",
+		sessionSelect,
+	}, queries)
+
+	// Every new connection is initialized, not only the first one.
+	fake.reset()
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	first, err := sqlDB.Conn(ctx)
+	assert.NoError(t, err)
+	second, err := sqlDB.Conn(ctx)
+	assert.NoError(t, err)
+	assert.NoError(t, first.Close())
+	assert.NoError(t, second.Close())
+
+	assert.Equal(t, []string{
+		"SET SESSION sql_mode = 'STRICT_TRANS_TABLES,NO_ZERO_DATE', time_zone = '+00:00', group_concat_max_len = 1048576",
+		"SET NAMES utf8mb4 COLLATE utf8mb4_unicode_ci",
+	}, queriesOf(fake.recorded()))
+}
+
+func TestNewConnection_SessionErrors(t *testing.T) {
+	fake.reset()
+	fake.handle(sessionHandler([]driver.Value{"STRICT_TRANS_TABLES", "SYSTEM", "1024"}, ""))
+	_, err := NewConnection(sessionConfig, sessionOptions...)
+	assert.ErrorIs(t, err, ErrSessionMismatch)
+
+	// Combination modes are expanded by the server.
+	fake.reset()
+	fake.handle(func(query string, args []interface{}) (driver.Rows, error) {
+		if query == "SELECT @@SESSION.sql_mode" {
+			return &fakeRows{columns: []string{"@@SESSION.sql_mode"}, values: [][]driver.Value{{"STRICT_TRANS_TABLES,STRICT_ALL_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION"}}}, nil
+		}
+		return nil, nil
+	})
+	db, err := NewConnection(sessionConfig, WithSessionVariable("sql_mode", "TRADITIONAL"))
+	assert.NoError(t, err)
+	assert.NoError(t, Close(db))
+
+	fake.reset()
+	fake.handle(sessionHandler(nil, "SET NAMES utf8mb4 COLLATE utf8mb4_unicode_ci"))
+	_, err = NewConnection(sessionConfig, sessionOptions...)
+	assert.Error(t, err)
+	assert.NotContains(t, queriesOf(fake.recorded()), "SELECT VERSION()")
+
+	fake.reset()
+	_, err = NewConnection(sessionConfig, WithSessionVariable("time_zone = '+00:00'; DROP TABLE users; --", 1))
+	assert.ErrorIs(t, err, ErrInvalidIdentifier)
+	assert.Empty(t, fake.recorded())
+}
+
+func TestSameSessionValue(t *testing.T) {
+	grid := []struct {
+		expected string
+		actual   string
+		same     bool
+	}{
+		{expected: "+00:00", actual: "+00:00", same: true},
+		{expected: "READ-COMMITTED", actual: "read-committed", same: true},
+		{expected: "STRICT_TRANS_TABLES, NO_ZERO_DATE", actual: "NO_ZERO_DATE,STRICT_TRANS_TABLES", same: true},
+		{expected: "true", actual: "1", same: true},
+		{expected: "OFF", actual: "0", same: true},
+		{expected: "1048576", actual: "1024"},
+		{expected: "STRICT_TRANS_TABLES", actual: "STRICT_TRANS_TABLES,NO_ZERO_DATE"},
+	}
+
+	for _, g := range grid {
+		assert.Equal(t, g.same, sameSessionValue(g.expected, g.actual), "%s %s", g.expected, g.actual)
+	}
+}
+
+func TestSameSQLMode(t *testing.T) {
+	grid := []struct {
+		expected string
+		actual   string
+		same     bool
+	}{
+		{expected: "STRICT_TRANS_TABLES,NO_ZERO_DATE", actual: "NO_ZERO_DATE,STRICT_TRANS_TABLES", same: true},
+		{expected: "STRICT_TRANS_TABLES", actual: "STRICT_TRANS_TABLES,NO_ZERO_DATE"},
+		{expected: "TRADITIONAL", actual: "STRICT_TRANS_TABLES,STRICT_ALL_TABLES,NO_ZERO_IN_DATE,NO_ZERO_DATE,ERROR_FOR_DIVISION_BY_ZERO,NO_ENGINE_SUBSTITUTION", same: true},
+		{expected: "ansi", actual: "REAL_AS_FLOAT,PIPES_AS_CONCAT,ANSI_QUOTES,IGNORE_SPACE,ONLY_FULL_GROUP_BY,ANSI", same: true},
+		{expected: "ANSI,NO_ZERO_DATE", actual: "REAL_AS_FLOAT,PIPES_AS_CONCAT,ANSI_QUOTES,IGNORE_SPACE,ONLY_FULL_GROUP_BY,ANSI"},
+	}
+
+	for _, g := range grid {
+		assert.Equal(t, g.same, sameSQLMode(g.expected, g.actual), "%s %s", g.expected, g.actual)
+	}
+}
@@ -0,0 +1,551 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	shardingName  = "connecter:sharding"
+	crossShardKey = "connecter:cross_shard"
+)
+
+var (
+	// ErrCrossShard is returned for statements on a sharded table whose
+	// shard is not known from the sharding key, such as queries without
+	// it that are not allowed by AllowCrossShard, and any such write.
+	ErrCrossShard = errors.New("mysql: cross-shard statement")
+
+	// ErrShardKey is returned for sharding key values the algorithm has
+	// no shard for.
+	ErrShardKey = errors.New("mysql: invalid sharding key")
+)
+
+// ShardAlgorithm maps the values of a sharding key to shards, each
+// shard is a table named after the logical one and its suffix.
+type ShardAlgorithm interface {
+	// Shard returns the suffix of the shard of the key value.
+	Shard(value interface{}) (string, error)
+	// Shards returns the suffixes of every shard.
+	Shards() []string
+}
+
+type modShards struct {
+	n      int
+	layout string
+}
+
+// ModShards shards by the integer key modulo n, such as orders_03 for
+// a user ID of 19 and 16 shards. n must be positive.
+func ModShards(n int) (ShardAlgorithm, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("mysql: invalid shard count %d", n)
+	}
+	return &modShards{n: n, layout: shardLayout(n)}, nil
+}
+
+func (m *modShards) Shard(value interface{}) (string, error) {
+	v := reflect.Indirect(reflect.ValueOf(value))
+	var n int64
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = v.Int() % int64(m.n)
+		if n < 0 {
+			n = -n
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = int64(v.Uint() % uint64(m.n))
+	default:
+		return "", fmt.Errorf("%w: %T is not an integer", ErrShardKey, value)
+	}
+	return fmt.Sprintf(m.layout, n), nil
+}
+
+func (m *modShards) Shards() []string {
+	return numberedShards(m.n, m.layout)
+}
+
+type hashShards struct {
+	n      int
+	layout string
+}
+
+// HashShards shards by the FNV-1a hash of the key modulo n, for keys of
+// any type such as strings. n must be positive.
+func HashShards(n int) (ShardAlgorithm, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("mysql: invalid shard count %d", n)
+	}
+	return &hashShards{n: n, layout: shardLayout(n)}, nil
+}
+
+func (h *hashShards) Shard(value interface{}) (string, error) {
+	v := reflect.Indirect(reflect.ValueOf(value))
+	if !v.IsValid() {
+		return "", fmt.Errorf("%w: nil", ErrShardKey)
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(fmt.Sprint(v.Interface())))
+	return fmt.Sprintf(h.layout, hash.Sum32()%uint32(h.n)), nil
+}
+
+func (h *hashShards) Shards() []string {
+	return numberedShards(h.n, h.layout)
+}
+
+// shardLayout pads shard numbers to the width of the last one.
+func shardLayout(n int) string {
+	return "%0" + strconv.Itoa(len(strconv.Itoa(n-1))) + "d"
+}
+
+func numberedShards(n int, layout string) []string {
+	shards := make([]string, n)
+	for i := range shards {
+		shards[i] = fmt.Sprintf(layout, i)
+	}
+	return shards
+}
+
+// DatePeriod defines the time range of a date shard.
+type DatePeriod string
+
+func (p DatePeriod) String() string {
+	return string(p)
+}
+
+const (
+	DatePeriodOfDay   DatePeriod = "day"
+	DatePeriodOfMonth DatePeriod = "month"
+	DatePeriodOfYear  DatePeriod = "year"
+)
+
+type dateShards struct {
+	period DatePeriod
+	from   time.Time
+	// end is the start of the period following the one of to.
+	end time.Time
+}
+
+// DateShards shards by the time key into one shard per period from the
+// period of from to the period of to, both whole, such as events_202112
+// for monthly shards. Times are sharded in the location of from.
+func DateShards(period DatePeriod, from, to time.Time) ShardAlgorithm {
+	d := &dateShards{period: period}
+	d.from = d.start(from)
+	d.end = d.next(d.start(to.In(from.Location())))
+	return d
+}
+
+func (d *dateShards) layout() string {
+	switch d.period {
+	case DatePeriodOfDay:
+		return "20060102"
+	case DatePeriodOfYear:
+		return "2006"
+	default:
+		return "200601"
+	}
+}
+
+func (d *dateShards) start(t time.Time) time.Time {
+	switch d.period {
+	case DatePeriodOfDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case DatePeriodOfYear:
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+}
+
+func (d *dateShards) next(t time.Time) time.Time {
+	switch d.period {
+	case DatePeriodOfDay:
+		return t.AddDate(0, 0, 1)
+	case DatePeriodOfYear:
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
+
+func (d *dateShards) Shard(value interface{}) (string, error) {
+	var t time.Time
+	switch v := value.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v == nil {
+			return "", fmt.Errorf("%w: nil", ErrShardKey)
+		}
+		t = *v
+	default:
+		return "", fmt.Errorf("%w: %T is not a time", ErrShardKey, value)
+	}
+
+	t = t.In(d.from.Location())
+	if t.Before(d.from) || !t.Before(d.end) {
+		return "", fmt.Errorf("%w: %s is out of the shard range", ErrShardKey, t)
+	}
+	return t.Format(d.layout()), nil
+}
+
+func (d *dateShards) Shards() []string {
+	var shards []string
+	for t := d.from; t.Before(d.end); t = d.next(t) {
+		shards = append(shards, t.Format(d.layout()))
+	}
+	return shards
+}
+
+// ShardRule defines how the logical table is sharded by its key column.
+type ShardRule struct {
+	Table     string
+	Key       string
+	Algorithm ShardAlgorithm
+}
+
+// Sharding is a gorm plugin that sends the statements on logical tables
+// to their shard tables, picked from the sharding key found in the
+// equality or IN conditions, or in the values of creates and of the
+// models of updates and deletes. Queries spanning several shards read
+// their union when allowed by AllowCrossShard, such writes fail.
+type Sharding struct {
+	rules map[string]ShardRule
+}
+
+// NewSharding initialize sharding instance for the rules.
+func NewSharding(rules ...ShardRule) *Sharding {
+	s := &Sharding{rules: make(map[string]ShardRule, len(rules))}
+	for _, rule := range rules {
+		s.rules[rule.Table] = rule
+	}
+	return s
+}
+
+// AllowCrossShard allows the queries of db to read every shard they
+// may span, such as queries without the sharding key.
+func AllowCrossShard(db *gorm.DB) *gorm.DB {
+	return db.Set(crossShardKey, true)
+}
+
+// Name implements gorm.Plugin.
+func (s *Sharding) Name() string {
+	return shardingName
+}
+
+// Initialize implements gorm.Plugin, it registers the callbacks that
+// rewrite the table of statements on sharded tables.
+func (s *Sharding) Initialize(db *gorm.DB) error {
+	callbacks := []struct {
+		name     string
+		register func(name string, fn func(*gorm.DB)) error
+		read     bool
+	}{
+		{"query", db.Callback().Query().Before("gorm:query").Register, true},
+		{"row", db.Callback().Row().Before("gorm:row").Register, true},
+		{"create", db.Callback().Create().Before("gorm:create").Register, false},
+		{"update", db.Callback().Update().Before("gorm:update").Register, false},
+		{"delete", db.Callback().Delete().Before("gorm:delete").Register, false},
+	}
+
+	for _, callback := range callbacks {
+		read := callback.read
+		if err := callback.register("connecter:sharding_"+callback.name, func(db *gorm.DB) {
+			s.route(db, read)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Tables returns the shard tables of the logical table.
+func (s *Sharding) Tables(table string) ([]string, error) {
+	rule, ok := s.rules[table]
+	if !ok {
+		return nil, fmt.Errorf("mysql: table %q is not sharded", table)
+	}
+
+	shards := rule.Algorithm.Shards()
+	tables := make([]string, len(shards))
+	for i, shard := range shards {
+		tables[i] = table + "_" + shard
+	}
+	return tables, nil
+}
+
+// ShardDDL returns the statements creating the missing shard tables of
+// the logical table, like the logical table which serves as template,
+// such as one created with AutoMigrate.
+func (s *Sharding) ShardDDL(table string) ([]string, error) {
+	tables, err := s.Tables(table)
+	if err != nil {
+		return nil, err
+	}
+
+	template, err := QuoteIdentifier(table)
+	if err != nil {
+		return nil, err
+	}
+
+	statements := make([]string, 0, len(tables))
+	for _, shard := range tables {
+		name, err := QuoteIdentifier(shard)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s LIKE %s", name, template))
+	}
+	return statements, nil
+}
+
+// CreateShards runs the statements of ShardDDL.
+func (s *Sharding) CreateShards(ctx context.Context, db *gorm.DB, table string) error {
+	statements, err := s.ShardDDL(table)
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range statements {
+		if err := db.WithContext(ctx).Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// route rewrites the table of the statement to its shards.
+func (s *Sharding) route(db *gorm.DB, read bool) {
+	if db.Error != nil || db.Statement.SQL.Len() > 0 {
+		return
+	}
+
+	rule, ok := s.rules[db.Statement.Table]
+	if !ok {
+		return
+	}
+
+	shards, err := s.shardsOf(db, rule, read)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+
+	if len(shards) == 0 {
+		if _, allowed := db.Get(crossShardKey); !read || !allowed {
+			_ = db.AddError(fmt.Errorf("%w: %s has no condition on %s", ErrCrossShard, rule.Table, rule.Key))
+			return
+		}
+		shards = rule.Algorithm.Shards()
+	}
+
+	if len(shards) == 1 {
+		db.Statement.Table = rule.Table + "_" + shards[0]
+		db.Statement.TableExpr = nil
+		return
+	}
+
+	if _, allowed := db.Get(crossShardKey); !read || !allowed {
+		_ = db.AddError(fmt.Errorf("%w: %s spans %d shards", ErrCrossShard, rule.Table, len(shards)))
+		return
+	}
+
+	selects := make([]string, len(shards))
+	for i, shard := range shards {
+		selects[i] = "SELECT * FROM " + db.Statement.Quote(rule.Table+"_"+shard)
+	}
+	db.Statement.TableExpr = &clause.Expr{
+		SQL: "(" + strings.Join(selects, " UNION ALL ") + ") AS " + db.Statement.Quote(rule.Table),
+	}
+}
+
+// shardsOf returns the sorted suffixes of the shards the statement
+// spans, none if the sharding key is not found.
+func (s *Sharding) shardsOf(db *gorm.DB, rule ShardRule, read bool) ([]string, error) {
+	values := whereKeyValues(db.Statement, rule.Key)
+	if len(values) == 0 {
+		values = modelKeyValues(db.Statement, rule.Key, read)
+	}
+
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		shard, err := rule.Algorithm.Shard(value)
+		if err != nil {
+			return nil, err
+		}
+		set[shard] = true
+	}
+
+	shards := make([]string, 0, len(set))
+	for shard := range set {
+		shards = append(shards, shard)
+	}
+	sort.Strings(shards)
+	return shards, nil
+}
+
+// whereKeyValues returns the values the WHERE conditions restrict the
+// key to, none if any condition is joined with OR.
+func whereKeyValues(stmt *gorm.Statement, key string) []interface{} {
+	c, ok := stmt.Clauses["WHERE"]
+	if !ok {
+		return nil
+	}
+	where, ok := c.Expression.(clause.Where)
+	if !ok {
+		return nil
+	}
+	return keyValuesOf(where.Exprs, key)
+}
+
+func keyValuesOf(exprs []clause.Expression, key string) []interface{} {
+	for _, expr := range exprs {
+		if _, ok := expr.(clause.OrConditions); ok {
+			return nil
+		}
+	}
+
+	for _, expr := range exprs {
+		switch e := expr.(type) {
+		case clause.Eq:
+			if columnNameOf(e.Column) == key {
+				return valuesOf(e.Value)
+			}
+		case clause.IN:
+			if columnNameOf(e.Column) == key {
+				return e.Values
+			}
+		case clause.AndConditions:
+			if values := keyValuesOf(e.Exprs, key); len(values) > 0 {
+				return values
+			}
+		case clause.Expr:
+			if values := exprKeyValues(e, key); len(values) > 0 {
+				return values
+			}
+		}
+	}
+	return nil
+}
+
+var (
+	keyConditionPattern = regexp.MustCompile("(?i)^\\s*(?:`?\\w+`?\\.)?`?(\\w+)`?\\s*(?:=|IN)\\s*\\(?\\s*\\?\\s*\\)?\\s*$")
+	andPattern          = regexp.MustCompile(`(?i)\s+AND\s+`)
+)
+
+// exprKeyValues finds the key in string conditions such as
+// "user_id = ? AND status = ?", ones with OR are skipped.
+func exprKeyValues(expr clause.Expr, key string) []interface{} {
+	if strings.Contains(strings.ToUpper(expr.SQL), " OR ") {
+		return nil
+	}
+
+	index := 0
+	for _, part := range andPattern.Split(expr.SQL, -1) {
+		if match := keyConditionPattern.FindStringSubmatch(part); match != nil && match[1] == key && index < len(expr.Vars) {
+			return valuesOf(expr.Vars[index])
+		}
+		index += strings.Count(part, "?")
+	}
+	return nil
+}
+
+// modelKeyValues returns the key values of the created rows, or of the
+// model of updates and deletes.
+func modelKeyValues(stmt *gorm.Statement, key string, read bool) []interface{} {
+	if read {
+		return nil
+	}
+
+	if dest, ok := stmt.Dest.(map[string]interface{}); ok {
+		if value, ok := dest[key]; ok {
+			return []interface{}{value}
+		}
+	}
+
+	if stmt.Schema == nil {
+		return nil
+	}
+	field := stmt.Schema.LookUpField(key)
+	if field == nil {
+		return nil
+	}
+
+	value := stmt.ReflectValue
+	if value.Kind() == reflect.Map {
+		value = reflect.Indirect(reflect.ValueOf(stmt.Model))
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if v, zero := field.ValueOf(value); !zero {
+			return []interface{}{v}
+		}
+	case reflect.Slice, reflect.Array:
+		values := make([]interface{}, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			row := reflect.Indirect(value.Index(i))
+			if row.Kind() != reflect.Struct {
+				return nil
+			}
+			v, zero := field.ValueOf(row)
+			if zero {
+				return nil
+			}
+			values = append(values, v)
+		}
+		return values
+	}
+	return nil
+}
+
+func columnNameOf(column interface{}) string {
+	switch c := column.(type) {
+	case clause.Column:
+		return c.Name
+	case string:
+		return c
+	}
+	return ""
+}
+
+// valuesOf expands slices bound to IN conditions.
+func valuesOf(value interface{}) []interface{} {
+	v := reflect.ValueOf(value)
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = v.Index(i).Interface()
+		}
+		return values
+	}
+	return []interface{}{value}
+}
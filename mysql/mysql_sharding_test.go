@@ -0,0 +1,213 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type shardedOrder struct {
+	ID     uint64
+	UserID uint64
+	Amount float64
+}
+
+func (shardedOrder) TableName() string { return "orders" }
+
+func newShardedDB(t *testing.T) *gorm.DB {
+	db := newDryRunDB(t)
+	mod, err := ModShards(16)
+	assert.NoError(t, err)
+	err = db.Use(NewSharding(ShardRule{Table: "orders", Key: "user_id", Algorithm: mod}))
+	assert.NoError(t, err)
+	return db.Session(&gorm.Session{SkipDefaultTransaction: true})
+}
+
+func TestSharding_Query(t *testing.T) {
+	db := newShardedDB(t)
+
+	grid := []struct {
+		name     string
+		query    func(db *gorm.DB) *gorm.DB
+		expected string
+		err      error
+	}{
+		{
+			name: "string condition",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where("status = ? AND user_id = ?", 1, 19).Find(&[]shardedOrder{})
+			},
+			expected: "SELECT * FROM `orders_03` WHERE status = ? AND user_id = ?",
+		},
+		{
+			name:     "struct condition",
+			query:    func(db *gorm.DB) *gorm.DB { return db.Where(&shardedOrder{UserID: 5}).First(&shardedOrder{}) },
+			expected: "SELECT * FROM `orders_05` WHERE `orders_05`.`user_id` = ? ORDER BY `orders_05`.`id` LIMIT 1",
+		},
+		{
+			name:     "in one shard",
+			query:    func(db *gorm.DB) *gorm.DB { return db.Find(&[]shardedOrder{}, "user_id IN ?", []uint64{2, 18}) },
+			expected: "SELECT * FROM `orders_02` WHERE user_id IN (?,?)",
+		},
+		{
+			name:  "in several shards",
+			query: func(db *gorm.DB) *gorm.DB { return db.Find(&[]shardedOrder{}, "user_id IN ?", []uint64{2, 3}) },
+			err:   ErrCrossShard,
+		},
+		{
+			name: "allowed in several shards",
+			query: func(db *gorm.DB) *gorm.DB {
+				return AllowCrossShard(db).Find(&[]shardedOrder{}, "user_id IN ?", []uint64{2, 3})
+			},
+			expected: "SELECT * FROM (SELECT * FROM `orders_02` UNION ALL SELECT * FROM `orders_03`) AS `orders` WHERE user_id IN (?,?)",
+		},
+		{
+			name:  "without key",
+			query: func(db *gorm.DB) *gorm.DB { return db.Find(&[]shardedOrder{}, "amount > ?", 10) },
+			err:   ErrCrossShard,
+		},
+		{
+			name: "or condition",
+			query: func(db *gorm.DB) *gorm.DB {
+				return db.Where("user_id = ?", 1).Or("amount > ?", 10).Find(&[]shardedOrder{})
+			},
+			err: ErrCrossShard,
+		},
+		{
+			name:     "not sharded",
+			query:    func(db *gorm.DB) *gorm.DB { return db.Find(&[]user{}) },
+			expected: "SELECT * FROM `users`",
+		},
+	}
+
+	for _, g := range grid {
+		tx := g.query(db)
+		if g.err != nil {
+			assert.ErrorIs(t, tx.Error, g.err, g.name)
+			continue
+		}
+		assert.NoError(t, tx.Error, g.name)
+		assert.Equal(t, g.expected, tx.Statement.SQL.String(), g.name)
+	}
+
+	tx := AllowCrossShard(db).Count(new(int64))
+	assert.Error(t, tx.Error)
+	tx = AllowCrossShard(db).Model(&shardedOrder{}).Count(new(int64))
+	assert.NoError(t, tx.Error)
+	assert.Contains(t, tx.Statement.SQL.String(), "SELECT count(*) FROM (SELECT * FROM `orders_00` UNION ALL")
+	assert.Contains(t, tx.Statement.SQL.String(), "SELECT * FROM `orders_15`) AS `orders`")
+}
+
+func TestSharding_Write(t *testing.T) {
+	db := newShardedDB(t)
+
+	tx := db.Create(&shardedOrder{UserID: 19, Amount: 9.5})
+	assert.NoError(t, tx.Error)
+	assert.Equal(t, "INSERT INTO `orders_03` (`user_id`,`amount`) VALUES (?,?)", tx.Statement.SQL.String())
+
+	tx = db.Create(&[]shardedOrder{{UserID: 3}, {UserID: 19}})
+	assert.NoError(t, tx.Error)
+	assert.Equal(t, "INSERT INTO `orders_03` (`user_id`,`amount`) VALUES (?,?),(?,?)", tx.Statement.SQL.String())
+
+	tx = db.Create(&[]shardedOrder{{UserID: 3}, {UserID: 4}})
+	assert.ErrorIs(t, tx.Error, ErrCrossShard)
+
+	tx = db.Model(&shardedOrder{ID: 1, UserID: 20}).Update("amount", 3)
+	assert.NoError(t, tx.Error)
+	assert.Equal(t, "UPDATE `orders_04` SET `amount`=? WHERE `id` = ?", tx.Statement.SQL.String())
+
+	tx = db.Where("user_id = ?", 7).Delete(&shardedOrder{})
+	assert.NoError(t, tx.Error)
+	assert.Equal(t, "DELETE FROM `orders_07` WHERE user_id = ?", tx.Statement.SQL.String())
+
+	tx = AllowCrossShard(db).Where("amount = ?", 0).Delete(&shardedOrder{})
+	assert.ErrorIs(t, tx.Error, ErrCrossShard)
+}
+
+func TestShardAlgorithms(t *testing.T) {
+	mod, err := ModShards(4)
+	assert.NoError(t, err)
+	shard, err := mod.Shard(int64(-7))
+	assert.NoError(t, err)
+	assert.Equal(t, "3", shard)
+	_, err = mod.Shard("7")
+	assert.ErrorIs(t, err, ErrShardKey)
+	assert.Equal(t, []string{"0", "1", "2", "3"}, mod.Shards())
+
+	hash, err := HashShards(8)
+	assert.NoError(t, err)
+	first, err := hash.Shard("tom")
+	assert.NoError(t, err)
+	second, err := hash.Shard("tom")
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Contains(t, hash.Shards(), first)
+
+	from := time.Date(2021, 11, 15, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 1, 31, 0, 0, 0, 0, time.UTC)
+	date := DateShards(DatePeriodOfMonth, from, to)
+	assert.Equal(t, []string{"202111", "202112", "202201"}, date.Shards())
+
+	shard, err = date.Shard(time.Date(2021, 11, 1, 8, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, "202111", shard)
+	_, err = date.Shard(time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC))
+	assert.ErrorIs(t, err, ErrShardKey)
+
+	// The period of to is sharded as a whole.
+	date = DateShards(DatePeriodOfMonth, from, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC))
+	shard, err = date.Shard(time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, "202403", shard)
+	_, err = date.Shard(time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC))
+	assert.ErrorIs(t, err, ErrShardKey)
+
+	for _, n := range []int{0, -1} {
+		_, err = ModShards(n)
+		assert.Error(t, err)
+		_, err = HashShards(n)
+		assert.Error(t, err)
+	}
+
+	assert.Equal(t, []string{"2021", "2022"}, DateShards(DatePeriodOfYear, from, to).Shards())
+	assert.Len(t, DateShards(DatePeriodOfDay, from, to).Shards(), 78)
+}
+
+func TestSharding_ShardDDL(t *testing.T) {
+	mod, err := ModShards(3)
+	assert.NoError(t, err)
+	sharding := NewSharding(ShardRule{Table: "orders", Key: "user_id", Algorithm: mod})
+
+	statements, err := sharding.ShardDDL("orders")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS `orders_0` LIKE `orders`",
+		"CREATE TABLE IF NOT EXISTS `orders_1` LIKE `orders`",
+		"CREATE TABLE IF NOT EXISTS `orders_2` LIKE `orders`",
+	}, statements)
+
+	_, err = sharding.ShardDDL("users")
+	assert.Error(t, err)
+
+	db := newFakeDB(t)
+	fake.reset()
+	assert.NoError(t, sharding.CreateShards(context.Background(), db, "orders"))
+	assert.Equal(t, statements, queriesOf(fake.recorded()))
+}
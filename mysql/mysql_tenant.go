@@ -0,0 +1,90 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+
+	"github.com/coolstina/connecter/tenant"
+	"gorm.io/gorm"
+)
+
+// TenantResolver resolves the connection config of a tenant.
+type TenantResolver interface {
+	Resolve(ctx context.Context, id string) (*Config, error)
+}
+
+// TenantResolverFunc is an adapter to allow the use of ordinary
+// functions as TenantResolver.
+type TenantResolverFunc func(ctx context.Context, id string) (*Config, error)
+
+// Resolve calls f(ctx, id).
+func (f TenantResolverFunc) Resolve(ctx context.Context, id string) (*Config, error) {
+	return f(ctx, id)
+}
+
+// TenantRouter routes each request to the database of the tenant
+// carried by its context, using one database per tenant.
+type TenantRouter struct {
+	cache *tenant.Cache
+}
+
+// NewTenantRouter initialize tenant router instance. Connections are
+// opened with NewConnection on first use, so the tenant database is
+// created if not exists, and cached according to the cache options.
+func NewTenantRouter(resolver TenantResolver, cache []tenant.Option, ops ...Option) *TenantRouter {
+	open := func(ctx context.Context, id string) (interface{}, func() error, error) {
+		config, err := resolver.Resolve(ctx, id)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		db, err := NewConnection(config, ops...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return db, func() error { return Close(db) }, nil
+	}
+
+	return &TenantRouter{cache: tenant.NewCache(open, cache...)}
+}
+
+// DB returns the connection of the tenant carried by ctx, bound to ctx,
+// and the function releasing it once no longer used. Evicted
+// connections are closed after their last release.
+func (r *TenantRouter) DB(ctx context.Context) (*gorm.DB, func(), error) {
+	id, err := tenant.MustFromContext(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conn, release, err := r.cache.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return conn.(*gorm.DB).WithContext(ctx), release, nil
+}
+
+// Evict closes the connection of the tenant if cached.
+func (r *TenantRouter) Evict(id string) error {
+	return r.cache.Evict(id)
+}
+
+// Close closes every tenant connection.
+func (r *TenantRouter) Close() error {
+	return r.cache.Close()
+}
@@ -16,8 +16,12 @@ package mysql
 
 import (
 	"testing"
+	"time"
 
+	driver "github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
 )
 
 var def = &Config{
@@ -33,18 +37,63 @@ var def = &Config{
 	DriverName:            "mysql",
 }
 
+// newDryRunDB opens a gorm db that builds statements without executing them.
+func newDryRunDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		DSN:                       NewDataSourceName(def.Host, def.Username, def.Password, def.Database),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	assert.NoError(t, err)
+	return db
+}
+
 func TestNewDataSourceName_NoneSelectDatabase(t *testing.T) {
-	expected := `root:root@tcp(127.0.0.1)/?charset=utf8mb4&parseTime=true&loc=Local`
+	expected := `root:root@tcp(127.0.0.1:3306)/?loc=Local&parseTime=true&charset=utf8mb4`
 	actual := NewDataSourceName(def.Host, def.Username, def.Password, "")
 	assert.Equal(t, expected, actual)
 }
 
 func TestNewDataSourceName_SelectDatabase(t *testing.T) {
-	expected := `root:root@tcp(127.0.0.1)/mysql?charset=utf8mb4&parseTime=true&loc=Local`
+	expected := `root:root@tcp(127.0.0.1:3306)/mysql?loc=Local&parseTime=true&charset=utf8mb4`
 	actual := NewDataSourceName(def.Host, def.Username, def.Password, "mysql")
 	assert.Equal(t, expected, actual)
 }
 
+func TestNewDataSourceName_EscapePassword(t *testing.T) {
+	password := "p@ss:w/rd"
+	dsn := NewDataSourceName("db.example.com", "root", password, "mysql",
+		WithLocation("Asia/Shanghai"),
+	)
+
+	config, err := driver.ParseDSN(dsn)
+	assert.NoError(t, err)
+	assert.Equal(t, "root", config.User)
+	assert.Equal(t, password, config.Passwd)
+	assert.Equal(t, "db.example.com:3306", config.Addr)
+	assert.Equal(t, "mysql", config.DBName)
+	assert.Equal(t, "Asia/Shanghai", config.Loc.String())
+}
+
+func TestNewDataSourceName_Options(t *testing.T) {
+	expected := `root:root@unix(/var/run/mysqld/mysqld.sock)/mysql?allowNativePasswords=false&collation=utf8mb4_unicode_ci&interpolateParams=true&multiStatements=true&readTimeout=3s&timeout=5s&tls=skip-verify&writeTimeout=3s&maxAllowedPacket=0&charset=utf8mb4&time_zone=%27%2B00%3A00%27`
+	actual := NewDataSourceName(def.Host, def.Username, def.Password, "mysql",
+		WithParseTime(false),
+		WithLocation("UTC"),
+		WithCollation("utf8mb4_unicode_ci"),
+		WithTimeout(5*time.Second),
+		WithReadTimeout(3*time.Second),
+		WithWriteTimeout(3*time.Second),
+		WithTLS("skip-verify"),
+		WithInterpolateParams(true),
+		WithMultiStatements(true),
+		WithMaxAllowedPacket(0),
+		WithAllowNativePasswords(false),
+		WithUnixSocket("/var/run/mysqld/mysqld.sock"),
+		WithParam("time_zone", "'+00:00'"),
+	)
+	assert.Equal(t, expected, actual)
+}
+
 func TestCreateDatabaseIfNotExists(t *testing.T) {
 	dsn := NewDataSourceName(def.Host, def.Username, def.Password, "")
 	assert.NotEmpty(t, dsn)
@@ -0,0 +1,202 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// txContext defines the transaction carried by a context, shared by
+// the nested calls on the same database. The transactions of other
+// databases are chained through parent.
+type txContext struct {
+	tx     *gorm.DB
+	pool   gorm.ConnPool
+	depth  *int
+	hooks  *[]func(ctx context.Context)
+	parent *txContext
+}
+
+// lookup returns the transaction of ctx on the database, db being the
+// one given to WithTx or the transaction itself.
+func (t *txContext) lookup(db *gorm.DB) (*txContext, bool) {
+	pool := connPool(db)
+	for ; t != nil; t = t.parent {
+		if t.pool == pool || connPool(t.tx) == pool {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// connPool returns the connection pool of the database.
+func connPool(db *gorm.DB) gorm.ConnPool {
+	if db.Statement != nil && db.Statement.ConnPool != nil {
+		return db.Statement.ConnPool
+	}
+	return db.ConnPool
+}
+
+// TxFromContext returns the transaction of the innermost WithTx call.
+func TxFromContext(ctx context.Context) (*gorm.DB, bool) {
+	t, ok := ctx.Value(txKey{}).(*txContext)
+	if !ok {
+		return nil, false
+	}
+	return t.tx, true
+}
+
+// AfterCommit registers fn to run once the transaction of ctx commits,
+// it is dropped if the transaction rolls back. Without transaction,
+// fn runs right away.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	t, ok := ctx.Value(txKey{}).(*txContext)
+	if !ok {
+		fn(ctx)
+		return
+	}
+	*t.hooks = append(*t.hooks, fn)
+}
+
+// WithTx runs fn in a transaction committed if fn returns no error and
+// rolled back otherwise. Deadlocks and lock wait timeouts roll back the
+// whole transaction, fn is retried from the start with backoff then.
+//
+// The transaction is carried by the context fn receives, calling WithTx
+// with it on the same database runs fn in a savepoint of the same
+// transaction instead, the options of the nested calls are ignored.
+// A nested call on another database runs its own transaction.
+func WithTx(ctx context.Context, db *gorm.DB, fn func(ctx context.Context, tx *gorm.DB) error, ops ...TxOption) error {
+	outer, _ := ctx.Value(txKey{}).(*txContext)
+	if t, ok := outer.lookup(db); ok {
+		return savepoint(ctx, t, fn)
+	}
+
+	options := &txOptions{
+		retries:    3,
+		minBackoff: 10 * time.Millisecond,
+		maxBackoff: time.Second,
+	}
+
+	for _, o := range ops {
+		o.apply(options)
+	}
+
+	for attempt := 0; ; attempt++ {
+		hooks, err := transaction(ctx, db, fn, outer, options)
+		if err == nil {
+			for _, hook := range hooks {
+				hook(ctx)
+			}
+			return nil
+		}
+
+		if attempt >= options.retries || !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(options.backoff(attempt)):
+		}
+	}
+}
+
+// transaction runs a single attempt, returning the after commit hooks.
+func transaction(ctx context.Context, db *gorm.DB, fn func(ctx context.Context, tx *gorm.DB) error, outer *txContext, options *txOptions) (hooks []func(ctx context.Context), err error) {
+	tx := db.WithContext(ctx).Begin(&sql.TxOptions{
+		Isolation: options.isolation,
+		ReadOnly:  options.readOnly,
+	})
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	depth := 0
+	t := &txContext{tx: tx, pool: connPool(db), depth: &depth, hooks: &hooks, parent: outer}
+	if err := fn(context.WithValue(ctx, txKey{}, t), tx); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+	committed = true
+
+	return hooks, nil
+}
+
+// savepoint runs a nested call, its hooks are kept only if it succeeds.
+func savepoint(ctx context.Context, t *txContext, fn func(ctx context.Context, tx *gorm.DB) error) (err error) {
+	*t.depth++
+	defer func() { *t.depth-- }()
+
+	name := fmt.Sprintf("connecter_sp%d", *t.depth)
+	if err := t.tx.SavePoint(name).Error; err != nil {
+		return err
+	}
+
+	hooks := len(*t.hooks)
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			*t.hooks = (*t.hooks)[:hooks]
+			// A failed rollback leaves the error of fn to the caller,
+			// the outer transaction rolls back as a whole then.
+			t.tx.RollbackTo(name)
+		}
+	}()
+
+	err = fn(ctx, t.tx)
+	panicked = false
+
+	return err
+}
+
+// isRetryable tells if the error rolled back the transaction and a new
+// attempt may succeed.
+func isRetryable(err error) bool {
+	return IsDeadlock(err) || IsLockWaitTimeout(err)
+}
+
+// backoff returns the delay before the retry of attempt, doubling from
+// the min backoff up to the max backoff, with jitter.
+func (o *txOptions) backoff(attempt int) time.Duration {
+	delay := o.minBackoff << uint(attempt)
+	if delay > o.maxBackoff || delay <= 0 {
+		delay = o.maxBackoff
+	}
+
+	if half := int64(delay / 2); half > 0 {
+		return time.Duration(half + rand.Int63n(half))
+	}
+	return delay
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"database/sql"
+	"time"
+)
+
+type TxOption interface {
+	apply(*txOptions)
+}
+
+type txOptionFunc func(ops *txOptions)
+
+func (o txOptionFunc) apply(ops *txOptions) {
+	o(ops)
+}
+
+type txOptions struct {
+	isolation  sql.IsolationLevel
+	readOnly   bool
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// WithIsolation Specifies the isolation level of the transaction.
+// Default is the server's, REPEATABLE READ unless configured.
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return txOptionFunc(func(ops *txOptions) {
+		ops.isolation = level
+	})
+}
+
+// WithReadOnly Specifies whether the transaction is read-only.
+func WithReadOnly(readOnly bool) TxOption {
+	return txOptionFunc(func(ops *txOptions) {
+		ops.readOnly = readOnly
+	})
+}
+
+// WithRetries Specifies how many times the transaction is retried on
+// deadlocks and lock wait timeouts, 0 disables retries. Default is 3.
+func WithRetries(retries int) TxOption {
+	return txOptionFunc(func(ops *txOptions) {
+		ops.retries = retries
+	})
+}
+
+// WithBackoff Specifies the delay before the first retry, doubled for
+// each retry up to max. Default is 10ms up to 1s.
+func WithBackoff(min, max time.Duration) TxOption {
+	return txOptionFunc(func(ops *txOptions) {
+		ops.minBackoff = min
+		ops.maxBackoff = max
+	})
+}
@@ -0,0 +1,206 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// failingHandler fails query with err the first n times.
+func failingHandler(query string, n int, err error) fakeHandler {
+	return func(q string, args []interface{}) (driver.Rows, error) {
+		if q == query && n != 0 {
+			n--
+			return nil, err
+		}
+		return nil, nil
+	}
+}
+
+func TestWithTx_Commit(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+
+	ctx := context.Background()
+	var committed bool
+	err := WithTx(ctx, db, func(ctx context.Context, tx *gorm.DB) error {
+		current, ok := TxFromContext(ctx)
+		assert.True(t, ok)
+		assert.Equal(t, tx, current)
+
+		AfterCommit(ctx, func(ctx context.Context) {
+			assert.Equal(t, "COMMIT", queriesOf(fake.recorded())[3])
+			committed = true
+		})
+
+		return tx.Exec("UPDATE users SET name = ?", "tom").Error
+	}, WithIsolation(sql.LevelReadCommitted), WithReadOnly(true))
+
+	assert.NoError(t, err)
+	assert.True(t, committed)
+	assert.Equal(t, []string{
+		"SET TRANSACTION ISOLATION LEVEL READ COMMITTED",
+		"START TRANSACTION READ ONLY",
+		"UPDATE users SET name = ?",
+		"COMMIT",
+	}, queriesOf(fake.recorded()))
+
+	_, ok := TxFromContext(ctx)
+	assert.False(t, ok)
+}
+
+func TestWithTx_Rollback(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+
+	expected := errors.New("failed")
+	err := WithTx(context.Background(), db, func(ctx context.Context, tx *gorm.DB) error {
+		AfterCommit(ctx, func(ctx context.Context) {
+			t.Error("hook of a rolled back transaction ran")
+		})
+		return expected
+	})
+
+	assert.Equal(t, expected, err)
+	assert.Equal(t, []string{"START TRANSACTION", "ROLLBACK"}, queriesOf(fake.recorded()))
+
+	fake.reset()
+	assert.Panics(t, func() {
+		_ = WithTx(context.Background(), db, func(ctx context.Context, tx *gorm.DB) error {
+			panic("boom")
+		})
+	})
+	assert.Equal(t, []string{"START TRANSACTION", "ROLLBACK"}, queriesOf(fake.recorded()))
+}
+
+func TestWithTx_Retry(t *testing.T) {
+	db := newFakeDB(t)
+	grid := []struct {
+		name     string
+		failures int
+		err      error
+		retries  int
+		attempts int
+		failed   bool
+	}{
+		{name: "deadlock", failures: 1, err: &mysqldriver.MySQLError{Number: 1213}, retries: 3, attempts: 2},
+		{name: "lock wait timeout", failures: 2, err: &mysqldriver.MySQLError{Number: 1205}, retries: 3, attempts: 3},
+		{name: "exhausted", failures: -1, err: &mysqldriver.MySQLError{Number: 1213}, retries: 2, attempts: 3, failed: true},
+		{name: "disabled", failures: -1, err: &mysqldriver.MySQLError{Number: 1213}, retries: 0, attempts: 1, failed: true},
+		{name: "duplicate key", failures: 1, err: &mysqldriver.MySQLError{Number: 1062}, retries: 3, attempts: 1, failed: true},
+	}
+
+	for _, g := range grid {
+		fake.reset()
+		fake.handle(failingHandler("UPDATE users SET name = ?", g.failures, g.err))
+
+		attempts := 0
+		err := WithTx(context.Background(), db, func(ctx context.Context, tx *gorm.DB) error {
+			attempts++
+			return tx.Exec("UPDATE users SET name = ?", "tom").Error
+		}, WithRetries(g.retries), WithBackoff(time.Millisecond, time.Millisecond))
+
+		assert.Equal(t, g.attempts, attempts, g.name)
+		if g.failed {
+			assert.ErrorIs(t, err, g.err, g.name)
+		} else {
+			assert.NoError(t, err, g.name)
+		}
+	}
+}
+
+func TestWithTx_Nested(t *testing.T) {
+	db := newFakeDB(t)
+	fake.reset()
+
+	var hooks []string
+	err := WithTx(context.Background(), db, func(ctx context.Context, tx *gorm.DB) error {
+		err := WithTx(ctx, db, func(ctx context.Context, tx *gorm.DB) error {
+			AfterCommit(ctx, func(ctx context.Context) { hooks = append(hooks, "failed") })
+			return errors.New("failed")
+		})
+		assert.Error(t, err)
+
+		return WithTx(ctx, db, func(ctx context.Context, tx *gorm.DB) error {
+			AfterCommit(ctx, func(ctx context.Context) { hooks = append(hooks, "nested") })
+			return tx.Exec("DELETE FROM users").Error
+		})
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nested"}, hooks)
+	assert.Equal(t, []string{
+		"START TRANSACTION",
+		"SAVEPOINT connecter_sp1",
+		"ROLLBACK TO SAVEPOINT connecter_sp1",
+		"SAVEPOINT connecter_sp1",
+		"DELETE FROM users",
+		"COMMIT",
+	}, queriesOf(fake.recorded()))
+}
+
+func TestWithTx_OtherDatabase(t *testing.T) {
+	db, other := newFakeDB(t), newFakeDB(t)
+	fake.reset()
+
+	err := WithTx(context.Background(), db, func(ctx context.Context, tx *gorm.DB) error {
+		err := WithTx(ctx, other, func(ctx context.Context, otherTx *gorm.DB) error {
+			current, _ := TxFromContext(ctx)
+			assert.Equal(t, otherTx, current)
+			return otherTx.Exec("DELETE FROM orders").Error
+		})
+		assert.NoError(t, err)
+
+		return WithTx(ctx, tx, func(ctx context.Context, nested *gorm.DB) error {
+			assert.Equal(t, tx, nested)
+			return nested.Exec("DELETE FROM users").Error
+		})
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"START TRANSACTION",
+		"START TRANSACTION",
+		"DELETE FROM orders",
+		"COMMIT",
+		"SAVEPOINT connecter_sp1",
+		"DELETE FROM users",
+		"COMMIT",
+	}, queriesOf(fake.recorded()))
+}
+
+func TestAfterCommit_NoTx(t *testing.T) {
+	ran := false
+	AfterCommit(context.Background(), func(ctx context.Context) { ran = true })
+	assert.True(t, ran)
+}
+
+func TestTxOptions_Backoff(t *testing.T) {
+	options := &txOptions{minBackoff: 10 * time.Millisecond, maxBackoff: 50 * time.Millisecond}
+	for attempt, max := range []time.Duration{10, 20, 40, 50, 50} {
+		delay := options.backoff(attempt)
+		assert.True(t, delay >= max*time.Millisecond/2 && delay < max*time.Millisecond, "attempt %d: %v", attempt, delay)
+	}
+	assert.GreaterOrEqual(t, options.backoff(80), 25*time.Millisecond)
+}
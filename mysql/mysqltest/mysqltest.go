@@ -0,0 +1,155 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mysqltest scopes the gorm db of tests to transactions rolled
+// back once the tests complete.
+package mysqltest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/coolstina/connecter/mysql"
+	"gorm.io/gorm"
+)
+
+var _ mysql.PinnedPool = (*testPool)(nil)
+
+// NewDB returns db scoped to a transaction rolled back when the test
+// and its subtests complete, so tests leave no rows behind. Passing the
+// scoped db of a test scopes a subtest to a savepoint of its transaction
+// instead, such subtests must not run in parallel. The tests calling it
+// with the db of NewConnection run in parallel, each in its own
+// transaction.
+//
+// The transactions the code under test begins on the scoped db, with
+// db.Transaction, db.Begin or mysql.WithTx, are savepoints of the test
+// transaction committed by releasing them. Reads on the scoped db are
+// never routed to replicas.
+func NewDB(tb testing.TB, db *gorm.DB) *gorm.DB {
+	tb.Helper()
+
+	pool, err := newTestPool(db)
+	if err != nil {
+		tb.Fatalf("mysql: begin test transaction: %v", err)
+	}
+
+	tb.Cleanup(func() {
+		if err := pool.rollback(); err != nil {
+			tb.Errorf("mysql: roll back test transaction: %v", err)
+		}
+	})
+
+	scoped := db.Session(&gorm.Session{NewDB: true, Context: context.Background()})
+	scoped.Statement.ConnPool = pool
+	return scoped
+}
+
+func newTestPool(db *gorm.DB) (*testPool, error) {
+	if parent, ok := db.Statement.ConnPool.(*testPool); ok {
+		return parent.savepoint()
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := sqlDB.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &testPool{tx: &testTx{Tx: tx}}, nil
+}
+
+// testTx defines the transaction of a test, shared by the savepoints.
+type testTx struct {
+	*sql.Tx
+	mu         sync.Mutex
+	savepoints int
+}
+
+// testPool defines the scope of a test, the whole transaction or a
+// savepoint of it when name is set.
+type testPool struct {
+	tx   *testTx
+	name string
+}
+
+// Pinned implements mysql.PinnedPool.
+func (p *testPool) Pinned() {}
+
+func (p *testPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.tx.PrepareContext(ctx, query)
+}
+
+func (p *testPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.tx.ExecContext(ctx, query, args...)
+}
+
+func (p *testPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.tx.QueryContext(ctx, query, args...)
+}
+
+func (p *testPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.tx.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx begins the transactions of the code under test as savepoints,
+// the options cannot apply inside the test transaction.
+func (p *testPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	child, err := p.savepoint()
+	if err != nil {
+		return nil, err
+	}
+	return &testSavepoint{testPool: child}, nil
+}
+
+func (p *testPool) savepoint() (*testPool, error) {
+	p.tx.mu.Lock()
+	p.tx.savepoints++
+	name := fmt.Sprintf("connecter_test_sp%d", p.tx.savepoints)
+	p.tx.mu.Unlock()
+
+	if _, err := p.tx.Exec("SAVEPOINT " + name); err != nil {
+		return nil, err
+	}
+	return &testPool{tx: p.tx, name: name}, nil
+}
+
+func (p *testPool) rollback() error {
+	if p.name == "" {
+		return p.tx.Rollback()
+	}
+	_, err := p.tx.Exec("ROLLBACK TO SAVEPOINT " + p.name)
+	return err
+}
+
+// testSavepoint defines a transaction of the code under test.
+type testSavepoint struct {
+	*testPool
+}
+
+func (s *testSavepoint) Commit() error {
+	_, err := s.tx.Exec("RELEASE SAVEPOINT " + s.name)
+	return err
+}
+
+func (s *testSavepoint) Rollback() error {
+	return s.rollback()
+}
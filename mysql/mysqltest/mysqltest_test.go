@@ -0,0 +1,189 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mysqltest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/coolstina/connecter"
+	"github.com/coolstina/connecter/mysql"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+const fakeDriverName = "connecter_mysqltest_fake"
+
+var fake = &fakeDriver{}
+
+func init() {
+	sql.Register(fakeDriverName, fake)
+}
+
+// fakeDriver records every statement instead of talking to a server.
+type fakeDriver struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func (d *fakeDriver) Open(dsn string) (driver.Conn, error) {
+	return &fakeConn{driver: d}, nil
+}
+
+func (d *fakeDriver) record(query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, query)
+}
+
+func (d *fakeDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = nil
+}
+
+func (d *fakeDriver) recorded() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]string(nil), d.queries...)
+}
+
+type fakeConn struct {
+	driver *fakeDriver
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fake: prepare is not supported")
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	c.driver.record("START TRANSACTION")
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.driver.record("COMMIT")
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.driver.record("ROLLBACK")
+	return nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.driver.record(query)
+	return fakeResult{}, nil
+}
+
+type fakeResult struct{}
+
+func (fakeResult) LastInsertId() (int64, error) { return 0, nil }
+
+func (fakeResult) RowsAffected() (int64, error) { return 1, nil }
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.driver.record(query)
+	if strings.EqualFold(query, "SELECT VERSION()") {
+		return &fakeRows{columns: []string{"VERSION()"}, values: [][]driver.Value{{"8.0.27"}}}, nil
+	}
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+type user struct {
+	ID   uint64
+	Name string
+}
+
+func TestNewDB(t *testing.T) {
+	db, err := mysql.NewConnection(&mysql.Config{
+		Database:           "connecter",
+		DriverName:         connecter.DriverName(fakeDriverName),
+		SkipCreateDatabase: true,
+	})
+	assert.NoError(t, err)
+	defer mysql.Close(db)
+	fake.reset()
+
+	t.Run("test", func(t *testing.T) {
+		scoped := NewDB(t, db)
+		assert.NoError(t, scoped.Exec("DELETE FROM users").Error)
+
+		// Transactions of the code under test are savepoints.
+		err := scoped.Transaction(func(tx *gorm.DB) error {
+			return tx.Exec("UPDATE users SET name = ?", "tom").Error
+		})
+		assert.NoError(t, err)
+
+		err = mysql.WithTx(context.Background(), scoped, func(ctx context.Context, tx *gorm.DB) error {
+			return errors.New("failed")
+		})
+		assert.Error(t, err)
+
+		assert.NoError(t, scoped.Create(&user{ID: 1, Name: "tom"}).Error)
+
+		t.Run("subtest", func(t *testing.T) {
+			nested := NewDB(t, scoped)
+			assert.NoError(t, nested.Exec("DELETE FROM orders").Error)
+		})
+	})
+
+	assert.Equal(t, []string{
+		"START TRANSACTION",
+		"DELETE FROM users",
+		"SAVEPOINT connecter_test_sp1",
+		"UPDATE users SET name = ?",
+		"RELEASE SAVEPOINT connecter_test_sp1",
+		"SAVEPOINT connecter_test_sp2",
+		"ROLLBACK TO SAVEPOINT connecter_test_sp2",
+		"SAVEPOINT connecter_test_sp3",
+		"INSERT INTO `users` (`name`,`id`) VALUES (?,?)",
+		"RELEASE SAVEPOINT connecter_test_sp3",
+		"SAVEPOINT connecter_test_sp4",
+		"DELETE FROM orders",
+		"ROLLBACK TO SAVEPOINT connecter_test_sp4",
+		"ROLLBACK",
+	}, fake.recorded())
+}
@@ -0,0 +1,293 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// Class defines the operation class a token is taken for.
+type Class string
+
+func (c Class) String() string {
+	return string(c)
+}
+
+const (
+	ClassOfRead  Class = "read"
+	ClassOfWrite Class = "write"
+	ClassOfBulk  Class = "bulk"
+)
+
+// Mode defines how Take behaves when no token is available.
+type Mode int
+
+const (
+	// ModeOfBlock waits for the next token.
+	ModeOfBlock Mode = iota
+	// ModeOfFailFast returns ErrLimited immediately.
+	ModeOfFailFast
+)
+
+// ErrLimited is returned in fail fast mode when no token is available.
+var ErrLimited = errors.New("ratelimit: rate limit exceeded")
+
+// Stats defines the counters of one operation class.
+type Stats struct {
+	// Allowed is the number of calls that got a token.
+	Allowed uint64
+	// Throttled is the number of allowed calls that had to wait.
+	Throttled uint64
+	// Rejected is the number of calls that failed fast or whose
+	// context was done while waiting.
+	Rejected uint64
+	// Waited is the total time spent waiting.
+	Waited time.Duration
+}
+
+// Limiter is a token bucket limiter per operation class.
+// A nil Limiter allows everything.
+type Limiter struct {
+	mu      sync.Mutex
+	buckets map[Class]*bucket
+	stats   map[Class]*Stats
+	options *options
+}
+
+// New initialize limiter instance with the given options.
+func New(ops ...Option) *Limiter {
+	opts := &options{
+		mode:  ModeOfBlock,
+		rates: make(map[Class]rate),
+		now:   time.Now,
+	}
+
+	for _, o := range ops {
+		o.apply(opts)
+	}
+
+	limiter := &Limiter{
+		buckets: make(map[Class]*bucket),
+		stats:   make(map[Class]*Stats),
+		options: opts,
+	}
+
+	for class, r := range opts.rates {
+		limiter.buckets[class] = newBucket(r, opts.now())
+	}
+
+	return limiter
+}
+
+// Mode returns the configured mode.
+func (l *Limiter) Mode() Mode {
+	if l == nil {
+		return ModeOfBlock
+	}
+	return l.options.mode
+}
+
+// Take takes a token for the class according to the configured mode.
+func (l *Limiter) Take(ctx context.Context, class Class) error {
+	if l.Mode() == ModeOfFailFast {
+		if !l.Allow(class) {
+			return ErrLimited
+		}
+		return nil
+	}
+	return l.Wait(ctx, class)
+}
+
+// Allow takes a token for the class if one is available now.
+func (l *Limiter) Allow(class Class) bool {
+	b := l.bucket(class)
+	if b == nil {
+		return true
+	}
+
+	allowed := b.allow(l.options.now())
+	l.record(class, func(stats *Stats) {
+		if allowed {
+			stats.Allowed++
+		} else {
+			stats.Rejected++
+		}
+	})
+
+	if !allowed {
+		l.observe(class, 0, ErrLimited)
+	}
+
+	return allowed
+}
+
+// Wait blocks until a token for the class is available or ctx is done.
+func (l *Limiter) Wait(ctx context.Context, class Class) error {
+	b := l.bucket(class)
+	if b == nil {
+		return nil
+	}
+
+	wait := b.reserve(l.options.now())
+	if wait <= 0 {
+		l.record(class, func(stats *Stats) { stats.Allowed++ })
+		return nil
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		l.record(class, func(stats *Stats) {
+			stats.Allowed++
+			stats.Throttled++
+			stats.Waited += wait
+		})
+		l.observe(class, wait, nil)
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		l.record(class, func(stats *Stats) { stats.Rejected++ })
+		l.observe(class, wait, ctx.Err())
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the counters per class.
+func (l *Limiter) Stats() map[Class]Stats {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make(map[Class]Stats, len(l.stats))
+	for class, s := range l.stats {
+		stats[class] = *s
+	}
+	return stats
+}
+
+func (l *Limiter) bucket(class Class) *bucket {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[class]; ok {
+		return b
+	}
+
+	if l.options.fallback == nil {
+		return nil
+	}
+
+	b := newBucket(*l.options.fallback, l.options.now())
+	l.buckets[class] = b
+	return b
+}
+
+func (l *Limiter) record(class Class, fn func(stats *Stats)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats, ok := l.stats[class]
+	if !ok {
+		stats = &Stats{}
+		l.stats[class] = stats
+	}
+	fn(stats)
+}
+
+func (l *Limiter) observe(class Class, waited time.Duration, err error) {
+	if l.options.observer != nil {
+		l.options.observer(class, waited, err)
+	}
+}
+
+type rate struct {
+	perSecond float64
+	burst     int
+}
+
+// bucket is a token bucket whose tokens may go negative,
+// a negative balance is the time the next caller has to wait.
+type bucket struct {
+	mu     sync.Mutex
+	rate   rate
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(r rate, now time.Time) *bucket {
+	if r.burst < 1 {
+		r.burst = 1
+	}
+	return &bucket{rate: r, tokens: float64(r.burst), last: now}
+}
+
+func (b *bucket) advance(now time.Time) {
+	if now.After(b.last) {
+		elapsed := now.Sub(b.last).Seconds()
+		b.tokens = math.Min(float64(b.rate.burst), b.tokens+elapsed*b.rate.perSecond)
+		b.last = now
+	}
+}
+
+func (b *bucket) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+func (b *bucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance(now)
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	if b.rate.perSecond <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(-b.tokens / b.rate.perSecond * float64(time.Second))
+}
+
+func (b *bucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(float64(b.rate.burst), b.tokens+1)
+}
@@ -0,0 +1,75 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import "time"
+
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(ops *options)
+
+func (o optionFunc) apply(ops *options) {
+	o(ops)
+}
+
+type options struct {
+	mode     Mode
+	rates    map[Class]rate
+	fallback *rate
+	now      func() time.Time
+	observer func(class Class, waited time.Duration, err error)
+}
+
+// WithRate Specifies the number of operations per second and the
+// burst size allowed for the class. Classes without a rate are not
+// limited unless WithDefaultRate is specified.
+func WithRate(class Class, perSecond float64, burst int) Option {
+	return optionFunc(func(ops *options) {
+		ops.rates[class] = rate{perSecond: perSecond, burst: burst}
+	})
+}
+
+// WithDefaultRate Specifies the rate of the classes without their own rate.
+func WithDefaultRate(perSecond float64, burst int) Option {
+	return optionFunc(func(ops *options) {
+		ops.fallback = &rate{perSecond: perSecond, burst: burst}
+	})
+}
+
+// WithMode Specifies whether Take blocks or fails fast.
+// Default is ModeOfBlock.
+func WithMode(mode Mode) Option {
+	return optionFunc(func(ops *options) {
+		ops.mode = mode
+	})
+}
+
+// WithObserver Specifies the function invoked for each throttled
+// or rejected call, err is nil when the call was only delayed.
+func WithObserver(observer func(class Class, waited time.Duration, err error)) Option {
+	return optionFunc(func(ops *options) {
+		ops.observer = observer
+	})
+}
+
+// WithClock Specifies the function used to refill buckets.
+// Default is time.Now.
+func WithClock(now func() time.Time) Option {
+	return optionFunc(func(ops *options) {
+		ops.now = now
+	})
+}
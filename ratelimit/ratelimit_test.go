@@ -0,0 +1,85 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_FailFast(t *testing.T) {
+	now := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	limiter := New(
+		WithMode(ModeOfFailFast),
+		WithRate(ClassOfWrite, 1, 2),
+		WithClock(func() time.Time { return now }),
+	)
+
+	ctx := context.Background()
+	assert.NoError(t, limiter.Take(ctx, ClassOfWrite))
+	assert.NoError(t, limiter.Take(ctx, ClassOfWrite))
+	assert.Equal(t, ErrLimited, limiter.Take(ctx, ClassOfWrite))
+
+	// Reads have no rate and are never limited.
+	assert.NoError(t, limiter.Take(ctx, ClassOfRead))
+
+	now = now.Add(time.Second)
+	assert.NoError(t, limiter.Take(ctx, ClassOfWrite))
+
+	stats := limiter.Stats()
+	assert.Equal(t, Stats{Allowed: 3, Rejected: 1}, stats[ClassOfWrite])
+	assert.Equal(t, Stats{}, stats[ClassOfRead])
+}
+
+func TestLimiter_Block(t *testing.T) {
+	var throttled []Class
+	limiter := New(
+		WithDefaultRate(100, 1),
+		WithObserver(func(class Class, waited time.Duration, err error) {
+			assert.NoError(t, err)
+			throttled = append(throttled, class)
+		}),
+	)
+
+	ctx := context.Background()
+	start := time.Now()
+	assert.NoError(t, limiter.Take(ctx, ClassOfBulk))
+	assert.NoError(t, limiter.Take(ctx, ClassOfBulk))
+	assert.True(t, time.Since(start) >= 5*time.Millisecond)
+
+	assert.Equal(t, []Class{ClassOfBulk}, throttled)
+	assert.Equal(t, uint64(2), limiter.Stats()[ClassOfBulk].Allowed)
+	assert.Equal(t, uint64(1), limiter.Stats()[ClassOfBulk].Throttled)
+}
+
+func TestLimiter_Wait_ContextDone(t *testing.T) {
+	limiter := New(WithRate(ClassOfRead, 0.001, 1))
+
+	assert.NoError(t, limiter.Wait(context.Background(), ClassOfRead))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, limiter.Wait(ctx, ClassOfRead))
+	assert.Equal(t, uint64(1), limiter.Stats()[ClassOfRead].Rejected)
+}
+
+func TestLimiter_Nil(t *testing.T) {
+	var limiter *Limiter
+	assert.NoError(t, limiter.Take(context.Background(), ClassOfWrite))
+	assert.Nil(t, limiter.Stats())
+}
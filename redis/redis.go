@@ -15,21 +15,109 @@
 package redis
 
 import (
+	"context"
 	"reflect"
 	"time"
 
 	"github.com/go-redis/redis"
 )
 
-// NewConnection create a new gorm db instance with the given options.
+// hook wraps the processing of a client, the client given is the
+// one whose processing is wrapped and owns the request context.
+type hook func(client *redis.Client)
+
+// Client is a redis client that keeps the hooks installed by options,
+// such as WithAudit, when it is bound to a request context.
+type Client struct {
+	*redis.Client
+
+	// base is the client as it was before the hooks were installed.
+	base   *redis.Client
+	hooks  []hook
+	prefix func(id string) string
+}
+
+// NewConnection create a new redis client instance with the given
+// options. Hooks installed by options, such as WithAudit, are bound
+// to the context of the returned client: a clone made with
+// client.WithContext runs them with the context of the client, not
+// its own, so actors, tenants and deadlines of the clone context are
+// ignored. Use NewClient for clients bound to a request context.
 func NewConnection(config *Config, ops ...Option) (*redis.Client, error) {
+	client, err := NewClient(config, ops...)
+	if err != nil {
+		return nil, err
+	}
+	return client.Client, nil
+}
+
+// NewClient create a new redis client instance with the given options,
+// whose WithContext keeps the hooks installed by options.
+func NewClient(config *Config, ops ...Option) (*Client, error) {
 	configure := configuration(config)
 
 	for _, o := range ops {
 		o.apply(configure)
 	}
 
-	return redis.NewClient(options(configure)), nil
+	client, err := newClient(configure)
+	if err != nil {
+		return nil, err
+	}
+
+	wrapped := &Client{
+		Client: client,
+		base:   client.WithContext(context.Background()),
+		hooks:  configure.hooks,
+		prefix: configure.prefix,
+	}
+	for _, h := range configure.hooks {
+		h(client)
+	}
+
+	if configure.warmUp != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), configure.warmUp.timeout)
+		report := WarmUp(ctx, client, configure.warmUp.connections)
+		cancel()
+
+		if configure.warmUp.report != nil {
+			configure.warmUp.report(report)
+		}
+	}
+
+	return wrapped, nil
+}
+
+// newClient creates a sentinel backed client when a master name is
+// configured and a single node client otherwise.
+func newClient(configure *Config) (*redis.Client, error) {
+	if configure.MasterName == "" {
+		return redis.NewClient(options(configure)), nil
+	}
+
+	addrs := configure.SentinelAddrs
+	if configure.sentinels != nil {
+		hosts, err := configure.sentinels.Resolve(context.TODO())
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(append([]string{}, addrs...), hosts...)
+	}
+
+	return redis.NewFailoverClient(failoverOptions(configure, addrs)), nil
+}
+
+// WithContext returns a shallow copy of the client bound to ctx.
+// Unlike the redis client's WithContext, hooks installed by options
+// are kept, so audit events and limits see the context values.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	// The hooks of the client are bound to its own context, the clone
+	// is made from the client without them.
+	clone := c.base.WithContext(ctx)
+	for _, h := range c.hooks {
+		h(clone)
+	}
+	return &Client{Client: clone, base: c.base, hooks: c.hooks, prefix: c.prefix}
 }
 
 func configuration(config *Config) *Config {
@@ -38,6 +126,8 @@ func configuration(config *Config) *Config {
 		Host:               config.Host,
 		Password:           config.Password,
 		Database:           config.Database,
+		MasterName:         config.MasterName,
+		SentinelAddrs:      config.SentinelAddrs,
 		MinRetryBackoff:    time.Millisecond * 8,
 		MaxRetryBackoff:    time.Millisecond * 512,
 		DialTimeout:        time.Second * 5,
@@ -101,3 +191,26 @@ func options(configure *Config) *redis.Options {
 	}
 	return options
 }
+
+func failoverOptions(configure *Config, addrs []string) *redis.FailoverOptions {
+	opt := options(configure)
+	return &redis.FailoverOptions{
+		MasterName:         configure.MasterName,
+		SentinelAddrs:      addrs,
+		Password:           opt.Password,
+		DB:                 opt.DB,
+		MaxRetries:         opt.MaxRetries,
+		MinRetryBackoff:    opt.MinRetryBackoff,
+		MaxRetryBackoff:    opt.MaxRetryBackoff,
+		DialTimeout:        opt.DialTimeout,
+		ReadTimeout:        opt.ReadTimeout,
+		WriteTimeout:       opt.WriteTimeout,
+		PoolSize:           opt.PoolSize,
+		MinIdleConns:       opt.MinIdleConns,
+		MaxConnAge:         opt.MaxConnAge,
+		PoolTimeout:        opt.PoolTimeout,
+		IdleTimeout:        opt.IdleTimeout,
+		IdleCheckFrequency: opt.IdleCheckFrequency,
+		TLSConfig:          configure.TLSConfig,
+	}
+}
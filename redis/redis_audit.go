@@ -0,0 +1,168 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/coolstina/connecter"
+	"github.com/coolstina/connecter/audit"
+	"github.com/go-redis/redis"
+)
+
+// WithAudit Specifies the auditor that records write commands,
+// including the ones sent through pipelines and transactions.
+// Bind the request context with Client.WithContext to attach the
+// actor.
+func WithAudit(auditor *audit.Auditor) Option {
+	return optionFunc(func(config *Config) {
+		config.hooks = append(config.hooks, func(client *redis.Client) {
+			client.WrapProcess(func(process func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+				return func(cmd redis.Cmder) error {
+					err := process(cmd)
+					if isWriteCommand(cmd.Name()) {
+						auditor.Record(client.Context(), auditEvent(cmd))
+					}
+					return err
+				}
+			})
+
+			client.WrapProcessPipeline(func(process func([]redis.Cmder) error) func([]redis.Cmder) error {
+				return func(cmds []redis.Cmder) error {
+					err := process(cmds)
+					for _, cmd := range cmds {
+						if isWriteCommand(cmd.Name()) {
+							auditor.Record(client.Context(), auditEvent(cmd))
+						}
+					}
+					return err
+				}
+			})
+		})
+	})
+}
+
+func auditEvent(cmd redis.Cmder) audit.Event {
+	args := cmd.Args()
+	event := audit.Event{
+		Backend:   connecter.DriverNameOfRedis,
+		Operation: audit.OperationOfWrite,
+		Target:    commandKey(args),
+		Statement: cmd.Name(),
+	}
+
+	if isDeleteCommand(cmd.Name()) {
+		event.Operation = audit.OperationOfDelete
+	}
+
+	if len(args) > 2 {
+		event.Fields = auditFields(cmd.Name(), args)
+	}
+
+	if err := cmd.Err(); err != nil && err != redis.Nil {
+		event.Error = err.Error()
+	}
+
+	if affected, ok := cmd.(*redis.IntCmd); ok {
+		event.Affected = affected.Val()
+	}
+
+	return event
+}
+
+// auditArguments defines the names of the arguments following the key
+// of write commands, so audit.WithRedactFields can target them.
+var auditArguments = map[string][]string{
+	"append":       {"value"},
+	"expire":       {"ttl"},
+	"expireat":     {"at"},
+	"getset":       {"value"},
+	"hincrby":      {"field", "increment"},
+	"hincrbyfloat": {"field", "increment"},
+	"hsetnx":       {"field", "value"},
+	"incrby":       {"increment"},
+	"decrby":       {"increment"},
+	"incrbyfloat":  {"increment"},
+	"linsert":      {"where", "pivot", "value"},
+	"lrem":         {"count", "value"},
+	"lset":         {"index", "value"},
+	"ltrim":        {"start", "stop"},
+	"pexpire":      {"ttl"},
+	"pexpireat":    {"at"},
+	"psetex":       {"ttl", "value"},
+	"rename":       {"newkey"},
+	"renamenx":     {"newkey"},
+	"set":          {"value"},
+	"setbit":       {"offset", "value"},
+	"setex":        {"ttl", "value"},
+	"setnx":        {"value"},
+	"setrange":     {"offset", "value"},
+	"smove":        {"destination", "member"},
+	"zincrby":      {"increment", "member"},
+}
+
+// auditValues defines write commands whose arguments following the
+// key are all values.
+var auditValues = map[string]struct{}{
+	"hdel": {}, "lpush": {}, "lpushx": {}, "pfadd": {}, "rpush": {},
+	"rpushx": {}, "sadd": {}, "srem": {}, "zrem": {},
+}
+
+// auditPairs defines write commands whose arguments are name and value
+// pairs, recorded by name so values can be redacted by their name.
+var auditPairs = map[string]int{
+	"hmset": 2, "hset": 2, "mset": 1, "msetnx": 1,
+}
+
+// auditFields names the arguments of a write command following its
+// key. Arguments past the named ones, such as options, are recorded in
+// order as "options", and arguments of other commands by their
+// position, "arg2" being the first one.
+func auditFields(name string, args []interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+
+	if from, ok := auditPairs[name]; ok {
+		values := make(map[string]interface{})
+		for i := from; i+1 < len(args); i += 2 {
+			values[fmt.Sprint(args[i])] = args[i+1]
+		}
+		fields["values"] = values
+		return fields
+	}
+
+	if _, ok := auditValues[name]; ok {
+		fields["values"] = args[2:]
+		return fields
+	}
+
+	names, ok := auditArguments[name]
+	if !ok {
+		for i := 2; i < len(args); i++ {
+			fields["arg"+strconv.Itoa(i)] = args[i]
+		}
+		return fields
+	}
+
+	for i, arg := range args[2:] {
+		if i < len(names) {
+			fields[names[i]] = arg
+			continue
+		}
+		options, _ := fields["options"].([]interface{})
+		fields["options"] = append(options, arg)
+	}
+	return fields
+}
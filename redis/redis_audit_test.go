@@ -0,0 +1,164 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/coolstina/connecter"
+	"github.com/coolstina/connecter/audit"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingSink collects the audit events written to it.
+type recordingSink struct {
+	mu     sync.Mutex
+	events []audit.Event
+}
+
+func (s *recordingSink) Write(ctx context.Context, event *audit.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, *event)
+	return nil
+}
+
+func (s *recordingSink) recorded() []audit.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]audit.Event(nil), s.events...)
+}
+
+func TestWithAudit(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		switch args[0] {
+		case "del":
+			return ":2\r\n"
+		case "incr":
+			return ":1\r\n"
+		case "get":
+			return "$1\r\n1\r\n"
+		}
+		return "+OK\r\n"
+	})
+
+	sink := &recordingSink{}
+	client := newFakeClient(t, server, WithAudit(audit.New(sink)))
+	ctx := audit.WithActor(context.Background(), audit.Actor{ID: "u1"})
+
+	assert.NoError(t, client.Get("username").Err())
+	assert.Empty(t, sink.recorded())
+
+	bound := client.WithContext(ctx)
+	assert.NoError(t, bound.Set("username", "helloshaohua", 0).Err())
+	assert.NoError(t, bound.Del("a", "b").Err())
+
+	events := sink.recorded()
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, connecter.DriverNameOfRedis, events[0].Backend)
+		assert.Equal(t, audit.OperationOfWrite, events[0].Operation)
+		assert.Equal(t, "username", events[0].Target)
+		assert.Equal(t, "set", events[0].Statement)
+		assert.Equal(t, "u1", events[0].Actor.ID)
+		assert.Equal(t, audit.OperationOfDelete, events[1].Operation)
+		assert.Equal(t, int64(2), events[1].Affected)
+	}
+
+	_, err := bound.Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.Incr("counter")
+		pipe.Get("counter")
+		return nil
+	})
+	assert.NoError(t, err)
+	events = sink.recorded()[2:]
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "incr", events[0].Statement)
+		assert.Equal(t, "u1", events[0].Actor.ID)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	server := newFakeServer(t, nil)
+	sink := &recordingSink{}
+	client := newFakeClient(t, server, WithAudit(audit.New(sink)))
+
+	// Clones of clones still record a single event with their context.
+	ctx := audit.WithActor(context.Background(), audit.Actor{ID: "u2"})
+	clone := client.WithContext(context.Background()).WithContext(ctx)
+	assert.NoError(t, clone.Set("a", 1, 0).Err())
+
+	events := sink.recorded()
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "u2", events[0].Actor.ID)
+	}
+}
+
+func TestNewConnection_WithContext(t *testing.T) {
+	server := newFakeServer(t, nil)
+	sink := &recordingSink{}
+	client, err := NewConnection(NewDefaultSimpleConfig(server.addr(), "", 0), WithAudit(audit.New(sink)))
+	assert.NoError(t, err)
+	defer client.Close()
+
+	// The hooks are bound to the context of the client returned, not
+	// to the context of its clones.
+	ctx := audit.WithActor(context.Background(), audit.Actor{ID: "u1"})
+	assert.NoError(t, client.WithContext(ctx).Set("a", 1, 0).Err())
+
+	events := sink.recorded()
+	if assert.Len(t, events, 1) {
+		assert.Nil(t, events[0].Actor)
+	}
+}
+
+func TestAuditFields(t *testing.T) {
+	grids := []struct {
+		args     []interface{}
+		expected map[string]interface{}
+	}{
+		{
+			args:     []interface{}{"set", "token", "secret", "ex", 60},
+			expected: map[string]interface{}{"value": "secret", "options": []interface{}{"ex", 60}},
+		},
+		{
+			args:     []interface{}{"hset", "user:1", "name", "tom", "password", "secret"},
+			expected: map[string]interface{}{"values": map[string]interface{}{"name": "tom", "password": "secret"}},
+		},
+		{
+			args:     []interface{}{"mset", "a", 1, "b", 2},
+			expected: map[string]interface{}{"values": map[string]interface{}{"a": 1, "b": 2}},
+		},
+		{
+			args:     []interface{}{"sadd", "tags", "a", "b"},
+			expected: map[string]interface{}{"values": []interface{}{"a", "b"}},
+		},
+		{
+			args:     []interface{}{"xadd", "stream", "*", "name", "tom"},
+			expected: map[string]interface{}{"arg2": "*", "arg3": "name", "arg4": "tom"},
+		},
+	}
+
+	for _, grid := range grids {
+		assert.Equal(t, grid.expected, auditFields(grid.args[0].(string), grid.args), grid.args[0])
+	}
+
+	// The values are redacted by their names.
+	auditor := audit.New(&recordingSink{}, audit.WithRedactFields("password"))
+	fields := auditor.Redact(auditFields("hset", []interface{}{"hset", "user:1", "name", "tom", "password", "secret"}))
+	assert.Equal(t, map[string]interface{}{"name": "tom", "password": "[REDACTED]"}, fields["values"])
+}
@@ -0,0 +1,237 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// writeCommands defines commands that mutate data, the value
+// reports whether the command removes data.
+var writeCommands = map[string]bool{
+	"append":           false,
+	"bitop":            false,
+	"blpop":            true,
+	"brpop":            true,
+	"brpoplpush":       false,
+	"bzpopmax":         true,
+	"bzpopmin":         true,
+	"decr":             false,
+	"decrby":           false,
+	"del":              true,
+	"expire":           false,
+	"expireat":         false,
+	"flushall":         true,
+	"flushdb":          true,
+	"geoadd":           false,
+	"getset":           false,
+	"hdel":             true,
+	"hincrby":          false,
+	"hincrbyfloat":     false,
+	"hmset":            false,
+	"hset":             false,
+	"hsetnx":           false,
+	"incr":             false,
+	"incrby":           false,
+	"incrbyfloat":      false,
+	"linsert":          false,
+	"lpop":             true,
+	"lpush":            false,
+	"lpushx":           false,
+	"lrem":             true,
+	"lset":             false,
+	"ltrim":            true,
+	"mset":             false,
+	"msetnx":           false,
+	"persist":          false,
+	"pexpire":          false,
+	"pexpireat":        false,
+	"pfadd":            false,
+	"pfmerge":          false,
+	"psetex":           false,
+	"rename":           false,
+	"renamenx":         false,
+	"restore":          false,
+	"rpop":             true,
+	"rpoplpush":        false,
+	"rpush":            false,
+	"rpushx":           false,
+	"sadd":             false,
+	"sdiffstore":       false,
+	"set":              false,
+	"setbit":           false,
+	"setex":            false,
+	"setnx":            false,
+	"setrange":         false,
+	"sinterstore":      false,
+	"smove":            false,
+	"spop":             true,
+	"srem":             true,
+	"sunionstore":      false,
+	"unlink":           true,
+	"xadd":             false,
+	"xdel":             true,
+	"xtrim":            true,
+	"zadd":             false,
+	"zincrby":          false,
+	"zinterstore":      false,
+	"zpopmax":          true,
+	"zpopmin":          true,
+	"zrem":             true,
+	"zremrangebylex":   true,
+	"zremrangebyrank":  true,
+	"zremrangebyscore": true,
+	"zunionstore":      false,
+}
+
+// isWriteCommand reports whether the named command mutates data.
+func isWriteCommand(name string) bool {
+	_, ok := writeCommands[strings.ToLower(name)]
+	return ok
+}
+
+// isDeleteCommand reports whether the named command removes data.
+func isDeleteCommand(name string) bool {
+	return writeCommands[strings.ToLower(name)]
+}
+
+// commandKey get the first key of the command arguments.
+func commandKey(args []interface{}) string {
+	if len(args) < 2 {
+		return ""
+	}
+	return fmt.Sprint(args[1])
+}
+
+// keylessCommands defines commands without key arguments.
+var keylessCommands = map[string]struct{}{
+	"auth": {}, "bgrewriteaof": {}, "bgsave": {}, "client": {}, "cluster": {},
+	"command": {}, "config": {}, "dbsize": {}, "debug": {}, "discard": {},
+	"echo": {}, "exec": {}, "flushall": {}, "flushdb": {}, "info": {},
+	"lastsave": {}, "monitor": {}, "multi": {}, "ping": {}, "quit": {},
+	"randomkey": {}, "readonly": {}, "readwrite": {}, "role": {}, "save": {},
+	"scan": {}, "script": {}, "slowlog": {}, "time": {}, "unwatch": {},
+	"wait": {},
+}
+
+// singleKeyCommands defines commands whose only key, or pattern, is
+// their first argument.
+var singleKeyCommands = map[string]struct{}{
+	"append": {}, "bitcount": {}, "bitfield": {}, "bitpos": {}, "decr": {},
+	"decrby": {}, "dump": {}, "expire": {}, "expireat": {}, "geoadd": {},
+	"geodist": {}, "geohash": {}, "geopos": {}, "get": {}, "getbit": {},
+	"getrange": {}, "getset": {}, "hdel": {}, "hexists": {}, "hget": {},
+	"hgetall": {}, "hincrby": {}, "hincrbyfloat": {}, "hkeys": {}, "hlen": {},
+	"hmget": {}, "hmset": {}, "hscan": {}, "hset": {}, "hsetnx": {},
+	"hstrlen": {}, "hvals": {}, "incr": {}, "incrby": {}, "incrbyfloat": {},
+	"keys": {}, "lindex": {}, "linsert": {}, "llen": {}, "lpop": {},
+	"lpush": {}, "lpushx": {}, "lrange": {}, "lrem": {}, "lset": {},
+	"ltrim": {}, "persist": {}, "pexpire": {}, "pexpireat": {}, "pfadd": {},
+	"psetex": {}, "pttl": {}, "publish": {}, "restore": {}, "rpop": {},
+	"rpush": {}, "rpushx": {}, "sadd": {}, "scard": {}, "set": {},
+	"setbit": {}, "setex": {}, "setnx": {}, "setrange": {}, "sismember": {},
+	"smembers": {}, "spop": {}, "srandmember": {}, "srem": {}, "sscan": {},
+	"strlen": {}, "ttl": {}, "type": {}, "xack": {}, "xadd": {},
+	"xclaim": {}, "xdel": {}, "xlen": {}, "xpending": {}, "xrange": {},
+	"xrevrange": {}, "xtrim": {}, "zadd": {}, "zcard": {}, "zcount": {},
+	"zincrby": {}, "zlexcount": {}, "zpopmax": {}, "zpopmin": {}, "zrange": {},
+	"zrangebylex": {}, "zrangebyscore": {}, "zrank": {}, "zrem": {},
+	"zremrangebylex": {}, "zremrangebyrank": {}, "zremrangebyscore": {},
+	"zrevrange": {}, "zrevrangebylex": {}, "zrevrangebyscore": {},
+	"zrevrank": {}, "zscan": {}, "zscore": {},
+}
+
+// multiKeyCommands defines commands whose arguments are all keys, or
+// all channels.
+var multiKeyCommands = map[string]struct{}{
+	"del": {}, "exists": {}, "mget": {}, "pfcount": {}, "pfmerge": {},
+	"psubscribe": {}, "punsubscribe": {}, "sdiff": {}, "sdiffstore": {},
+	"sinter": {}, "sinterstore": {}, "subscribe": {}, "sunion": {},
+	"sunionstore": {}, "touch": {}, "unlink": {}, "unsubscribe": {},
+	"watch": {},
+}
+
+// commandKeys get the argument positions of the command keys, channels
+// included. It reports false for commands whose keys are unknown.
+func commandKeys(args []interface{}) ([]int, bool) {
+	if len(args) == 0 {
+		return nil, false
+	}
+
+	name := strings.ToLower(fmt.Sprint(args[0]))
+	if _, ok := keylessCommands[name]; ok {
+		return nil, true
+	}
+
+	keys := make([]int, 0, 1)
+	between := func(from, to int) ([]int, bool) {
+		for i := from; i < to && i < len(args); i++ {
+			keys = append(keys, i)
+		}
+		return keys, true
+	}
+
+	if _, ok := singleKeyCommands[name]; ok {
+		return between(1, 2)
+	}
+
+	if _, ok := multiKeyCommands[name]; ok {
+		return between(1, len(args))
+	}
+
+	switch name {
+	case "mset", "msetnx":
+		for i := 1; i < len(args); i += 2 {
+			keys = append(keys, i)
+		}
+		return keys, true
+	case "rename", "renamenx", "rpoplpush", "smove", "brpoplpush":
+		return between(1, 3)
+	case "blpop", "brpop", "bzpopmin", "bzpopmax":
+		return between(1, len(args)-1)
+	case "bitop":
+		return between(2, len(args))
+	case "object", "xgroup", "xinfo":
+		return between(2, 3)
+	case "eval", "evalsha":
+		return between(3, 3+numkeys(args, 2))
+	case "zunionstore", "zinterstore":
+		keys = append(keys, 1)
+		return between(3, 3+numkeys(args, 2))
+	case "xread", "xreadgroup":
+		// The keys follow STREAMS, as many as the IDs after them.
+		for i := 1; i < len(args); i++ {
+			if strings.EqualFold(fmt.Sprint(args[i]), "streams") {
+				return between(i+1, i+1+(len(args)-i-1)/2)
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// numkeys get the number of keys declared at the given position.
+func numkeys(args []interface{}, position int) int {
+	if position >= len(args) {
+		return 0
+	}
+	n, err := strconv.Atoi(fmt.Sprint(args[position]))
+	if err != nil {
+		return 0
+	}
+	return n
+}
@@ -17,6 +17,8 @@ package redis
 import (
 	"crypto/tls"
 	"time"
+
+	"github.com/coolstina/connecter/discovery"
 )
 
 // Config defines config for redis.
@@ -78,6 +80,21 @@ type Config struct {
 
 	// TLS Config to use. When set TLS will be negotiated.
 	TLSConfig *tls.Config
+
+	// The master name, when set the client connects through the
+	// sentinels and Host is ignored.
+	MasterName string
+	// A seed list of host:port addresses of sentinel nodes.
+	SentinelAddrs []string
+
+	// Hooks applied to the client after it has been created.
+	hooks []hook
+	// Key prefix of each tenant, set by WithTenantPrefix.
+	prefix func(id string) string
+	// Warm-up run once the client has been created.
+	warmUp *warmUp
+	// Discoverer resolving the sentinel addresses.
+	sentinels *discovery.Discoverer
 }
 
 // NewDefaultSimpleConfig initialize default simple connection config.
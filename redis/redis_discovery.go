@@ -0,0 +1,40 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"github.com/coolstina/connecter/discovery"
+)
+
+// WithSentinel Specifies the master name and the sentinel addresses,
+// the client connects to the master the sentinels report and follows
+// its failovers.
+func WithSentinel(masterName string, addrs ...string) Option {
+	return optionFunc(func(config *Config) {
+		config.MasterName = masterName
+		config.SentinelAddrs = addrs
+	})
+}
+
+// WithSentinelDiscovery Specifies the master name and the discoverer
+// resolving the sentinel addresses when connecting. The targets are
+// resolved once, the client can't replace its sentinels afterwards,
+// it follows failovers and the other sentinels through them.
+func WithSentinelDiscovery(masterName string, discoverer *discovery.Discoverer) Option {
+	return optionFunc(func(config *Config) {
+		config.MasterName = masterName
+		config.sentinels = discoverer
+	})
+}
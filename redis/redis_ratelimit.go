@@ -0,0 +1,86 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"github.com/coolstina/connecter/ratelimit"
+	"github.com/go-redis/redis"
+)
+
+// WithRateLimit Specifies the limiter that throttles commands,
+// write commands are writes, pipelines and transactions are bulk
+// and other commands are reads. Bind the request context with
+// Client.WithContext to bound the time spent waiting.
+func WithRateLimit(limiter *ratelimit.Limiter) Option {
+	return optionFunc(func(config *Config) {
+		config.hooks = append(config.hooks, func(client *redis.Client) {
+			client.WrapProcess(func(process func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+				return func(cmd redis.Cmder) error {
+					class := ratelimit.ClassOfRead
+					if isWriteCommand(cmd.Name()) {
+						class = ratelimit.ClassOfWrite
+					}
+
+					if err := limiter.Take(client.Context(), class); err != nil {
+						reject(err, cmd)
+						return err
+					}
+					return process(cmd)
+				}
+			})
+
+			client.WrapProcessPipeline(func(process func([]redis.Cmder) error) func([]redis.Cmder) error {
+				return func(cmds []redis.Cmder) error {
+					if err := limiter.Take(client.Context(), ratelimit.ClassOfBulk); err != nil {
+						reject(err, cmds...)
+						return err
+					}
+					return process(cmds)
+				}
+			})
+		})
+	})
+}
+
+// rejecter is the limiter of a client that fails every command with
+// its error before connecting.
+type rejecter struct {
+	err error
+}
+
+func (r rejecter) Allow() error {
+	return r.err
+}
+
+func (r rejecter) ReportResult(result error) {}
+
+// reject fails the commands with err. Commands only take errors from
+// a client processing them, so they are processed by a client that
+// rejects them before they are sent.
+func reject(err error, cmds ...redis.Cmder) {
+	client := redis.NewClient(&redis.Options{IdleTimeout: -1}).SetLimiter(rejecter{err: err})
+	defer client.Close()
+
+	if len(cmds) == 1 {
+		_ = client.Process(cmds[0])
+		return
+	}
+
+	pipe := client.Pipeline()
+	for _, cmd := range cmds {
+		_ = pipe.Process(cmd)
+	}
+	_, _ = pipe.Exec()
+}
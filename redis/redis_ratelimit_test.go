@@ -0,0 +1,72 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"testing"
+
+	"github.com/coolstina/connecter/ratelimit"
+	"github.com/go-redis/redis"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		if args[0] == "get" {
+			return "$12\r\nhelloshaohua\r\n"
+		}
+		return "+OK\r\n"
+	})
+
+	limiter := ratelimit.New(
+		ratelimit.WithRate(ratelimit.ClassOfRead, 0.001, 1),
+		ratelimit.WithRate(ratelimit.ClassOfBulk, 0.001, 1),
+		ratelimit.WithMode(ratelimit.ModeOfFailFast),
+	)
+	client := newFakeClient(t, server, WithRateLimit(limiter))
+
+	actual, err := client.Get("username").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, "helloshaohua", actual)
+
+	actual, err = client.Get("username").Result()
+	assert.Equal(t, ratelimit.ErrLimited, err)
+	assert.Empty(t, actual)
+
+	// Writes have no limit of their own.
+	assert.NoError(t, client.Set("username", "coolstina", 0).Err())
+
+	pipeline := func(pipe redis.Pipeliner) error {
+		pipe.Set("a", 1, 0)
+		pipe.Set("b", 2, 0)
+		return nil
+	}
+	_, err = client.Pipelined(pipeline)
+	assert.NoError(t, err)
+
+	cmds, err := client.Pipelined(pipeline)
+	assert.Equal(t, ratelimit.ErrLimited, err)
+	for _, cmd := range cmds {
+		assert.Equal(t, ratelimit.ErrLimited, cmd.Err())
+	}
+
+	var gets int
+	for _, command := range server.recorded() {
+		if command[0] == "get" {
+			gets++
+		}
+	}
+	assert.Equal(t, 1, gets)
+}
@@ -0,0 +1,180 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/coolstina/connecter/tenant"
+	"github.com/go-redis/redis"
+)
+
+// ErrCrossTenant is returned for commands of a tenant client reaching
+// the keys of every tenant.
+var ErrCrossTenant = errors.New("redis: command spans every tenant")
+
+// ErrUnknownKeys is returned for commands of a tenant client whose keys
+// can't be located, so they can't be prefixed.
+var ErrUnknownKeys = errors.New("redis: command keys unknown")
+
+// crossTenantCommands defines keyless commands reaching every key of
+// the database or switching the database, a tenant client can't run
+// them.
+var crossTenantCommands = map[string]struct{}{
+	"dbsize": {}, "flushall": {}, "flushdb": {}, "randomkey": {}, "select": {},
+	"swapdb": {},
+}
+
+// WithTenantPrefix Specifies the key prefix of each tenant, every key
+// of a command is prefixed with the prefix of the tenant carried by
+// the client context, bind it with Client.WithContext. Commands with keys
+// fail with tenant.ErrNoTenant when the context carries no tenant.
+// Channels of PUBLISH and SUBSCRIBE commands are prefixed as keys.
+// Replies, such as KEYS results, are returned unchanged. Commands
+// reaching every key or another database, such as FLUSHDB, DBSIZE or
+// SELECT, fail with ErrCrossTenant, and SCAN requires a MATCH pattern,
+// which is prefixed. Commands whose keys aren't known fail with
+// ErrUnknownKeys. The publish/subscribe connections of the redis client
+// bypass the options, subscribe with Client.Subscribe and
+// Client.PSubscribe, whose channels are prefixed.
+func WithTenantPrefix(prefix func(id string) string) Option {
+	return optionFunc(func(config *Config) {
+		config.prefix = prefix
+		config.hooks = append(config.hooks, func(client *redis.Client) {
+			client.WrapProcess(func(process func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+				return func(cmd redis.Cmder) error {
+					if err := prefixKeys(client, cmd, prefix); err != nil {
+						reject(err, cmd)
+						return err
+					}
+					return process(cmd)
+				}
+			})
+
+			client.WrapProcessPipeline(func(process func([]redis.Cmder) error) func([]redis.Cmder) error {
+				return func(cmds []redis.Cmder) error {
+					for _, cmd := range cmds {
+						if err := prefixKeys(client, cmd, prefix); err != nil {
+							reject(err, cmds...)
+							return err
+						}
+					}
+					return process(cmds)
+				}
+			})
+		})
+	})
+}
+
+func prefixKeys(client *redis.Client, cmd redis.Cmder, prefix func(id string) string) error {
+	args := cmd.Args()
+	name := strings.ToLower(cmd.Name())
+	if _, ok := crossTenantCommands[name]; ok {
+		return ErrCrossTenant
+	}
+
+	keys, ok := commandKeys(args)
+	if !ok {
+		return ErrUnknownKeys
+	}
+	if name == "scan" {
+		keys = scanMatch(args)
+		if len(keys) == 0 {
+			return ErrCrossTenant
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	id, err := tenant.MustFromContext(client.Context())
+	if err != nil {
+		return err
+	}
+
+	p := prefix(id)
+	for _, i := range keys {
+		switch key := args[i].(type) {
+		case string:
+			args[i] = p + key
+		case []byte:
+			args[i] = append([]byte(p), key...)
+		default:
+			args[i] = p + fmt.Sprint(key)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe subscribes the client to the given channels, prefixed
+// with the prefix of the tenant carried by the client context when
+// made with WithTenantPrefix. Channels given to the returned PubSub
+// afterwards, and the channels of the messages received, aren't
+// changed. When the context carries no tenant, the PubSub returned
+// is closed.
+func (c *Client) Subscribe(channels ...string) *redis.PubSub {
+	channels, err := c.prefixChannels(channels)
+	if err != nil {
+		return closed(c.Client.Subscribe())
+	}
+	return c.Client.Subscribe(channels...)
+}
+
+// PSubscribe subscribes the client to the given patterns, prefixed
+// like the channels of Subscribe.
+func (c *Client) PSubscribe(channels ...string) *redis.PubSub {
+	channels, err := c.prefixChannels(channels)
+	if err != nil {
+		return closed(c.Client.PSubscribe())
+	}
+	return c.Client.PSubscribe(channels...)
+}
+
+func (c *Client) prefixChannels(channels []string) ([]string, error) {
+	if c.prefix == nil || len(channels) == 0 {
+		return channels, nil
+	}
+
+	id, err := tenant.MustFromContext(c.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	prefixed := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		prefixed = append(prefixed, c.prefix(id)+channel)
+	}
+	return prefixed, nil
+}
+
+// closed closes the PubSub so it reports an error once used.
+func closed(pubsub *redis.PubSub) *redis.PubSub {
+	_ = pubsub.Close()
+	return pubsub
+}
+
+// scanMatch get the argument position of the SCAN MATCH pattern, the
+// arguments can't be extended, so a SCAN without MATCH has none.
+func scanMatch(args []interface{}) []int {
+	for i := 2; i+1 < len(args); i += 2 {
+		if strings.EqualFold(fmt.Sprint(args[i]), "match") {
+			return []int{i + 1}
+		}
+	}
+	return nil
+}
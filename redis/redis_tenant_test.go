@@ -0,0 +1,72 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coolstina/connecter/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithTenantPrefix(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		switch args[0] {
+		case "scan":
+			return "*2\r\n$1\r\n0\r\n*1\r\n$9\r\nt1:user:1\r\n"
+		case "publish":
+			return ":1\r\n"
+		case "subscribe":
+			return "*3\r\n$9\r\nsubscribe\r\n$7\r\nt1:news\r\n:1\r\n"
+		}
+		return "+OK\r\n"
+	})
+
+	client := newFakeClient(t, server, WithTenantPrefix(func(id string) string { return id + ":" }))
+	_, err := client.Subscribe("news").Receive()
+	assert.Error(t, err)
+
+	client = client.WithContext(tenant.WithTenant(context.Background(), "t1"))
+
+	assert.NoError(t, client.Set("username", "helloshaohua", 0).Err())
+
+	keys, cursor, err := client.Scan(0, "user:*", 10).Result()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(0), cursor)
+	assert.Equal(t, []string{"t1:user:1"}, keys)
+
+	assert.Equal(t, ErrCrossTenant, client.Scan(0, "", 10).Err())
+	assert.Equal(t, ErrCrossTenant, client.FlushDB().Err())
+	assert.Equal(t, ErrCrossTenant, client.FlushAll().Err())
+	assert.Equal(t, ErrCrossTenant, client.DBSize().Err())
+	assert.Equal(t, ErrCrossTenant, client.RandomKey().Err())
+	assert.Equal(t, ErrCrossTenant, client.Do("select", 1).Err())
+	assert.Equal(t, ErrUnknownKeys, client.Do("sort", "a", "store", "b").Err())
+
+	assert.NoError(t, client.Publish("news", "hello").Err())
+
+	pubsub := client.Subscribe("news")
+	_, err = pubsub.Receive()
+	assert.NoError(t, err)
+	assert.NoError(t, pubsub.Close())
+
+	assert.Equal(t, [][]string{
+		{"set", "t1:username", "helloshaohua"},
+		{"scan", "0", "match", "t1:user:*", "count", "10"},
+		{"publish", "t1:news", "hello"},
+		{"subscribe", "t1:news"},
+	}, server.recorded())
+}
@@ -15,9 +15,13 @@
 package redis
 
 import (
+	"context"
 	"testing"
 	"time"
 
+	"github.com/coolstina/connecter/ratelimit"
+	"github.com/coolstina/connecter/tenant"
+	"github.com/go-redis/redis"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -41,3 +45,66 @@ func TestNewConnection(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "helloshaohua", actual)
 }
+
+func TestReject(t *testing.T) {
+	cmd := redis.NewStatusCmd("set", "username", "helloshaohua")
+	reject(ratelimit.ErrLimited, cmd)
+
+	actual, err := cmd.Result()
+	assert.Equal(t, ratelimit.ErrLimited, err)
+	assert.Empty(t, actual)
+
+	cmds := []redis.Cmder{redis.NewStringCmd("get", "a"), redis.NewIntCmd("incr", "b")}
+	reject(context.DeadlineExceeded, cmds...)
+	for _, cmd := range cmds {
+		assert.Equal(t, context.DeadlineExceeded, cmd.Err())
+	}
+}
+
+func TestCommandKeys(t *testing.T) {
+	grids := []struct {
+		args     []interface{}
+		expected []int
+		unknown  bool
+	}{
+		{args: []interface{}{"ping"}},
+		{args: []interface{}{"select", 8}, unknown: true},
+		{args: []interface{}{"sort", "a", "store", "b"}, unknown: true},
+		{args: []interface{}{"get", "username"}, expected: []int{1}},
+		{args: []interface{}{"set", "username", "helloshaohua", "ex", 5}, expected: []int{1}},
+		{args: []interface{}{"del", "a", "b", "c"}, expected: []int{1, 2, 3}},
+		{args: []interface{}{"mset", "a", 1, "b", 2}, expected: []int{1, 3}},
+		{args: []interface{}{"blpop", "a", "b", 0}, expected: []int{1, 2}},
+		{args: []interface{}{"eval", "return 1", 2, "a", "b", "argv"}, expected: []int{3, 4}},
+		{args: []interface{}{"zunionstore", "out", 2, "a", "b", "weights", 1, 2}, expected: []int{1, 3, 4}},
+		{args: []interface{}{"publish", "news", "hello"}, expected: []int{1}},
+		{args: []interface{}{"subscribe", "news", "sports"}, expected: []int{1, 2}},
+		{args: []interface{}{"psubscribe", "news.*"}, expected: []int{1}},
+		{args: []interface{}{"xread", "count", 2, "streams", "a", "b", "0", "0"}, expected: []int{4, 5}},
+		{args: []interface{}{"xreadgroup", "group", "g", "c", "streams", "a", ">"}, expected: []int{5}},
+	}
+
+	for _, grid := range grids {
+		actual, ok := commandKeys(grid.args)
+		assert.Equal(t, !grid.unknown, ok, grid.args[0])
+		if grid.expected == nil {
+			assert.Empty(t, actual, grid.args[0])
+			continue
+		}
+		assert.Equal(t, grid.expected, actual, grid.args[0])
+	}
+}
+
+func TestPrefixKeys(t *testing.T) {
+	client, err := NewConnection(NewDefaultSimpleConfig("localhost:6379", "", 8))
+	assert.NoError(t, err)
+
+	prefix := func(id string) string { return id + ":" }
+
+	cmd := redis.NewStatusCmd("mset", "a", 1, "b", 2)
+	assert.Equal(t, tenant.ErrNoTenant, prefixKeys(client, cmd, prefix))
+
+	client = client.WithContext(tenant.WithTenant(context.Background(), "t1"))
+	assert.NoError(t, prefixKeys(client, cmd, prefix))
+	assert.Equal(t, []interface{}{"mset", "t1:a", 1, "t1:b", 2}, cmd.Args())
+}
@@ -0,0 +1,74 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package redis
+
+import (
+	"context"
+	"time"
+
+	"github.com/coolstina/connecter"
+	"github.com/go-redis/redis"
+)
+
+type warmUp struct {
+	connections int
+	timeout     time.Duration
+	report      func(report connecter.WarmUpReport)
+}
+
+// WithWarmUp Specifies the number of connections NewConnection opens
+// in parallel before returning, bounded by the timeout. The report
+// tells how many succeeded.
+func WithWarmUp(connections int, timeout time.Duration, report func(report connecter.WarmUpReport)) Option {
+	return optionFunc(func(config *Config) {
+		config.warmUp = &warmUp{connections: connections, timeout: timeout, report: report}
+	})
+}
+
+// WarmUp opens n pool connections in parallel and returns them idle
+// to the pool, the report tells how many were established before ctx
+// was done. Each connection is pinned by a transaction while held.
+func WarmUp(ctx context.Context, client *redis.Client, n int) connecter.WarmUpReport {
+	return connecter.WarmUp(ctx, connecter.DriverNameOfRedis, n, func(ctx context.Context) (func(), error) {
+		pinned := make(chan error, 1)
+		release := make(chan struct{})
+
+		go func() {
+			_ = client.Watch(func(tx *redis.Tx) error {
+				err := tx.Ping().Err()
+				pinned <- err
+				if err == nil {
+					<-release
+				}
+				return err
+			})
+		}()
+
+		select {
+		case err := <-pinned:
+			if err != nil {
+				return nil, err
+			}
+			return func() { close(release) }, nil
+		case <-ctx.Done():
+			go func() {
+				if err := <-pinned; err == nil {
+					close(release)
+				}
+			}()
+			return nil, ctx.Err()
+		}
+	})
+}
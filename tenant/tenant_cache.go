@@ -0,0 +1,319 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenant
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCacheClosed is returned by Get after the cache has been closed.
+var ErrCacheClosed = errors.New("tenant: cache closed")
+
+// Opener opens the connection of a tenant, the returned close
+// function is called when the connection is evicted and no longer held.
+type Opener func(ctx context.Context, id string) (conn interface{}, close func() error, err error)
+
+type entry struct {
+	id       string
+	conn     interface{}
+	close    func() error
+	lastUsed time.Time
+	element  *list.Element
+	// refs is the number of Get callers holding the connection, it is
+	// closed once removed and no longer held.
+	refs    int
+	removed bool
+}
+
+type call struct {
+	done chan struct{}
+	err  error
+}
+
+// Cache lazily opens and caches one connection per tenant.
+// Connections are evicted in least recently used order once the
+// cache is full, and after being idle for the idle timeout. Evicted
+// connections are closed once every Get holding them is released.
+type Cache struct {
+	mu      sync.Mutex
+	open    Opener
+	entries map[string]*entry
+	order   *list.List
+	calls   map[string]*call
+	closed  bool
+	stop    chan struct{}
+	options *options
+}
+
+// NewCache initialize cache instance with the given opener.
+func NewCache(open Opener, ops ...Option) *Cache {
+	opts := &options{
+		maxSize: 64,
+		now:     time.Now,
+	}
+
+	for _, o := range ops {
+		o.apply(opts)
+	}
+
+	c := &Cache{
+		open:    open,
+		entries: make(map[string]*entry),
+		order:   list.New(),
+		calls:   make(map[string]*call),
+		stop:    make(chan struct{}),
+		options: opts,
+	}
+
+	if opts.idleTimeout > 0 {
+		go c.janitor()
+	}
+
+	return c
+}
+
+// Get returns the connection of the tenant, opening it on first use,
+// along with the function releasing it. The connection stays open
+// until released, even when evicted meanwhile, so release must be
+// called once it is no longer used. Concurrent callers for the same
+// tenant share a single open, a nil ctx is context.Background().
+func (c *Cache) Get(ctx context.Context, id string) (interface{}, func(), error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return nil, nil, ErrCacheClosed
+		}
+
+		if e, ok := c.entries[id]; ok {
+			e.refs++
+			e.lastUsed = c.options.now()
+			c.order.MoveToFront(e.element)
+			c.mu.Unlock()
+			return e.conn, c.releaser(e), nil
+		}
+
+		pending, ok := c.calls[id]
+		if !ok {
+			break
+		}
+		c.mu.Unlock()
+
+		// Once opened the connection is taken from the entries.
+		select {
+		case <-pending.done:
+			if pending.err != nil {
+				return nil, nil, pending.err
+			}
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	pending := &call{done: make(chan struct{})}
+	c.calls[id] = pending
+	c.mu.Unlock()
+
+	conn, closeFunc, err := c.open(ctx, id)
+	pending.err = err
+
+	var e *entry
+	c.mu.Lock()
+	delete(c.calls, id)
+	if err == nil && c.closed {
+		pending.err = ErrCacheClosed
+	}
+	if pending.err == nil {
+		e = &entry{id: id, conn: conn, close: closeFunc, lastUsed: c.options.now(), refs: 1}
+		e.element = c.order.PushFront(e)
+		c.entries[id] = e
+	}
+	evicted := c.overflow()
+	c.mu.Unlock()
+	close(pending.done)
+
+	if err == nil && pending.err != nil && closeFunc != nil {
+		_ = closeFunc()
+	}
+	_ = c.release(evicted)
+
+	if pending.err != nil {
+		return nil, nil, pending.err
+	}
+	return conn, c.releaser(e), nil
+}
+
+// releaser returns the function releasing the hold of a Get on the
+// entry, the entry is closed by the last release once removed.
+func (c *Cache) releaser(e *entry) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			e.refs--
+			e.lastUsed = c.options.now()
+			closing := e.removed && e.refs == 0
+			c.mu.Unlock()
+
+			if closing {
+				_ = c.release([]*entry{e})
+			}
+		})
+	}
+}
+
+// Evict removes the connection of the tenant if cached, it is closed
+// now or once no longer held.
+func (c *Cache) Evict(id string) error {
+	c.mu.Lock()
+	var evicted []*entry
+	if e, ok := c.entries[id]; ok {
+		evicted = c.remove(evicted, e)
+	}
+	c.mu.Unlock()
+
+	return c.release(evicted)
+}
+
+// Len returns the number of cached connections.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Close closes every cached connection, the cache can't be used after.
+// Connections still held are closed once released.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.stop)
+
+	entries := make([]*entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		entries = append(entries, e)
+	}
+	evicted := make([]*entry, 0, len(entries))
+	for _, e := range entries {
+		evicted = c.remove(evicted, e)
+	}
+	c.mu.Unlock()
+
+	return c.release(evicted)
+}
+
+// overflow removes the least recently used entries above the maximum
+// size, returning the ones to close.
+func (c *Cache) overflow() []*entry {
+	evicted := make([]*entry, 0)
+	for c.options.maxSize > 0 && c.order.Len() > c.options.maxSize {
+		evicted = c.remove(evicted, c.order.Back().Value.(*entry))
+	}
+	return evicted
+}
+
+// expired removes the entries unused for the idle timeout, returning
+// the ones to close. Held entries are in use, so never idle.
+func (c *Cache) expired() []*entry {
+	evicted := make([]*entry, 0)
+	deadline := c.options.now().Add(-c.options.idleTimeout)
+	for element := c.order.Back(); element != nil; {
+		e := element.Value.(*entry)
+		element = element.Prev()
+		if e.refs > 0 {
+			continue
+		}
+		if e.lastUsed.After(deadline) {
+			break
+		}
+		evicted = c.remove(evicted, e)
+	}
+	return evicted
+}
+
+// remove removes the entry from the cache, appending it to evicted
+// unless held, it is closed by its last release then.
+func (c *Cache) remove(evicted []*entry, e *entry) []*entry {
+	c.order.Remove(e.element)
+	delete(c.entries, e.id)
+	e.removed = true
+	if e.refs > 0 {
+		return evicted
+	}
+	return append(evicted, e)
+}
+
+// release closes the given entries outside of the lock.
+func (c *Cache) release(evicted []*entry) error {
+	var first error
+	for _, e := range evicted {
+		var err error
+		if e.close != nil {
+			err = e.close()
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+		if c.options.onEvict != nil {
+			c.options.onEvict(e.id, err)
+		}
+	}
+	return first
+}
+
+func (c *Cache) janitor() {
+	interval := c.options.idleTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.Sweep()
+		case <-c.stop:
+			return
+		}
+	}
+}
+
+// Sweep closes the connections idle for longer than the idle timeout,
+// it is called periodically when an idle timeout is specified.
+func (c *Cache) Sweep() {
+	if c.options.idleTimeout <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	evicted := c.expired()
+	c.mu.Unlock()
+
+	_ = c.release(evicted)
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenant
+
+import "time"
+
+type Option interface {
+	apply(*options)
+}
+
+type optionFunc func(ops *options)
+
+func (o optionFunc) apply(ops *options) {
+	o(ops)
+}
+
+type options struct {
+	maxSize     int
+	idleTimeout time.Duration
+	now         func() time.Time
+	onEvict     func(id string, err error)
+}
+
+// WithMaxSize Specifies the maximum number of cached connections,
+// the least recently used one is closed when it is exceeded.
+// Default value 64.
+func WithMaxSize(size int) Option {
+	return optionFunc(func(ops *options) {
+		ops.maxSize = size
+	})
+}
+
+// WithIdleTimeout Specifies the amount of time after which an unused
+// connection is closed. The default is 0, meaning never.
+func WithIdleTimeout(timeout time.Duration) Option {
+	return optionFunc(func(ops *options) {
+		ops.idleTimeout = timeout
+	})
+}
+
+// WithEvictHandler Specifies the function invoked after a connection
+// has been evicted and closed, err is the close error.
+func WithEvictHandler(handler func(id string, err error)) Option {
+	return optionFunc(func(ops *options) {
+		ops.onEvict = handler
+	})
+}
+
+// WithClock Specifies the function used to track idle time.
+// Default is time.Now.
+func WithClock(now func() time.Time) Option {
+	return optionFunc(func(ops *options) {
+		ops.now = now
+	})
+}
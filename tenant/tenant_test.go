@@ -0,0 +1,155 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tenant
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeOpener struct {
+	mu     sync.Mutex
+	opened int32
+	closed []string
+}
+
+func (f *fakeOpener) open(ctx context.Context, id string) (interface{}, func() error, error) {
+	atomic.AddInt32(&f.opened, 1)
+	if id == "broken" {
+		return nil, nil, errors.New("unknown tenant")
+	}
+
+	closeFunc := func() error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.closed = append(f.closed, id)
+		return nil
+	}
+	return "conn:" + id, closeFunc, nil
+}
+
+func TestFromContext(t *testing.T) {
+	_, err := MustFromContext(context.Background())
+	assert.Equal(t, ErrNoTenant, err)
+
+	id, ok := FromContext(WithTenant(context.Background(), "t1"))
+	assert.True(t, ok)
+	assert.Equal(t, "t1", id)
+}
+
+func TestCache_LeastRecentlyUsed(t *testing.T) {
+	opener := &fakeOpener{}
+	cache := NewCache(opener.open, WithMaxSize(2))
+	ctx := context.Background()
+
+	for _, id := range []string{"t1", "t2", "t1", "t3"} {
+		conn, release, err := cache.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, "conn:"+id, conn)
+		release()
+	}
+
+	assert.Equal(t, 2, cache.Len())
+	assert.Equal(t, int32(3), opener.opened)
+	assert.Equal(t, []string{"t2"}, opener.closed)
+
+	_, _, err := cache.Get(ctx, "broken")
+	assert.Error(t, err)
+	assert.Equal(t, 2, cache.Len())
+
+	assert.NoError(t, cache.Close())
+	assert.ElementsMatch(t, []string{"t2", "t1", "t3"}, opener.closed)
+
+	_, _, err = cache.Get(ctx, "t1")
+	assert.Equal(t, ErrCacheClosed, err)
+}
+
+func TestCache_Sweep(t *testing.T) {
+	now := time.Date(2021, 12, 1, 0, 0, 0, 0, time.UTC)
+	opener := &fakeOpener{}
+	cache := NewCache(opener.open,
+		WithIdleTimeout(time.Minute),
+		WithClock(func() time.Time { return now }),
+	)
+	defer cache.Close()
+
+	ctx := context.Background()
+	_, release, _ := cache.Get(ctx, "t1")
+	release()
+	now = now.Add(30 * time.Second)
+	_, release, _ = cache.Get(ctx, "t2")
+	release()
+	_, held, _ := cache.Get(ctx, "t3")
+	now = now.Add(45 * time.Second)
+
+	cache.Sweep()
+	assert.Equal(t, []string{"t1"}, opener.closed)
+	assert.Equal(t, 2, cache.Len())
+
+	held()
+	now = now.Add(time.Minute)
+	cache.Sweep()
+	assert.ElementsMatch(t, []string{"t1", "t2", "t3"}, opener.closed)
+}
+
+func TestCache_Held(t *testing.T) {
+	opener := &fakeOpener{}
+	cache := NewCache(opener.open, WithMaxSize(1))
+
+	_, held, err := cache.Get(nil, "t1")
+	assert.NoError(t, err)
+
+	_, release, err := cache.Get(context.Background(), "t2")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, cache.Len())
+	assert.Empty(t, opener.closed)
+
+	held()
+	held()
+	assert.Equal(t, []string{"t1"}, opener.closed)
+
+	assert.NoError(t, cache.Close())
+	assert.Equal(t, []string{"t1"}, opener.closed)
+
+	release()
+	assert.Equal(t, []string{"t1", "t2"}, opener.closed)
+}
+
+func TestCache_SharedOpen(t *testing.T) {
+	opener := &fakeOpener{}
+	cache := NewCache(opener.open)
+	defer cache.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, release, err := cache.Get(context.Background(), "t1")
+			assert.NoError(t, err)
+			assert.Equal(t, "conn:t1", conn)
+			release()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&opener.opened))
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connecter
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// WarmUpReport defines the result of a connection pool warm-up.
+type WarmUpReport struct {
+	Backend   DriverName
+	Requested int
+	Succeeded int
+	Errors    []error
+	Elapsed   time.Duration
+}
+
+// Ready reports whether every requested connection was established.
+func (r WarmUpReport) Ready() bool {
+	return r.Succeeded >= r.Requested
+}
+
+// Err returns the first warm-up error, if any.
+func (r WarmUpReport) Err() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return r.Errors[0]
+}
+
+// Dialer establishes one connection for a warm-up, the returned
+// release function hands it back to its pool. Dialers of drivers
+// unable to hold a connection return a nil release, concurrent dials
+// may then share a connection and the report counts requests.
+type Dialer func(ctx context.Context) (release func(), err error)
+
+// WarmUp runs dial n times in parallel until ctx is done. Connections
+// are held until every dial has finished, so that each dial opens a
+// connection of its own, and then released to their pool. An n of 0
+// or less requests no warm-up and returns an empty report.
+func WarmUp(ctx context.Context, backend DriverName, n int, dial Dialer) WarmUpReport {
+	if n <= 0 {
+		return WarmUpReport{Backend: backend}
+	}
+
+	report := WarmUpReport{Backend: backend, Requested: n}
+	start := time.Now()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		releases = make([]func(), 0, n)
+	)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			release, err := dial(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				report.Errors = append(report.Errors, err)
+				return
+			}
+
+			report.Succeeded++
+			if release != nil {
+				releases = append(releases, release)
+			}
+		}()
+	}
+
+	wg.Wait()
+	for _, release := range releases {
+		release()
+	}
+
+	report.Elapsed = time.Since(start)
+	return report
+}
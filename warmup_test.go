@@ -0,0 +1,79 @@
+// Copyright 2021 helloshaohua <wu.shaohua@foxmail.com>;
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package connecter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmUp(t *testing.T) {
+	var (
+		open     int32
+		peak     int32
+		released int32
+		calls    int32
+		mu       sync.Mutex
+	)
+
+	dial := func(ctx context.Context) (func(), error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return nil, errors.New("connection refused")
+		}
+
+		mu.Lock()
+		open++
+		if open > peak {
+			peak = open
+		}
+		mu.Unlock()
+
+		return func() {
+			mu.Lock()
+			open--
+			mu.Unlock()
+			atomic.AddInt32(&released, 1)
+		}, nil
+	}
+
+	report := WarmUp(context.Background(), DriverNameOfMySQL, 8, dial)
+	assert.Equal(t, DriverNameOfMySQL, report.Backend)
+	assert.Equal(t, 8, report.Requested)
+	assert.Equal(t, 7, report.Succeeded)
+	assert.False(t, report.Ready())
+	assert.EqualError(t, report.Err(), "connection refused")
+
+	// Every connection was held until all dials finished.
+	assert.Equal(t, int32(7), peak)
+	assert.Equal(t, int32(7), released)
+}
+
+func TestWarmUp_None(t *testing.T) {
+	dial := func(ctx context.Context) (func(), error) {
+		t.Fatal("unexpected dial")
+		return nil, nil
+	}
+
+	for _, n := range []int{0, -1} {
+		report := WarmUp(context.Background(), DriverNameOfRedis, n, dial)
+		assert.Equal(t, WarmUpReport{Backend: DriverNameOfRedis}, report)
+		assert.True(t, report.Ready())
+	}
+}